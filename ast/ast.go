@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"math/big"
 	"monkey/token"
 	"strings"
 )
@@ -31,6 +32,7 @@ AST Nodes
 
 type Program struct {
 	Statements []Statement
+	Comments   []*Comment // in source order, not attached to statements
 }
 
 func (p *Program) TokenLiteral() string {
@@ -50,12 +52,22 @@ func (p *Program) String() string {
 }
 
 func NewProgram() *Program {
-	return &Program{Statements: []Statement{}}
+	return &Program{Statements: []Statement{}, Comments: []*Comment{}}
 }
 
+// Comment is a // line comment. Comments do not affect evaluation; they are
+// kept for tools that print source back.
+type Comment struct {
+	Token token.Token // the COMMENT token, including the leading //
+}
+
+func (c *Comment) TokenLiteral() string { return c.Token.Literal }
+func (c *Comment) String() string       { return c.Token.Literal }
+
 type BlockStatement struct {
 	Token      token.Token // the '{' token
 	Statements []Statement
+	Rbrace     token.Position // the closing '}'
 }
 
 func (stmt *BlockStatement) statementNode()       {}
@@ -69,9 +81,11 @@ func (stmt *BlockStatement) String() string {
 }
 
 type LetStatement struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token    token.Token
+	Name     *Identifier
+	Type     TypeAnnotation // nil when the binding is not annotated
+	Value    Expression
+	Exported bool // preceded by 'export'
 }
 
 func (stmt *LetStatement) statementNode()       {}
@@ -79,8 +93,14 @@ func (stmt *LetStatement) TokenLiteral() string { return stmt.Token.Literal }
 
 func (stmt *LetStatement) String() string {
 	var out bytes.Buffer
+	if stmt.Exported {
+		out.WriteString("export ")
+	}
 	out.WriteString(stmt.TokenLiteral() + " ")
 	out.WriteString(stmt.Name.String())
+	if stmt.Type != nil {
+		out.WriteString(": " + stmt.Type.String())
+	}
 	out.WriteString(" = ")
 	if stmt.Value != nil {
 		out.WriteString(stmt.Value.String())
@@ -107,6 +127,30 @@ func (stmt *ReturnStatement) String() string {
 	return out.String()
 }
 
+type ImportStatement struct {
+	Token token.Token // the 'import' token
+	Path  *StringLiteral
+	Names []*Identifier // imported bindings, nil to import the whole module
+}
+
+func (stmt *ImportStatement) statementNode()       {}
+func (stmt *ImportStatement) TokenLiteral() string { return stmt.Token.Literal }
+
+func (stmt *ImportStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(stmt.TokenLiteral() + " ")
+	if stmt.Names != nil {
+		names := []string{}
+		for _, name := range stmt.Names {
+			names = append(names, name.String())
+		}
+		out.WriteString("{ " + strings.Join(names, ", ") + " } from ")
+	}
+	out.WriteString("\"" + stmt.Path.Value + "\"")
+	out.WriteString(";")
+	return out.String()
+}
+
 type ExpressionStatement struct {
 	Token      token.Token // first token in the expression
 	Expression Expression
@@ -180,10 +224,42 @@ func (expr *IfExpression) String() string {
 	return out.String()
 }
 
+type TryExpression struct {
+	Token          token.Token // the 'try' token
+	Block          *BlockStatement
+	CatchParameter *Identifier // nil when the caught error is not bound
+	CatchBlock     *BlockStatement
+	FinallyBlock   *BlockStatement
+}
+
+func (expr *TryExpression) expressionNode()      {}
+func (expr *TryExpression) TokenLiteral() string { return expr.Token.Literal }
+func (expr *TryExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(expr.Block.String())
+	if expr.CatchBlock != nil {
+		out.WriteString(" catch")
+		if expr.CatchParameter != nil {
+			out.WriteString("(" + expr.CatchParameter.String() + ")")
+		}
+		out.WriteString(" ")
+		out.WriteString(expr.CatchBlock.String())
+	}
+	if expr.FinallyBlock != nil {
+		out.WriteString(" finally ")
+		out.WriteString(expr.FinallyBlock.String())
+	}
+	return out.String()
+}
+
 type FunctionLiteral struct {
-	Token      token.Token // the 'fn' token
-	Parameters []*Identifier
-	Body       *BlockStatement
+	Token          token.Token // the 'fn' token
+	Name           string      // name of the let binding, if any
+	Parameters     []*Identifier
+	ParameterTypes []TypeAnnotation // nil, or one per parameter with nil for those not annotated
+	ReturnType     TypeAnnotation   // nil when the result is not annotated
+	Body           *BlockStatement
 }
 
 func (expr *FunctionLiteral) expressionNode()      {}
@@ -192,14 +268,22 @@ func (expr *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _, param := range expr.Parameters {
-		params = append(params, param.String())
+	for i, param := range expr.Parameters {
+		if i < len(expr.ParameterTypes) && expr.ParameterTypes[i] != nil {
+			params = append(params, param.String()+": "+expr.ParameterTypes[i].String())
+		} else {
+			params = append(params, param.String())
+		}
 	}
 
 	out.WriteString(expr.Token.Literal)
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") ")
+	out.WriteString(")")
+	if expr.ReturnType != nil {
+		out.WriteString(": " + expr.ReturnType.String())
+	}
+	out.WriteString(" ")
 	out.WriteString(expr.Body.String())
 
 	return out.String()
@@ -209,6 +293,7 @@ type CallExpression struct {
 	Token     token.Token // the '(' token
 	Function  Expression  // Identifier or FunctionLiteral
 	Arguments []Expression
+	Rparen    token.Position // the closing ')'
 }
 
 func (expr *CallExpression) expressionNode()      {}
@@ -230,8 +315,19 @@ func (expr *CallExpression) String() string {
 }
 
 type Identifier struct {
-	Token token.Token
-	Value string
+	Token   token.Token
+	Value   string
+	Binding *Binding // set by the resolver, nil for names bound outside the program
+}
+
+// Binding locates the declaration an identifier refers to: Depth counts the
+// scopes between the identifier and the declaring scope, and Slot is the
+// position of the name among that scope's declarations. Tools such as the
+// language server use it to match references to declarations; the evaluator
+// still looks names up by name.
+type Binding struct {
+	Depth int
+	Slot  int
 }
 
 func (expr *Identifier) expressionNode()      {}
@@ -244,12 +340,22 @@ func (expr *Identifier) String() string {
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+	Big   *big.Int // set instead of Value when the literal overflows int64
 }
 
 func (expr *IntegerLiteral) expressionNode()      {}
 func (expr *IntegerLiteral) TokenLiteral() string { return expr.Token.Literal }
 func (expr *IntegerLiteral) String() string       { return expr.Token.Literal }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (expr *FloatLiteral) expressionNode()      {}
+func (expr *FloatLiteral) TokenLiteral() string { return expr.Token.Literal }
+func (expr *FloatLiteral) String() string       { return expr.Token.Literal }
+
 type BooleanLiteral struct {
 	Token token.Token
 	Value bool
@@ -268,9 +374,20 @@ func (expr *StringLiteral) expressionNode()      {}
 func (expr *StringLiteral) TokenLiteral() string { return expr.Token.Literal }
 func (expr *StringLiteral) String() string       { return expr.Token.Literal }
 
+type RegexLiteral struct {
+	Token   token.Token // the whole /pattern/flags literal
+	Pattern string
+	Flags   string
+}
+
+func (expr *RegexLiteral) expressionNode()      {}
+func (expr *RegexLiteral) TokenLiteral() string { return expr.Token.Literal }
+func (expr *RegexLiteral) String() string       { return expr.Token.Literal }
+
 type ArrayLiteral struct {
 	Token    token.Token // the '[' token
 	Elements []Expression
+	Rbracket token.Position // the closing ']'
 }
 
 func (expr *ArrayLiteral) expressionNode()      {}
@@ -291,10 +408,40 @@ func (expr *ArrayLiteral) String() string {
 	return out.String()
 }
 
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
+}
+
+type HashLiteral struct {
+	Token  token.Token // the '{' token
+	Pairs  []HashLiteralPair
+	Rbrace token.Position // the closing '}'
+}
+
+func (expr *HashLiteral) expressionNode()      {}
+func (expr *HashLiteral) TokenLiteral() string { return expr.Token.Literal }
+
+func (expr *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range expr.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
 type IndexExpression struct {
-	Token token.Token // the '[' token
-	Left  Expression
-	Index Expression
+	Token    token.Token // the '[' token
+	Left     Expression
+	Index    Expression
+	Rbracket token.Position // the closing ']'
 }
 
 func (expr *IndexExpression) expressionNode()      {}
@@ -310,3 +457,84 @@ func (expr *IndexExpression) String() string {
 
 	return out.String()
 }
+
+type MemberExpression struct {
+	Token    token.Token // the '.' token
+	Object   Expression
+	Property *Identifier
+}
+
+func (expr *MemberExpression) expressionNode()      {}
+func (expr *MemberExpression) TokenLiteral() string { return expr.Token.Literal }
+func (expr *MemberExpression) String() string {
+	return expr.Object.String() + "." + expr.Property.String()
+}
+
+/**********************************************************
+Type Annotations
+*********************************************************/
+
+// TypeAnnotation is the type written after a let name, a parameter or a
+// parameter list. Annotations are verified by the checker package and have
+// no effect on evaluation.
+type TypeAnnotation interface {
+	Node
+	typeNode()
+}
+
+// NamedType is a type written as a name, such as int or any.
+type NamedType struct {
+	Token token.Token // the name
+}
+
+func (typ *NamedType) typeNode()            {}
+func (typ *NamedType) TokenLiteral() string { return typ.Token.Literal }
+func (typ *NamedType) String() string       { return typ.Token.Literal }
+
+// ArrayType is written [element].
+type ArrayType struct {
+	Token    token.Token // the '[' token
+	Element  TypeAnnotation
+	Rbracket token.Position // the closing ']'
+}
+
+func (typ *ArrayType) typeNode()            {}
+func (typ *ArrayType) TokenLiteral() string { return typ.Token.Literal }
+func (typ *ArrayType) String() string       { return "[" + typ.Element.String() + "]" }
+
+// HashType is written {key: value}.
+type HashType struct {
+	Token  token.Token // the '{' token
+	Key    TypeAnnotation
+	Value  TypeAnnotation
+	Rbrace token.Position // the closing '}'
+}
+
+func (typ *HashType) typeNode()            {}
+func (typ *HashType) TokenLiteral() string { return typ.Token.Literal }
+func (typ *HashType) String() string {
+	return "{" + typ.Key.String() + ": " + typ.Value.String() + "}"
+}
+
+// FunctionType is written fn(parameters): result, where the result may be
+// left out.
+type FunctionType struct {
+	Token      token.Token // the 'fn' token
+	Parameters []TypeAnnotation
+	Return     TypeAnnotation // nil when left out
+	Rparen     token.Position // the ')' closing the parameters
+}
+
+func (typ *FunctionType) typeNode()            {}
+func (typ *FunctionType) TokenLiteral() string { return typ.Token.Literal }
+func (typ *FunctionType) String() string {
+	params := []string{}
+	for _, param := range typ.Parameters {
+		params = append(params, param.String())
+	}
+	out := "fn(" + strings.Join(params, ", ") + ")"
+	if typ.Return != nil {
+		out += ": " + typ.Return.String()
+	}
+	return out
+}
@@ -0,0 +1,605 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"monkey/token"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// JSON_VERSION is the version of the schema written by EncodeJSON. It is
+// bumped whenever a node kind or field changes incompatibly.
+const JSON_VERSION = 1
+
+// EncodeJSON encodes node and its children as JSON. Every node is an object
+// with its "kind" (the Go type name), "pos" (the position of its token, which
+// is the operator for infix, call, index and member expressions), "span"
+// (see SpanOf) and its fields; a Program also records the schema "version".
+// Missing children are encoded as null.
+func EncodeJSON(node Node) ([]byte, error) {
+	value, err := encodeNode(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// DecodeJSON rebuilds a program encoded by EncodeJSON.
+func DecodeJSON(data []byte) (*Program, error) {
+	decoder := &jsonDecoder{}
+	node := decoder.node(data)
+	if decoder.err != nil {
+		return nil, decoder.err
+	}
+	program, ok := node.(*Program)
+	if !ok {
+		return nil, fmt.Errorf("ast: expected a Program, got %T", node)
+	}
+	return program, nil
+}
+
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonSpan struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+type jsonObject map[string]interface{}
+
+// jsonLeadingKeys are written before the other keys of a node, which follow
+// in alphabetical order.
+var jsonLeadingKeys = []string{"kind", "version", "pos", "span"}
+
+func (object jsonObject) MarshalJSON() ([]byte, error) {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		rank := func(key string) int {
+			for rank, leading := range jsonLeadingKeys {
+				if key == leading {
+					return rank
+				}
+			}
+			return len(jsonLeadingKeys)
+		}
+		if rank(keys[i]) != rank(keys[j]) {
+			return rank(keys[i]) < rank(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		value, err := json.Marshal(object[key])
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString(strconv.Quote(key) + ":")
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+func encodeNode(node Node) (interface{}, error) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return nil, nil
+	}
+
+	span := SpanOf(node)
+	object := jsonObject{
+		"kind": reflect.TypeOf(node).Elem().Name(),
+		"span": jsonSpan{Start: jsonPosition(span.Start), End: jsonPosition(span.End)},
+	}
+	if _, ok := node.(*Program); !ok {
+		object["pos"] = jsonPosition(tokenOf(node).Position)
+	}
+
+	var err error
+	children := func(fields jsonObject) {
+		for name, child := range fields {
+			if err == nil {
+				object[name], err = encodeChild(child)
+			}
+		}
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		object["version"] = JSON_VERSION
+		children(jsonObject{"statements": node.Statements, "comments": node.Comments})
+	case *Comment:
+		object["text"] = node.Token.Literal
+	case *BlockStatement:
+		children(jsonObject{"statements": node.Statements})
+	case *LetStatement:
+		object["exported"] = node.Exported
+		children(jsonObject{"name": node.Name, "type": node.Type, "value": node.Value})
+	case *ReturnStatement:
+		children(jsonObject{"value": node.ReturnValue})
+	case *ImportStatement:
+		children(jsonObject{"path": node.Path, "names": node.Names})
+	case *ExpressionStatement:
+		children(jsonObject{"expression": node.Expression})
+	case *PrefixExpression:
+		object["operator"] = node.Operator
+		children(jsonObject{"right": node.Right})
+	case *InfixExpression:
+		object["operator"] = node.Operator
+		children(jsonObject{"left": node.Left, "right": node.Right})
+	case *IfExpression:
+		children(jsonObject{"condition": node.Condition, "then": node.ThenBranch, "else": node.ElseBranch})
+	case *TryExpression:
+		children(jsonObject{"block": node.Block, "catch_parameter": node.CatchParameter, "catch": node.CatchBlock, "finally": node.FinallyBlock})
+	case *FunctionLiteral:
+		object["name"] = node.Name
+		children(jsonObject{"parameters": node.Parameters, "parameter_types": node.ParameterTypes, "return_type": node.ReturnType, "body": node.Body})
+	case *CallExpression:
+		children(jsonObject{"function": node.Function, "arguments": node.Arguments})
+	case *Identifier:
+		object["name"] = node.Value
+	case *IntegerLiteral:
+		object["literal"] = node.Token.Literal
+		if node.Big != nil {
+			object["value"] = node.Big.String()
+		} else {
+			object["value"] = strconv.FormatInt(node.Value, 10)
+		}
+	case *FloatLiteral:
+		object["literal"] = node.Token.Literal
+		object["value"] = node.Value
+	case *BooleanLiteral:
+		object["value"] = node.Value
+	case *StringLiteral:
+		object["value"] = node.Value
+	case *RegexLiteral:
+		object["pattern"] = node.Pattern
+		object["flags"] = node.Flags
+	case *ArrayLiteral:
+		children(jsonObject{"elements": node.Elements})
+	case *HashLiteral:
+		pairs := make([]jsonObject, len(node.Pairs))
+		for i, pair := range node.Pairs {
+			pairs[i] = jsonObject{}
+			for name, child := range map[string]Node{"key": pair.Key, "value": pair.Value} {
+				if err == nil {
+					pairs[i][name], err = encodeNode(child)
+				}
+			}
+		}
+		object["pairs"] = pairs
+	case *IndexExpression:
+		children(jsonObject{"left": node.Left, "index": node.Index})
+	case *MemberExpression:
+		children(jsonObject{"object": node.Object, "property": node.Property})
+	case *NamedType:
+		object["name"] = node.Token.Literal
+	case *ArrayType:
+		children(jsonObject{"element": node.Element})
+	case *HashType:
+		children(jsonObject{"key": node.Key, "value": node.Value})
+	case *FunctionType:
+		// the span of a function type may end with its result, so the
+		// parenthesis closing its parameters is recorded separately
+		object["rparen"] = jsonPosition(node.Rparen)
+		children(jsonObject{"parameters": node.Parameters, "return": node.Return})
+	default:
+		return nil, fmt.Errorf("ast: cannot encode %T as JSON", node)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// encodeChild encodes a child node or a slice of them.
+func encodeChild(child interface{}) (interface{}, error) {
+	if child == nil {
+		return nil, nil
+	}
+	if node, ok := child.(Node); ok {
+		return encodeNode(node)
+	}
+
+	value := reflect.ValueOf(child)
+	if value.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("ast: cannot encode %T as JSON", child)
+	}
+	if value.IsNil() {
+		return nil, nil
+	}
+	elements := make([]interface{}, value.Len())
+	for i := range elements {
+		node, _ := value.Index(i).Interface().(Node)
+		element, err := encodeNode(node)
+		if err != nil {
+			return nil, err
+		}
+		elements[i] = element
+	}
+	return elements, nil
+}
+
+// jsonDecoder rebuilds nodes from their JSON encoding, keeping the first
+// error it runs into.
+type jsonDecoder struct {
+	err error
+}
+
+func (d *jsonDecoder) fail(format string, a ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("ast: "+format, a...)
+	}
+}
+
+func (d *jsonDecoder) node(data json.RawMessage) Node {
+	if d.err != nil || isNull(data) {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		d.fail("node must be a JSON object, got %.20s", data)
+		return nil
+	}
+	var kind string
+	d.field(fields, "node", "kind", &kind)
+
+	var pos jsonPosition
+	var span jsonSpan
+	if kind != "Program" {
+		d.field(fields, kind, "pos", &pos)
+	}
+	d.field(fields, kind, "span", &span)
+	if d.err != nil {
+		return nil
+	}
+	tok := func(tokenType token.TokenType, literal string) token.Token {
+		return token.Token{Type: tokenType, Literal: literal, Position: token.Position(pos)}
+	}
+	// the closing delimiter is the last character of the span
+	closing := token.Position{Line: span.End.Line, Column: span.End.Column - 1}
+
+	switch kind {
+	case "Program":
+		var version int
+		d.field(fields, kind, "version", &version)
+		if d.err == nil && version != JSON_VERSION {
+			d.fail("unsupported schema version %d", version)
+		}
+		program := &Program{Statements: d.statements(fields, kind, "statements"), Comments: []*Comment{}}
+		for _, element := range d.list(fields, kind, "comments") {
+			comment, ok := d.node(element).(*Comment)
+			if !ok {
+				d.fail("%s.comments must contain comments", kind)
+				return nil
+			}
+			program.Comments = append(program.Comments, comment)
+		}
+		return program
+	case "Comment":
+		var text string
+		d.field(fields, kind, "text", &text)
+		return &Comment{Token: tok(token.COMMENT, text)}
+	case "BlockStatement":
+		return &BlockStatement{Token: tok(token.LBRACE, "{"), Statements: d.statements(fields, kind, "statements"), Rbrace: closing}
+	case "LetStatement":
+		stmt := &LetStatement{Token: tok(token.LET, "let")}
+		d.field(fields, kind, "exported", &stmt.Exported)
+		stmt.Name = d.identifier(fields, kind, "name", true)
+		stmt.Type = d.typeAnnotation(fields, kind, "type", false)
+		stmt.Value = d.expression(fields, kind, "value", true)
+		return stmt
+	case "ReturnStatement":
+		return &ReturnStatement{Token: tok(token.RETURN, "return"), ReturnValue: d.expression(fields, kind, "value", false)}
+	case "ImportStatement":
+		stmt := &ImportStatement{Token: tok(token.IMPORT, "import")}
+		if path, ok := d.expression(fields, kind, "path", true).(*StringLiteral); ok {
+			stmt.Path = path
+		} else {
+			d.fail("%s.path must be a StringLiteral", kind)
+		}
+		stmt.Names = d.identifiers(fields, kind, "names")
+		return stmt
+	case "ExpressionStatement":
+		expr := d.expression(fields, kind, "expression", true)
+		leading := leadingToken(expr)
+		return &ExpressionStatement{Token: token.Token{Type: leading.Type, Literal: leading.Literal, Position: token.Position(pos)}, Expression: expr}
+	case "PrefixExpression":
+		var operator string
+		d.field(fields, kind, "operator", &operator)
+		return &PrefixExpression{Token: tok(token.TokenType(operator), operator), Operator: operator, Right: d.expression(fields, kind, "right", true)}
+	case "InfixExpression":
+		var operator string
+		d.field(fields, kind, "operator", &operator)
+		return &InfixExpression{
+			Token:    tok(token.TokenType(operator), operator),
+			Left:     d.expression(fields, kind, "left", true),
+			Operator: operator,
+			Right:    d.expression(fields, kind, "right", true),
+		}
+	case "IfExpression":
+		return &IfExpression{
+			Token:      tok(token.IF, "if"),
+			Condition:  d.expression(fields, kind, "condition", true),
+			ThenBranch: d.block(fields, kind, "then", true),
+			ElseBranch: d.block(fields, kind, "else", false),
+		}
+	case "TryExpression":
+		return &TryExpression{
+			Token:          tok(token.TRY, "try"),
+			Block:          d.block(fields, kind, "block", true),
+			CatchParameter: d.identifier(fields, kind, "catch_parameter", false),
+			CatchBlock:     d.block(fields, kind, "catch", false),
+			FinallyBlock:   d.block(fields, kind, "finally", false),
+		}
+	case "FunctionLiteral":
+		function := &FunctionLiteral{Token: tok(token.FUNCTION, "fn")}
+		d.field(fields, kind, "name", &function.Name)
+		function.Parameters = d.identifiers(fields, kind, "parameters")
+		function.ParameterTypes = d.typeAnnotations(fields, kind, "parameter_types", true)
+		function.ReturnType = d.typeAnnotation(fields, kind, "return_type", false)
+		function.Body = d.block(fields, kind, "body", true)
+		return function
+	case "CallExpression":
+		return &CallExpression{
+			Token:     tok(token.LPAREN, "("),
+			Function:  d.expression(fields, kind, "function", true),
+			Arguments: d.expressions(fields, kind, "arguments"),
+			Rparen:    closing,
+		}
+	case "Identifier":
+		var name string
+		d.field(fields, kind, "name", &name)
+		return &Identifier{Token: tok(token.IDENTIFIER, name), Value: name}
+	case "IntegerLiteral":
+		var literal, value string
+		d.field(fields, kind, "literal", &literal)
+		d.field(fields, kind, "value", &value)
+		integer, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			d.fail("%s.value must be a decimal integer, got %q", kind, value)
+			return nil
+		}
+		if integer.IsInt64() {
+			return &IntegerLiteral{Token: tok(token.INT, literal), Value: integer.Int64()}
+		}
+		return &IntegerLiteral{Token: tok(token.INT, literal), Big: integer}
+	case "FloatLiteral":
+		float := &FloatLiteral{}
+		var literal string
+		d.field(fields, kind, "literal", &literal)
+		d.field(fields, kind, "value", &float.Value)
+		float.Token = tok(token.FLOAT, literal)
+		return float
+	case "BooleanLiteral":
+		boolean := &BooleanLiteral{}
+		d.field(fields, kind, "value", &boolean.Value)
+		if boolean.Value {
+			boolean.Token = tok(token.TRUE, "true")
+		} else {
+			boolean.Token = tok(token.FALSE, "false")
+		}
+		return boolean
+	case "StringLiteral":
+		str := &StringLiteral{}
+		d.field(fields, kind, "value", &str.Value)
+		str.Token = tok(token.STRING, str.Value)
+		return str
+	case "RegexLiteral":
+		regex := &RegexLiteral{}
+		d.field(fields, kind, "pattern", &regex.Pattern)
+		d.field(fields, kind, "flags", &regex.Flags)
+		regex.Token = tok(token.REGEX, "/"+regex.Pattern+"/"+regex.Flags)
+		return regex
+	case "ArrayLiteral":
+		return &ArrayLiteral{Token: tok(token.LBRACKET, "["), Elements: d.expressions(fields, kind, "elements"), Rbracket: closing}
+	case "HashLiteral":
+		hash := &HashLiteral{Token: tok(token.LBRACE, "{"), Pairs: []HashLiteralPair{}, Rbrace: closing}
+		var pairs []map[string]json.RawMessage
+		d.field(fields, kind, "pairs", &pairs)
+		for _, pair := range pairs {
+			hash.Pairs = append(hash.Pairs, HashLiteralPair{
+				Key:   d.expression(pair, kind, "key", true),
+				Value: d.expression(pair, kind, "value", true),
+			})
+		}
+		return hash
+	case "IndexExpression":
+		return &IndexExpression{
+			Token:    tok(token.LBRACKET, "["),
+			Left:     d.expression(fields, kind, "left", true),
+			Index:    d.expression(fields, kind, "index", true),
+			Rbracket: closing,
+		}
+	case "MemberExpression":
+		return &MemberExpression{
+			Token:    tok(token.DOT, "."),
+			Object:   d.expression(fields, kind, "object", true),
+			Property: d.identifier(fields, kind, "property", true),
+		}
+	case "NamedType":
+		var name string
+		d.field(fields, kind, "name", &name)
+		return &NamedType{Token: tok(token.IDENTIFIER, name)}
+	case "ArrayType":
+		return &ArrayType{Token: tok(token.LBRACKET, "["), Element: d.typeAnnotation(fields, kind, "element", true), Rbracket: closing}
+	case "HashType":
+		return &HashType{
+			Token:  tok(token.LBRACE, "{"),
+			Key:    d.typeAnnotation(fields, kind, "key", true),
+			Value:  d.typeAnnotation(fields, kind, "value", true),
+			Rbrace: closing,
+		}
+	case "FunctionType":
+		var rparen jsonPosition
+		d.field(fields, kind, "rparen", &rparen)
+		return &FunctionType{
+			Token:      tok(token.FUNCTION, "fn"),
+			Parameters: d.typeAnnotations(fields, kind, "parameters", false),
+			Return:     d.typeAnnotation(fields, kind, "return", false),
+			Rparen:     token.Position(rparen),
+		}
+	default:
+		d.fail("unknown node kind %q", kind)
+		return nil
+	}
+}
+
+// field unmarshals fields[name] into target, which is left unchanged when
+// the field is missing.
+func (d *jsonDecoder) field(fields map[string]json.RawMessage, kind string, name string, target interface{}) {
+	data, ok := fields[name]
+	if d.err != nil || !ok {
+		return
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		d.fail("%s.%s: %s", kind, name, err)
+	}
+}
+
+func (d *jsonDecoder) expression(fields map[string]json.RawMessage, kind string, name string, required bool) Expression {
+	node := d.node(fields[name])
+	if node == nil {
+		if required {
+			d.fail("%s.%s is required", kind, name)
+		}
+		return nil
+	}
+	expr, ok := node.(Expression)
+	if !ok {
+		d.fail("%s.%s must be an expression, got %T", kind, name, node)
+	}
+	return expr
+}
+
+func (d *jsonDecoder) identifier(fields map[string]json.RawMessage, kind string, name string, required bool) *Identifier {
+	expr := d.expression(fields, kind, name, required)
+	if expr == nil {
+		return nil
+	}
+	identifier, ok := expr.(*Identifier)
+	if !ok {
+		d.fail("%s.%s must be an Identifier, got %T", kind, name, expr)
+	}
+	return identifier
+}
+
+func (d *jsonDecoder) block(fields map[string]json.RawMessage, kind string, name string, required bool) *BlockStatement {
+	node := d.node(fields[name])
+	if node == nil {
+		if required {
+			d.fail("%s.%s is required", kind, name)
+		}
+		return nil
+	}
+	block, ok := node.(*BlockStatement)
+	if !ok {
+		d.fail("%s.%s must be a BlockStatement, got %T", kind, name, node)
+	}
+	return block
+}
+
+func (d *jsonDecoder) list(fields map[string]json.RawMessage, kind string, name string) []json.RawMessage {
+	var elements []json.RawMessage
+	d.field(fields, kind, name, &elements)
+	return elements
+}
+
+func (d *jsonDecoder) statements(fields map[string]json.RawMessage, kind string, name string) []Statement {
+	statements := []Statement{}
+	for _, element := range d.list(fields, kind, name) {
+		stmt, ok := d.node(element).(Statement)
+		if !ok {
+			d.fail("%s.%s must contain statements", kind, name)
+			return nil
+		}
+		statements = append(statements, stmt)
+	}
+	return statements
+}
+
+func (d *jsonDecoder) expressions(fields map[string]json.RawMessage, kind string, name string) []Expression {
+	expressions := []Expression{}
+	for _, element := range d.list(fields, kind, name) {
+		expr, ok := d.node(element).(Expression)
+		if !ok {
+			d.fail("%s.%s must contain expressions", kind, name)
+			return nil
+		}
+		expressions = append(expressions, expr)
+	}
+	return expressions
+}
+
+// identifiers decodes a list of identifiers, keeping a null list as nil.
+func (d *jsonDecoder) identifiers(fields map[string]json.RawMessage, kind string, name string) []*Identifier {
+	if isNull(fields[name]) {
+		return nil
+	}
+	identifiers := []*Identifier{}
+	for _, element := range d.list(fields, kind, name) {
+		identifier, ok := d.node(element).(*Identifier)
+		if !ok {
+			d.fail("%s.%s must contain identifiers", kind, name)
+			return nil
+		}
+		identifiers = append(identifiers, identifier)
+	}
+	return identifiers
+}
+
+func (d *jsonDecoder) typeAnnotation(fields map[string]json.RawMessage, kind string, name string, required bool) TypeAnnotation {
+	node := d.node(fields[name])
+	if node == nil {
+		if required {
+			d.fail("%s.%s is required", kind, name)
+		}
+		return nil
+	}
+	typ, ok := node.(TypeAnnotation)
+	if !ok {
+		d.fail("%s.%s must be a type, got %T", kind, name, node)
+	}
+	return typ
+}
+
+// typeAnnotations decodes a list of types. With optional set, a null list
+// is kept as nil and the list may contain nulls.
+func (d *jsonDecoder) typeAnnotations(fields map[string]json.RawMessage, kind string, name string, optional bool) []TypeAnnotation {
+	if optional && isNull(fields[name]) {
+		return nil
+	}
+	types := []TypeAnnotation{}
+	for _, element := range d.list(fields, kind, name) {
+		if optional && isNull(element) {
+			types = append(types, nil)
+			continue
+		}
+		typ, ok := d.node(element).(TypeAnnotation)
+		if !ok {
+			d.fail("%s.%s must contain types", kind, name)
+			return nil
+		}
+		types = append(types, typ)
+	}
+	return types
+}
+
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == "null"
+}
@@ -0,0 +1,114 @@
+package ast_test
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %v", p.Errors())
+	}
+	return program
+}
+
+func TestEncodeJSON(t *testing.T) {
+	data, err := ast.EncodeJSON(parse(t, "x + 0x1F;"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(data), `{
+		"kind": "Program",
+		"version": 1,
+		"span": {"start": {"line": 1, "column": 1}, "end": {"line": 1, "column": 9}},
+		"comments": [],
+		"statements": [{
+			"kind": "ExpressionStatement",
+			"pos": {"line": 1, "column": 1},
+			"span": {"start": {"line": 1, "column": 1}, "end": {"line": 1, "column": 9}},
+			"expression": {
+				"kind": "InfixExpression",
+				"pos": {"line": 1, "column": 3},
+				"span": {"start": {"line": 1, "column": 1}, "end": {"line": 1, "column": 9}},
+				"operator": "+",
+				"left": {
+					"kind": "Identifier",
+					"pos": {"line": 1, "column": 1},
+					"span": {"start": {"line": 1, "column": 1}, "end": {"line": 1, "column": 2}},
+					"name": "x"
+				},
+				"right": {
+					"kind": "IntegerLiteral",
+					"pos": {"line": 1, "column": 5},
+					"span": {"start": {"line": 1, "column": 5}, "end": {"line": 1, "column": 9}},
+					"literal": "0x1F",
+					"value": "31"
+				}
+			}
+		}]
+	}`)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	input := `// a library
+import { take } from "lib";
+export let add = fn(a, b) { return a + b; };
+let values = [1, 2.5, -3, 123456789012345678901234567890, "multi
+line", /a+/i, !true];
+let table = {"a": values[0], 1: add(1, 2)};
+if (table.a > 0) { print("%d", 1) } else { take(values, 1) };
+try { 1 / 0 } catch (e) { e.message } finally { return 0; }; // done
+let apply: fn(fn(int): [string], int) = fn(f: fn(int): [string], x: int): {string: any} { f(x) };
+`
+	program := parse(t, input)
+
+	data, err := ast.EncodeJSON(program)
+	assert.NoError(t, err)
+
+	decoded, err := ast.DecodeJSON(data)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, decoded.String(), program.String())
+
+	again, err := ast.EncodeJSON(decoded)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(again), string(data))
+}
+
+func TestSpanOf(t *testing.T) {
+	program := parse(t, "let s = \"a\nbc\";\nf(x)[0].y")
+
+	assert.Equal(t, ast.SpanOf(program.Statements[0]), ast.Span{
+		Start: token.Position{Line: 1, Column: 1},
+		End:   token.Position{Line: 2, Column: 4},
+	})
+	assert.Equal(t, ast.SpanOf(program.Statements[1]), ast.Span{
+		Start: token.Position{Line: 3, Column: 1},
+		End:   token.Position{Line: 3, Column: 10},
+	})
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[]`, "ast: node must be a JSON object, got []"},
+		{`{"kind": "Program", "version": 2}`, "ast: unsupported schema version 2"},
+		{`{"kind": "Program", "version": 1, "statements": [{"kind": "Widget"}]}`, `ast: unknown node kind "Widget"`},
+		{`{"kind": "Program", "version": 1, "statements": [{"kind": "ReturnStatement", "value": {"kind": "BlockStatement"}}]}`, "ast: ReturnStatement.value must be an expression, got *ast.BlockStatement"},
+		{`{"kind": "Program", "version": 1, "statements": [{"kind": "ExpressionStatement"}]}`, "ast: ExpressionStatement.expression is required"},
+		{`{"kind": "Identifier", "name": "x"}`, "ast: expected a Program, got *ast.Identifier"},
+	}
+
+	for _, tt := range tests {
+		_, err := ast.DecodeJSON([]byte(tt.input))
+		assert.EqualError(t, err, tt.expected, tt.input)
+	}
+}
@@ -0,0 +1,178 @@
+package ast
+
+import (
+	"monkey/token"
+	"strings"
+)
+
+// Span is the source range of a node, from the first character of its first
+// token up to, but not including, End. Parentheses around a grouped
+// expression are not part of its span.
+type Span struct {
+	Start token.Position
+	End   token.Position
+}
+
+// SpanOf returns the source range covered by node.
+func SpanOf(node Node) Span {
+	return Span{Start: start(node), End: end(node)}
+}
+
+func start(node Node) token.Position {
+	switch node := node.(type) {
+	case *Program:
+		if len(node.Statements) == 0 {
+			return token.Position{Line: 1, Column: 1}
+		}
+		return start(node.Statements[0])
+	}
+	return leadingToken(node).Position
+}
+
+func end(node Node) token.Position {
+	switch node := node.(type) {
+	case *Program:
+		if len(node.Statements) == 0 {
+			return token.Position{Line: 1, Column: 1}
+		}
+		return end(node.Statements[len(node.Statements)-1])
+	case *BlockStatement:
+		return after(node.Rbrace, "}")
+	case *LetStatement:
+		return end(node.Value)
+	case *ReturnStatement:
+		if node.ReturnValue == nil {
+			return after(node.Token.Position, node.Token.Literal)
+		}
+		return end(node.ReturnValue)
+	case *ImportStatement:
+		return end(node.Path)
+	case *ExpressionStatement:
+		return end(node.Expression)
+	case *PrefixExpression:
+		return end(node.Right)
+	case *InfixExpression:
+		return end(node.Right)
+	case *IfExpression:
+		if node.ElseBranch != nil {
+			return end(node.ElseBranch)
+		}
+		return end(node.ThenBranch)
+	case *TryExpression:
+		if node.FinallyBlock != nil {
+			return end(node.FinallyBlock)
+		}
+		if node.CatchBlock != nil {
+			return end(node.CatchBlock)
+		}
+		return end(node.Block)
+	case *FunctionLiteral:
+		return end(node.Body)
+	case *CallExpression:
+		return after(node.Rparen, ")")
+	case *StringLiteral:
+		return after(node.Token.Position, "\""+node.Token.Literal+"\"")
+	case *ArrayLiteral:
+		return after(node.Rbracket, "]")
+	case *HashLiteral:
+		return after(node.Rbrace, "}")
+	case *IndexExpression:
+		return after(node.Rbracket, "]")
+	case *MemberExpression:
+		return end(node.Property)
+	case *ArrayType:
+		return after(node.Rbracket, "]")
+	case *HashType:
+		return after(node.Rbrace, "}")
+	case *FunctionType:
+		if node.Return != nil {
+			return end(node.Return)
+		}
+		return after(node.Rparen, ")")
+	}
+	tok := tokenOf(node)
+	return after(tok.Position, tok.Literal)
+}
+
+// after returns the position following text that starts at position.
+func after(position token.Position, text string) token.Position {
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		return token.Position{Line: position.Line + strings.Count(text, "\n"), Column: len(text) - i}
+	}
+	return token.Position{Line: position.Line, Column: position.Column + len(text)}
+}
+
+// leadingToken returns the first token of node.
+func leadingToken(node Node) token.Token {
+	switch node := node.(type) {
+	case *InfixExpression:
+		return leadingToken(node.Left)
+	case *CallExpression:
+		return leadingToken(node.Function)
+	case *IndexExpression:
+		return leadingToken(node.Left)
+	case *MemberExpression:
+		return leadingToken(node.Object)
+	}
+	return tokenOf(node)
+}
+
+// tokenOf returns the token stored in node, which is the operator for infix,
+// call, index and member expressions.
+func tokenOf(node Node) token.Token {
+	switch node := node.(type) {
+	case *Comment:
+		return node.Token
+	case *BlockStatement:
+		return node.Token
+	case *LetStatement:
+		return node.Token
+	case *ReturnStatement:
+		return node.Token
+	case *ImportStatement:
+		return node.Token
+	case *ExpressionStatement:
+		return node.Token
+	case *PrefixExpression:
+		return node.Token
+	case *InfixExpression:
+		return node.Token
+	case *IfExpression:
+		return node.Token
+	case *TryExpression:
+		return node.Token
+	case *FunctionLiteral:
+		return node.Token
+	case *CallExpression:
+		return node.Token
+	case *Identifier:
+		return node.Token
+	case *IntegerLiteral:
+		return node.Token
+	case *FloatLiteral:
+		return node.Token
+	case *BooleanLiteral:
+		return node.Token
+	case *StringLiteral:
+		return node.Token
+	case *RegexLiteral:
+		return node.Token
+	case *ArrayLiteral:
+		return node.Token
+	case *HashLiteral:
+		return node.Token
+	case *IndexExpression:
+		return node.Token
+	case *MemberExpression:
+		return node.Token
+	case *NamedType:
+		return node.Token
+	case *ArrayType:
+		return node.Token
+	case *HashType:
+		return node.Token
+	case *FunctionType:
+		return node.Token
+	}
+	return token.Token{}
+}
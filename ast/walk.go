@@ -0,0 +1,311 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// A Visitor's Visit method is called for each node reached by Walk. If the
+// returned visitor is not nil, Walk visits the children of node with it and
+// then calls its Visit method with nil.
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth-first order, visiting
+// children in source order. The comments of a program are visited after its
+// statements. Nil nodes, including nil pointers stored in a Node, are
+// skipped, so a partially built tree can be walked.
+func Walk(v Visitor, node Node) {
+	if isNil(node) {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		walkStatements(v, node.Statements)
+		for _, comment := range node.Comments {
+			Walk(v, comment)
+		}
+	case *BlockStatement:
+		walkStatements(v, node.Statements)
+	case *LetStatement:
+		Walk(v, node.Name)
+		Walk(v, node.Type)
+		Walk(v, node.Value)
+	case *ReturnStatement:
+		Walk(v, node.ReturnValue)
+	case *ImportStatement:
+		for _, name := range node.Names {
+			Walk(v, name)
+		}
+		Walk(v, node.Path)
+	case *ExpressionStatement:
+		Walk(v, node.Expression)
+	case *PrefixExpression:
+		Walk(v, node.Right)
+	case *InfixExpression:
+		Walk(v, node.Left)
+		Walk(v, node.Right)
+	case *IfExpression:
+		Walk(v, node.Condition)
+		Walk(v, node.ThenBranch)
+		Walk(v, node.ElseBranch)
+	case *TryExpression:
+		Walk(v, node.Block)
+		Walk(v, node.CatchParameter)
+		Walk(v, node.CatchBlock)
+		Walk(v, node.FinallyBlock)
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			Walk(v, param)
+			if i < len(node.ParameterTypes) {
+				Walk(v, node.ParameterTypes[i])
+			}
+		}
+		Walk(v, node.ReturnType)
+		Walk(v, node.Body)
+	case *CallExpression:
+		Walk(v, node.Function)
+		walkExpressions(v, node.Arguments)
+	case *ArrayLiteral:
+		walkExpressions(v, node.Elements)
+	case *HashLiteral:
+		for _, pair := range node.Pairs {
+			Walk(v, pair.Key)
+			Walk(v, pair.Value)
+		}
+	case *IndexExpression:
+		Walk(v, node.Left)
+		Walk(v, node.Index)
+	case *MemberExpression:
+		Walk(v, node.Object)
+		Walk(v, node.Property)
+	case *ArrayType:
+		Walk(v, node.Element)
+	case *HashType:
+		Walk(v, node.Key)
+		Walk(v, node.Value)
+	case *FunctionType:
+		for _, param := range node.Parameters {
+			Walk(v, param)
+		}
+		Walk(v, node.Return)
+	case *Comment, *Identifier, *IntegerLiteral, *FloatLiteral, *BooleanLiteral, *StringLiteral, *RegexLiteral, *NamedType:
+		// leaves
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", node))
+	}
+
+	v.Visit(nil)
+}
+
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	value := reflect.ValueOf(node)
+	return value.Kind() == reflect.Ptr && value.IsNil()
+}
+
+func walkStatements(v Visitor, stmts []Statement) {
+	for _, stmt := range stmts {
+		Walk(v, stmt)
+	}
+}
+
+func walkExpressions(v Visitor, exprs []Expression) {
+	for _, expr := range exprs {
+		Walk(v, expr)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node like Walk, calling f for each
+// node. If f returns true, Inspect continues with the children of node, and
+// then calls f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// ModifierFunc returns the replacement for node, or node itself to keep it.
+type ModifierFunc func(node Node) Node
+
+// Modify rewrites the tree rooted at node bottom-up: the children of each
+// node are modified first, then the node is replaced by what modifier
+// returns for it, and the result of the root is returned. Nodes are updated
+// in place.
+//
+// A statement replaced by nil is removed from its list, and nil clears an
+// optional field such as an else branch. A replacement must fit the field it
+// is stored in, so a parameter or a let name can only become another
+// identifier and a block only another block; Modify panics otherwise.
+// Comments are left alone.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		node.Statements = modifyStatements(node.Statements, modifier)
+	case *BlockStatement:
+		node.Statements = modifyStatements(node.Statements, modifier)
+	case *LetStatement:
+		node.Name = modifyIdentifier(node.Name, "LetStatement.Name", modifier)
+		node.Type = modifyType(node.Type, "LetStatement.Type", modifier)
+		node.Value = modifyExpression(node.Value, "LetStatement.Value", modifier)
+	case *ReturnStatement:
+		node.ReturnValue = modifyExpression(node.ReturnValue, "ReturnStatement.ReturnValue", modifier)
+	case *ImportStatement:
+		for i, name := range node.Names {
+			node.Names[i] = modifyIdentifier(name, "ImportStatement.Names", modifier)
+		}
+		if path, ok := Modify(node.Path, modifier).(*StringLiteral); ok {
+			node.Path = path
+		} else {
+			panic("ast.Modify: ImportStatement.Path must stay a *StringLiteral")
+		}
+	case *ExpressionStatement:
+		node.Expression = modifyExpression(node.Expression, "ExpressionStatement.Expression", modifier)
+	case *PrefixExpression:
+		node.Right = modifyExpression(node.Right, "PrefixExpression.Right", modifier)
+	case *InfixExpression:
+		node.Left = modifyExpression(node.Left, "InfixExpression.Left", modifier)
+		node.Right = modifyExpression(node.Right, "InfixExpression.Right", modifier)
+	case *IfExpression:
+		node.Condition = modifyExpression(node.Condition, "IfExpression.Condition", modifier)
+		node.ThenBranch = modifyBlock(node.ThenBranch, "IfExpression.ThenBranch", modifier)
+		node.ElseBranch = modifyBlock(node.ElseBranch, "IfExpression.ElseBranch", modifier)
+	case *TryExpression:
+		node.Block = modifyBlock(node.Block, "TryExpression.Block", modifier)
+		node.CatchParameter = modifyIdentifier(node.CatchParameter, "TryExpression.CatchParameter", modifier)
+		node.CatchBlock = modifyBlock(node.CatchBlock, "TryExpression.CatchBlock", modifier)
+		node.FinallyBlock = modifyBlock(node.FinallyBlock, "TryExpression.FinallyBlock", modifier)
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i] = modifyIdentifier(param, "FunctionLiteral.Parameters", modifier)
+		}
+		for i, typ := range node.ParameterTypes {
+			node.ParameterTypes[i] = modifyType(typ, "FunctionLiteral.ParameterTypes", modifier)
+		}
+		node.ReturnType = modifyType(node.ReturnType, "FunctionLiteral.ReturnType", modifier)
+		node.Body = modifyBlock(node.Body, "FunctionLiteral.Body", modifier)
+	case *CallExpression:
+		node.Function = modifyExpression(node.Function, "CallExpression.Function", modifier)
+		node.Arguments = modifyExpressions(node.Arguments, "CallExpression.Arguments", modifier)
+	case *ArrayLiteral:
+		node.Elements = modifyExpressions(node.Elements, "ArrayLiteral.Elements", modifier)
+	case *HashLiteral:
+		for i, pair := range node.Pairs {
+			node.Pairs[i] = HashLiteralPair{
+				Key:   modifyExpression(pair.Key, "HashLiteral key", modifier),
+				Value: modifyExpression(pair.Value, "HashLiteral value", modifier),
+			}
+		}
+	case *IndexExpression:
+		node.Left = modifyExpression(node.Left, "IndexExpression.Left", modifier)
+		node.Index = modifyExpression(node.Index, "IndexExpression.Index", modifier)
+	case *MemberExpression:
+		node.Object = modifyExpression(node.Object, "MemberExpression.Object", modifier)
+		node.Property = modifyIdentifier(node.Property, "MemberExpression.Property", modifier)
+	case *ArrayType:
+		node.Element = modifyType(node.Element, "ArrayType.Element", modifier)
+	case *HashType:
+		node.Key = modifyType(node.Key, "HashType.Key", modifier)
+		node.Value = modifyType(node.Value, "HashType.Value", modifier)
+	case *FunctionType:
+		for i, param := range node.Parameters {
+			node.Parameters[i] = modifyType(param, "FunctionType.Parameters", modifier)
+		}
+		node.Return = modifyType(node.Return, "FunctionType.Return", modifier)
+	case *Comment:
+		return node
+	}
+
+	return modifier(node)
+}
+
+func modifyStatements(stmts []Statement, modifier ModifierFunc) []Statement {
+	modified := stmts[:0]
+	for _, stmt := range stmts {
+		switch result := Modify(stmt, modifier).(type) {
+		case nil:
+		case Statement:
+			modified = append(modified, result)
+		default:
+			panic(fmt.Sprintf("ast.Modify: cannot replace a statement with %T", result))
+		}
+	}
+	return modified
+}
+
+func modifyExpressions(exprs []Expression, field string, modifier ModifierFunc) []Expression {
+	for i, expr := range exprs {
+		exprs[i] = modifyExpression(expr, field, modifier)
+	}
+	return exprs
+}
+
+func modifyExpression(expr Expression, field string, modifier ModifierFunc) Expression {
+	if expr == nil {
+		return nil
+	}
+	switch result := Modify(expr, modifier).(type) {
+	case nil:
+		return nil
+	case Expression:
+		return result
+	default:
+		panic(fmt.Sprintf("ast.Modify: cannot replace %s with %T", field, result))
+	}
+}
+
+func modifyIdentifier(ident *Identifier, field string, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	switch result := Modify(ident, modifier).(type) {
+	case nil:
+		return nil
+	case *Identifier:
+		return result
+	default:
+		panic(fmt.Sprintf("ast.Modify: %s must stay an *Identifier", field))
+	}
+}
+
+func modifyBlock(block *BlockStatement, field string, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	switch result := Modify(block, modifier).(type) {
+	case nil:
+		return nil
+	case *BlockStatement:
+		return result
+	default:
+		panic(fmt.Sprintf("ast.Modify: %s must stay a *BlockStatement", field))
+	}
+}
+
+func modifyType(typ TypeAnnotation, field string, modifier ModifierFunc) TypeAnnotation {
+	if typ == nil {
+		return nil
+	}
+	switch result := Modify(typ, modifier).(type) {
+	case nil:
+		return nil
+	case TypeAnnotation:
+		return result
+	default:
+		panic(fmt.Sprintf("ast.Modify: %s must stay a type", field))
+	}
+}
@@ -0,0 +1,191 @@
+package ast_test
+
+import (
+	"fmt"
+	"monkey/ast"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspect(t *testing.T) {
+	program := parse(t, `import { a } from "lib";
+let f = fn(x) { if (x > 1) { return -x; } else { [x, 2.5][0] } };
+try { f(1) } catch (e) { {"k": e.message, 3: /r/} } finally { true };
+// done
+`)
+
+	kinds := []string{}
+	depth := 0
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node == nil {
+			depth--
+			return false
+		}
+		kinds = append(kinds, strings.Repeat(" ", depth)+strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast."))
+		depth++
+		return true
+	})
+
+	assert.Equal(t, depth, 0)
+	assert.Equal(t, strings.Join(kinds, "\n"), strings.TrimSpace(`
+Program
+ ImportStatement
+  Identifier
+  StringLiteral
+ LetStatement
+  Identifier
+  FunctionLiteral
+   Identifier
+   BlockStatement
+    ExpressionStatement
+     IfExpression
+      InfixExpression
+       Identifier
+       IntegerLiteral
+      BlockStatement
+       ReturnStatement
+        PrefixExpression
+         Identifier
+      BlockStatement
+       ExpressionStatement
+        IndexExpression
+         ArrayLiteral
+          Identifier
+          FloatLiteral
+         IntegerLiteral
+ ExpressionStatement
+  TryExpression
+   BlockStatement
+    ExpressionStatement
+     CallExpression
+      Identifier
+      IntegerLiteral
+   Identifier
+   BlockStatement
+    ExpressionStatement
+     HashLiteral
+      StringLiteral
+      MemberExpression
+       Identifier
+       Identifier
+      IntegerLiteral
+      RegexLiteral
+   BlockStatement
+    ExpressionStatement
+     BooleanLiteral
+ Comment`))
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parse(t, "let f = fn(x) { x + 1 }; f(2)")
+
+	identifiers := []string{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			identifiers = append(identifiers, ident.Value)
+		}
+		_, isFunction := node.(*ast.FunctionLiteral)
+		return !isFunction
+	})
+
+	assert.Equal(t, identifiers, []string{"f", "f"})
+}
+
+func TestInspectSkipsNilNodes(t *testing.T) {
+	program := &ast.Program{Statements: []ast.Statement{
+		nil,
+		&ast.ExpressionStatement{Expression: &ast.IfExpression{
+			Condition: &ast.PrefixExpression{Operator: "!", Right: (*ast.Identifier)(nil)},
+		}},
+		(*ast.LetStatement)(nil),
+		&ast.ExpressionStatement{Expression: &ast.CallExpression{
+			Function:  &ast.Identifier{Value: "f"},
+			Arguments: []ast.Expression{nil, (*ast.IntegerLiteral)(nil)},
+		}},
+	}}
+
+	kinds := []string{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node != nil {
+			kinds = append(kinds, strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast."))
+		}
+		return true
+	})
+
+	assert.Equal(t, kinds, []string{"Program", "ExpressionStatement", "IfExpression", "PrefixExpression", "ExpressionStatement", "CallExpression", "Identifier"})
+
+	ast.Inspect(nil, func(node ast.Node) bool {
+		t.Errorf("visited %T", node)
+		return true
+	})
+	ast.Inspect((*ast.Program)(nil), func(node ast.Node) bool {
+		t.Errorf("visited %T", node)
+		return true
+	})
+}
+
+func TestModify(t *testing.T) {
+	one := func(node ast.Node) ast.Node {
+		integer, ok := node.(*ast.IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+		integer.Value = 2
+		integer.Token.Literal = "2"
+		return integer
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1", "2"},
+		{"1 + 1", "(2 + 2)"},
+		{"-1", "(-2)"},
+		{"let x = 1;", "let x = 2;"},
+		{"return 1;", "return 2;"},
+		{"if (1) { 1 } else { 1 }", "if2 2else 2"},
+		{"try { 1 } catch (e) { 1 } finally { 1 }", "try 2 catch(e) 2 finally 2"},
+		{"fn(x) { 1 }", "fn(x) 2"},
+		{"f(1, x, 1)", "f(2, x, 2)"},
+		{"[1, [1]]", "[2, [2]]"},
+		{"{1: 1}", "{2: 2}"},
+		{"x[1]", "(x[2])"},
+		{"[1].y", "[2].y"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		assert.Equal(t, ast.Modify(program, one).String(), tt.expected, tt.input)
+	}
+}
+
+func TestModifyRemovesStatements(t *testing.T) {
+	program := parse(t, "let x = 1; print(x); fn() { print(x); x }")
+
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if stmt, ok := node.(*ast.ExpressionStatement); ok {
+			if call, ok := stmt.Expression.(*ast.CallExpression); ok && call.Function.String() == "print" {
+				return nil
+			}
+		}
+		return node
+	})
+
+	assert.Equal(t, program.String(), "let x = 1;fn() x")
+}
+
+func TestModifyRejectsMisplacedNodes(t *testing.T) {
+	program := parse(t, "let x = 1;")
+
+	assert.PanicsWithValue(t, "ast.Modify: LetStatement.Name must stay an *Identifier", func() {
+		ast.Modify(program, func(node ast.Node) ast.Node {
+			if ident, ok := node.(*ast.Identifier); ok {
+				return &ast.StringLiteral{Token: ident.Token, Value: ident.Value}
+			}
+			return node
+		})
+	})
+}
@@ -0,0 +1,574 @@
+// Package checker verifies the type annotations of a Monkey program.
+//
+// Annotations are optional. The checker infers the types of literals,
+// arrays, hashes, functions and builtin calls locally and reports the
+// operations the evaluator would reject with a TypeError or an ArityError,
+// along with values that do not match their annotations. Anything it cannot
+// infer has type any, which is accepted everywhere, so unannotated programs
+// only get errors for code that could never run successfully.
+package checker
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/token"
+	"sort"
+)
+
+// Error is a problem found by the checker.
+type Error struct {
+	Position token.Position
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position, e.Message)
+}
+
+// Check reports the type errors in program sorted by position.
+func Check(program *ast.Program) []*Error {
+	return check(program, nil)
+}
+
+// Infer reports the type errors in program like Check, and returns the type
+// inferred for each of its expressions and for the names declared by its
+// lets and parameters.
+func Infer(program *ast.Program) (map[ast.Expression]Type, []*Error) {
+	inferred := map[ast.Expression]Type{}
+	return inferred, check(program, inferred)
+}
+
+func check(program *ast.Program, inferred map[ast.Expression]Type) []*Error {
+	c := &checker{
+		builtins: evaluator.NewBuiltins(),
+		types:    map[ast.TypeAnnotation]Type{},
+		inferred: inferred,
+		errors:   []*Error{},
+	}
+	c.scope = newScope(nil, false)
+	c.declare(program.Statements)
+	c.statements(program.Statements)
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		a, b := c.errors[i].Position, c.errors[j].Position
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return c.errors
+}
+
+// variable is a name declared in a scope. declared is the type every value
+// bound to it has, which is what functions referring to it can rely on;
+// current is the type of the value bound last while checking the scope.
+type variable struct {
+	declared Type
+	current  Type
+	bound    bool
+}
+
+// scope mirrors the evaluator environments like the resolver does.
+type scope struct {
+	outer     *scope
+	function  bool
+	variables map[string]*variable
+}
+
+func newScope(outer *scope, function bool) *scope {
+	return &scope{outer: outer, function: function, variables: map[string]*variable{}}
+}
+
+// function holds what is known about the function being checked.
+type function struct {
+	result  Type // the annotated result, or nil
+	returns Type // the join of the types returned so far, or nil
+}
+
+type checker struct {
+	scope       *scope
+	function    *function
+	conditional int // nesting of blocks in the current scope that may not run
+	builtins    *object.Builtins
+	types       map[ast.TypeAnnotation]Type
+	inferred    map[ast.Expression]Type // nil unless called by Infer
+	errors      []*Error
+}
+
+// declare adds the lets of stmts to the current scope before they are
+// checked, so functions can refer to lets that follow them. A name bound by
+// a single let takes the type of its annotation, or the signature of the
+// function literal it is bound to.
+func (c *checker) declare(stmts []ast.Statement) {
+	lets := map[string][]*ast.LetStatement{}
+	var declare func(node ast.Node) bool
+	declare = func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			lets[node.Name.Value] = append(lets[node.Name.Value], node)
+		case *ast.ImportStatement:
+			for _, name := range node.Names {
+				c.scope.variables[name.Value] = &variable{declared: ANY, current: ANY}
+			}
+			return false
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.TryExpression:
+			// the catch block has a scope of its own
+			ast.Inspect(node.Block, declare)
+			if node.FinallyBlock != nil {
+				ast.Inspect(node.FinallyBlock, declare)
+			}
+			return false
+		}
+		return true
+	}
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, declare)
+	}
+
+	for name, stmts := range lets {
+		declared := ANY
+		if len(stmts) == 1 {
+			if stmts[0].Type != nil {
+				declared = c.annotation(stmts[0].Type)
+			} else if fn, ok := stmts[0].Value.(*ast.FunctionLiteral); ok {
+				declared = c.signature(fn)
+			}
+		}
+		c.scope.variables[name] = &variable{declared: declared, current: declared}
+	}
+}
+
+// lookup returns the type of the value a name refers to, and the builtin it
+// names when no scope declares it.
+func (c *checker) lookup(name string) (Type, *object.Builtin) {
+	crossed := false // whether a function boundary lies between use and scope
+	for s := c.scope; s != nil; s = s.outer {
+		if v, ok := s.variables[name]; ok {
+			if !crossed && v.bound {
+				return v.current, nil
+			}
+			return v.declared, nil
+		}
+		crossed = crossed || s.function
+	}
+
+	builtin, ok := c.builtins.Get(name)
+	if !ok {
+		return ANY, nil
+	}
+	if builtin.Value != nil {
+		switch builtin.Value.Type() {
+		case object.INTEGER_OBJ:
+			return INT, nil
+		case object.FLOAT_OBJ:
+			return FLOAT, nil
+		}
+		return ANY, nil
+	}
+	if typ, ok := builtinTypes[name]; ok {
+		return typ, builtin
+	}
+	return &functionType{result: ANY}, builtin
+}
+
+func (c *checker) bind(name string, typ Type) {
+	v, ok := c.scope.variables[name]
+	if !ok {
+		v = &variable{declared: ANY}
+		c.scope.variables[name] = v
+	}
+	if c.conditional > 0 && v.bound {
+		v.current = join(v.current, typ)
+	} else {
+		v.current = typ
+	}
+	v.bound = true
+}
+
+// statements checks stmts in order and returns the type of the value they
+// produce, or nil when they always return.
+func (c *checker) statements(stmts []ast.Statement) Type {
+	var result Type = NULL
+	for _, stmt := range stmts {
+		result = c.statement(stmt)
+	}
+	return result
+}
+
+func (c *checker) statement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		if stmt.Expression == nil {
+			return NULL
+		}
+		return c.expression(stmt.Expression)
+	case *ast.LetStatement:
+		c.let(stmt)
+	case *ast.ReturnStatement:
+		c.ret(stmt)
+		return nil
+	case *ast.ImportStatement:
+		for _, name := range stmt.Names {
+			c.bind(name.Value, ANY)
+		}
+	}
+	return ANY
+}
+
+func (c *checker) let(stmt *ast.LetStatement) {
+	typ := ANY
+	if stmt.Value != nil {
+		typ = c.expression(stmt.Value)
+	}
+	if stmt.Type != nil {
+		declared := c.annotation(stmt.Type)
+		if stmt.Value != nil && !assignable(typ, declared) {
+			c.errorf(ast.SpanOf(stmt.Value).Start, "cannot use %s as %s in let %s", typ, declared, stmt.Name.Value)
+		}
+		typ = declared
+	}
+	c.record(stmt.Name, typ)
+	c.bind(stmt.Name.Value, typ)
+}
+
+func (c *checker) ret(stmt *ast.ReturnStatement) {
+	typ := NULL
+	if stmt.ReturnValue != nil {
+		typ = c.expression(stmt.ReturnValue)
+	}
+	if c.function == nil {
+		return
+	}
+	if c.function.result != nil && !assignable(typ, c.function.result) {
+		c.errorf(stmt.Token.Position, "cannot return %s from a function returning %s", typ, c.function.result)
+	}
+	c.function.returns = join(c.function.returns, typ)
+}
+
+// block checks a block that runs only on some paths.
+func (c *checker) block(block *ast.BlockStatement) Type {
+	c.conditional++
+	defer func() { c.conditional-- }()
+	return c.statements(block.Statements)
+}
+
+func (c *checker) expression(expr ast.Expression) Type {
+	typ := c.infer(expr)
+	c.record(expr, typ)
+	return typ
+}
+
+func (c *checker) record(expr ast.Expression, typ Type) {
+	if c.inferred != nil {
+		c.inferred[expr] = typ
+	}
+}
+
+func (c *checker) infer(expr ast.Expression) Type {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return INT
+	case *ast.FloatLiteral:
+		return FLOAT
+	case *ast.StringLiteral:
+		return STRING
+	case *ast.BooleanLiteral:
+		return BOOL
+	case *ast.RegexLiteral:
+		return REGEX
+	case *ast.Identifier:
+		typ, _ := c.lookup(expr.Value)
+		return typ
+	case *ast.PrefixExpression:
+		return c.prefix(expr)
+	case *ast.InfixExpression:
+		return c.infix(expr)
+	case *ast.IfExpression:
+		c.expression(expr.Condition)
+		then := c.block(expr.ThenBranch)
+		if expr.ElseBranch == nil {
+			return join(then, NULL)
+		}
+		return join(then, c.block(expr.ElseBranch))
+	case *ast.TryExpression:
+		return c.try(expr)
+	case *ast.FunctionLiteral:
+		return c.functionLiteral(expr)
+	case *ast.CallExpression:
+		return c.call(expr)
+	case *ast.ArrayLiteral:
+		var element Type
+		for _, elem := range expr.Elements {
+			element = join(element, c.expression(elem))
+		}
+		if element == nil {
+			element = ANY
+		}
+		return &arrayType{element: element}
+	case *ast.HashLiteral:
+		var key, value Type
+		for _, pair := range expr.Pairs {
+			key = join(key, c.expression(pair.Key))
+			value = join(value, c.expression(pair.Value))
+		}
+		if key == nil {
+			key, value = ANY, ANY
+		}
+		return &hashType{key: key, value: value}
+	case *ast.IndexExpression:
+		return c.index(expr)
+	case *ast.MemberExpression:
+		return c.member(expr)
+	}
+	return ANY
+}
+
+func (c *checker) prefix(expr *ast.PrefixExpression) Type {
+	right := c.expression(expr.Right)
+	switch {
+	case expr.Operator == "!":
+		return BOOL
+	case right == ANY || isNumber(right):
+		return right
+	}
+	c.errorf(expr.Token.Position, "unknown operator: %s%s", expr.Operator, right)
+	return ANY
+}
+
+// infix follows the rules of the evaluator for operands of known types.
+func (c *checker) infix(expr *ast.InfixExpression) Type {
+	left, right := c.expression(expr.Left), c.expression(expr.Right)
+	comparison := expr.Operator == "<" || expr.Operator == ">" || expr.Operator == "==" || expr.Operator == "!="
+
+	switch {
+	case left == ANY || right == ANY:
+		if comparison {
+			return BOOL
+		}
+		return ANY
+	case isNumber(left) && isNumber(right):
+		if comparison {
+			return BOOL
+		}
+		return join(left, right)
+	case left == STRING && right == STRING && (comparison || expr.Operator == "+"):
+		if comparison {
+			return BOOL
+		}
+		return STRING
+	case expr.Operator == "==" || expr.Operator == "!=":
+		return BOOL
+	case kind(left) != kind(right):
+		c.errorf(expr.Token.Position, "type mismatch: %s %s %s", left, expr.Operator, right)
+	default:
+		c.errorf(expr.Token.Position, "unknown operator: %s %s %s", left, expr.Operator, right)
+	}
+	return ANY
+}
+
+// kind returns the name of the runtime type of the values of typ.
+func kind(typ Type) string {
+	switch typ.(type) {
+	case *arrayType:
+		return "array"
+	case *hashType:
+		return "hash"
+	case *functionType:
+		return "fn"
+	}
+	return typ.String()
+}
+
+func (c *checker) try(expr *ast.TryExpression) Type {
+	result := c.block(expr.Block)
+	if expr.CatchBlock != nil {
+		c.scope = newScope(c.scope, false)
+		if expr.CatchParameter != nil {
+			c.record(expr.CatchParameter, ERROR)
+			c.bind(expr.CatchParameter.Value, ERROR)
+		}
+		c.declare(expr.CatchBlock.Statements)
+		result = join(result, c.block(expr.CatchBlock))
+		c.scope = c.scope.outer
+	}
+	if expr.FinallyBlock != nil {
+		c.statements(expr.FinallyBlock.Statements)
+	}
+	return result
+}
+
+// signature returns the type of fn given by its annotations alone.
+func (c *checker) signature(fn *ast.FunctionLiteral) *functionType {
+	typ := &functionType{parameters: []Type{}, result: ANY}
+	for i := range fn.Parameters {
+		if i < len(fn.ParameterTypes) && fn.ParameterTypes[i] != nil {
+			typ.parameters = append(typ.parameters, c.annotation(fn.ParameterTypes[i]))
+		} else {
+			typ.parameters = append(typ.parameters, ANY)
+		}
+	}
+	if fn.ReturnType != nil {
+		typ.result = c.annotation(fn.ReturnType)
+	}
+	return typ
+}
+
+func (c *checker) functionLiteral(fn *ast.FunctionLiteral) Type {
+	typ := c.signature(fn)
+
+	outer, conditional := c.function, c.conditional
+	c.scope = newScope(c.scope, true)
+	c.function, c.conditional = &function{}, 0
+	defer func() {
+		c.scope = c.scope.outer
+		c.function, c.conditional = outer, conditional
+	}()
+
+	if fn.ReturnType != nil {
+		c.function.result = typ.result
+	}
+	for i, param := range fn.Parameters {
+		c.record(param, typ.parameters[i])
+		c.scope.variables[param.Value] = &variable{declared: typ.parameters[i]}
+		c.bind(param.Value, typ.parameters[i])
+	}
+	c.declare(fn.Body.Statements)
+
+	last := c.statements(fn.Body.Statements)
+	if last != nil && c.function.result != nil && !assignable(last, c.function.result) {
+		position := fn.Body.Token.Position
+		if n := len(fn.Body.Statements); n > 0 {
+			position = ast.SpanOf(fn.Body.Statements[n-1]).Start
+		}
+		c.errorf(position, "cannot return %s from a function returning %s", last, c.function.result)
+	}
+	if fn.ReturnType == nil {
+		typ.result = join(c.function.returns, last)
+		if typ.result == nil {
+			typ.result = ANY
+		}
+	}
+	return typ
+}
+
+func (c *checker) call(expr *ast.CallExpression) Type {
+	var callee Type
+	var builtin *object.Builtin
+	if ident, ok := expr.Function.(*ast.Identifier); ok {
+		callee, builtin = c.lookup(ident.Value)
+		c.record(ident, callee)
+	} else {
+		callee = c.expression(expr.Function)
+	}
+
+	args := []Type{}
+	for _, arg := range expr.Arguments {
+		args = append(args, c.expression(arg))
+	}
+
+	fn, ok := callee.(*functionType)
+	if !ok {
+		if callee != ANY {
+			c.errorf(expr.Token.Position, "not a function: %s", callee)
+		}
+		return ANY
+	}
+
+	if builtin != nil {
+		if !c.arity(expr, builtin) {
+			return ANY
+		}
+	} else if fn.parameters != nil && len(args) != len(fn.parameters) {
+		c.errorf(expr.Token.Position, "wrong number of arguments. got=%d, want=%d", len(args), len(fn.parameters))
+		return ANY
+	}
+	for i, arg := range args {
+		if i < len(fn.parameters) && !assignable(arg, fn.parameters[i]) {
+			c.errorf(ast.SpanOf(expr.Arguments[i]).Start, "cannot use %s as %s in argument %d to %s",
+				arg, fn.parameters[i], i+1, expr.Function.String())
+		}
+	}
+
+	if builtin != nil && arrayBuiltins[builtin.Name] {
+		return arrayResult(builtin.Name, args)
+	}
+	return fn.result
+}
+
+// arity reports a call to builtin with a wrong number of arguments using
+// the messages of the evaluator.
+func (c *checker) arity(expr *ast.CallExpression, builtin *object.Builtin) bool {
+	if builtin.Params == nil {
+		return true
+	}
+	got := len(expr.Arguments)
+	min, max := builtin.Arity()
+	switch {
+	case max < 0 && got < min:
+		c.errorf(expr.Token.Position, "wrong number of arguments. got=%d, want at least %d", got, min)
+	case max >= 0 && min == max && got != min:
+		c.errorf(expr.Token.Position, "wrong number of arguments. got=%d, want=%d", got, min)
+	case max >= 0 && (got < min || got > max):
+		c.errorf(expr.Token.Position, "wrong number of arguments. got=%d, want %d to %d", got, min, max)
+	default:
+		return true
+	}
+	return false
+}
+
+// arrayResult returns the result of the array builtin name called with args.
+func arrayResult(name string, args []Type) Type {
+	if len(args) == 0 {
+		return ANY
+	}
+	array, ok := args[0].(*arrayType)
+	if !ok {
+		return ANY
+	}
+	switch name {
+	case "first", "last":
+		return array.element
+	case "find":
+		return ANY
+	case "push":
+		return &arrayType{element: join(array.element, args[1])}
+	}
+	return array
+}
+
+func (c *checker) index(expr *ast.IndexExpression) Type {
+	left, index := c.expression(expr.Left), c.expression(expr.Index)
+	switch left := left.(type) {
+	case *arrayType:
+		if index == INT || index == ANY {
+			return left.element
+		}
+	case *hashType:
+		return left.value
+	default:
+		if left == ANY {
+			return ANY
+		}
+	}
+	c.errorf(expr.Token.Position, "index operator not supported: %s", left)
+	return ANY
+}
+
+func (c *checker) member(expr *ast.MemberExpression) Type {
+	obj := c.expression(expr.Object)
+	switch obj := obj.(type) {
+	case *hashType:
+		return obj.value
+	default:
+		if obj == ANY || obj == ERROR {
+			return ANY
+		}
+	}
+	c.errorf(expr.Token.Position, "member access not supported: %s.%s", obj, expr.Property.Value)
+	return ANY
+}
+
+func (c *checker) errorf(position token.Position, format string, a ...interface{}) {
+	c.errors = append(c.errors, &Error{Position: position, Message: fmt.Sprintf(format, a...)})
+}
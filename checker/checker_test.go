@@ -0,0 +1,88 @@
+package checker_test
+
+import (
+	"monkey/checker"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func check(t *testing.T, input string) []string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %v", p.Errors())
+	}
+
+	errors := []string{}
+	for _, err := range checker.Check(program) {
+		errors = append(errors, err.Error())
+	}
+	return errors
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		// unannotated code is only rejected when it can never succeed
+		{"let f = fn(x) { x + 1 }; f(\"a\") + len([1])", []string{}},
+		{"let x = 1; let x = \"a\"; x + \"b\"", []string{}},
+		{"1 + \"a\"", []string{"1:3: type mismatch: int + string"}},
+		{"\"a\" - \"b\"", []string{"1:5: unknown operator: string - string"}},
+		{"true < false", []string{"1:6: unknown operator: bool < bool"}},
+		{"-\"a\"", []string{"1:1: unknown operator: -string"}},
+		{"1 == \"a\"; [1] != {}", []string{}},
+		{"let n = 1; n()", []string{"1:13: not a function: int"}},
+		{"[1][\"a\"]", []string{"1:4: index operator not supported: [int]"}},
+		{"\"abc\"[0]", []string{"1:6: index operator not supported: string"}},
+		{"let s = \"a\"; s.length", []string{"1:15: member access not supported: string.length"}},
+		{"try { 1 } catch (e) { e.message }; {\"a\": 1}.a", []string{}},
+
+		// annotations
+		{"let x: int = 1; let y: float = x; let z: any = \"a\";", []string{}},
+		{"let x: int = \"a\";", []string{"1:14: cannot use string as int in let x"}},
+		{"let xs: [int] = [1, 2.5];", []string{"1:17: cannot use [float] as [int] in let xs"}},
+		{"let xs: [string] = split(\"a b\", \" \"); let h: {string: int} = {\"a\": 1};", []string{}},
+		{"let x: number = 1;", []string{"1:8: unknown type number"}},
+		{"let add = fn(a: int, b: int): int { a + b }; add(1, 2) + add(3, 4)", []string{}},
+		{"let add = fn(a: int, b: int): int { a + b }; add(1, \"2\")", []string{
+			"1:53: cannot use string as int in argument 2 to add",
+		}},
+		{"let add = fn(a: int, b: int): int { a + b }; add(1)", []string{
+			"1:49: wrong number of arguments. got=1, want=2",
+		}},
+		{"let add = fn(a: int, b: int): int { a + b }; let s: string = add(1, 2);", []string{
+			"1:62: cannot use int as string in let s",
+		}},
+		{"fn(a: int): string { a }", []string{"1:22: cannot return int from a function returning string"}},
+		{"fn(a: int): int { if (a > 0) { return \"pos\" }; a }", []string{
+			"1:32: cannot return string from a function returning int",
+		}},
+		{"let twice = fn(f: fn(int): int, x: int): int { f(f(x)) }; twice(fn(n: int): int { n * 2 }, 1)", []string{}},
+		{"let twice = fn(f: fn(int): int, x: int): int { f(f(x)) }; twice(fn(s: string): string { s }, 1)", []string{
+			"1:65: cannot use fn(string): string as fn(int): int in argument 1 to twice",
+		}},
+
+		// inference through functions and builtins
+		{"let double = fn(n: int) { n * 2 }; let s: string = double(2);", []string{
+			"1:52: cannot use int as string in let s",
+		}},
+		{"let f = fn() { g(1) }; let g = fn(a: string) { a };", []string{
+			"1:18: cannot use int as string in argument 1 to g",
+		}},
+		{"let s: string = upper(\"a\") + to_string(1); let n: int = len(s);", []string{}},
+		{"upper(1)", []string{"1:7: cannot use int as string in argument 1 to upper"}},
+		{"len(1, 2)", []string{"1:4: wrong number of arguments. got=2, want=1"}},
+		{"let xs = [\"a\"]; let x: int = first(xs);", []string{"1:30: cannot use string as int in let x"}},
+		{"let xs: [int] = range(3); let r: [int] = push(reverse(xs), 4);", []string{}},
+		{"let x: float = PI * 2; let y: int = sqrt(x);", []string{"1:37: cannot use float as int in let y"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, check(t, tt.input), tt.expected, tt.input)
+	}
+}
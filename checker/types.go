@@ -0,0 +1,243 @@
+package checker
+
+import (
+	"monkey/ast"
+	"strings"
+)
+
+// Type is the static type of an expression.
+type Type interface {
+	String() string
+}
+
+type basicType string
+
+func (typ basicType) String() string { return string(typ) }
+
+var (
+	ANY    Type = basicType("any")
+	INT    Type = basicType("int")
+	FLOAT  Type = basicType("float")
+	STRING Type = basicType("string")
+	BOOL   Type = basicType("bool")
+	REGEX  Type = basicType("regex")
+	ERROR  Type = basicType("error")
+	NULL   Type = basicType("null")
+)
+
+// namedTypes are the types that can be written as a name in annotations.
+var namedTypes = map[string]Type{
+	"any":    ANY,
+	"int":    INT,
+	"float":  FLOAT,
+	"string": STRING,
+	"bool":   BOOL,
+	"regex":  REGEX,
+	"error":  ERROR,
+	"null":   NULL,
+	"array":  &arrayType{element: ANY},
+	"hash":   &hashType{key: ANY, value: ANY},
+}
+
+type arrayType struct {
+	element Type
+}
+
+func (typ *arrayType) String() string { return "[" + typ.element.String() + "]" }
+
+type hashType struct {
+	key   Type
+	value Type
+}
+
+func (typ *hashType) String() string {
+	return "{" + typ.key.String() + ": " + typ.value.String() + "}"
+}
+
+type functionType struct {
+	parameters []Type // nil when the parameters are unknown
+	result     Type
+}
+
+func (typ *functionType) String() string {
+	if typ.parameters == nil {
+		return "fn"
+	}
+	params := []string{}
+	for _, param := range typ.parameters {
+		params = append(params, param.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + "): " + typ.result.String()
+}
+
+func isNumber(typ Type) bool {
+	return typ == INT || typ == FLOAT
+}
+
+// assignable reports whether a value of type from can be used where a value
+// of type to is expected. Any converts both ways, and an int is accepted
+// where a float is expected.
+func assignable(from Type, to Type) bool {
+	if from == ANY || to == ANY || from == to || from == INT && to == FLOAT {
+		return true
+	}
+	switch to := to.(type) {
+	case *arrayType:
+		from, ok := from.(*arrayType)
+		return ok && assignable(from.element, to.element)
+	case *hashType:
+		from, ok := from.(*hashType)
+		return ok && assignable(from.key, to.key) && assignable(from.value, to.value)
+	case *functionType:
+		from, ok := from.(*functionType)
+		if !ok {
+			return false
+		}
+		if from.parameters == nil || to.parameters == nil {
+			return assignable(from.result, to.result)
+		}
+		if len(from.parameters) != len(to.parameters) {
+			return false
+		}
+		for i := range to.parameters {
+			if !assignable(to.parameters[i], from.parameters[i]) {
+				return false
+			}
+		}
+		return assignable(from.result, to.result)
+	}
+	return false
+}
+
+// join returns a type covering both a and b, where nil stands for a branch
+// that never produces a value.
+func join(a Type, b Type) Type {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.String() == b.String():
+		return a
+	case isNumber(a) && isNumber(b):
+		return FLOAT
+	}
+	if a, ok := a.(*arrayType); ok {
+		if b, ok := b.(*arrayType); ok {
+			return &arrayType{element: join(a.element, b.element)}
+		}
+	}
+	return ANY
+}
+
+// annotation converts a type written in the source, reporting unknown names
+// the first time it is converted.
+func (c *checker) annotation(typ ast.TypeAnnotation) Type {
+	if typ == nil {
+		return ANY
+	}
+	if converted, ok := c.types[typ]; ok {
+		return converted
+	}
+	converted := c.convert(typ)
+	c.types[typ] = converted
+	return converted
+}
+
+func (c *checker) convert(typ ast.TypeAnnotation) Type {
+	switch typ := typ.(type) {
+	case *ast.NamedType:
+		if named, ok := namedTypes[typ.Token.Literal]; ok {
+			return named
+		}
+		c.errorf(typ.Token.Position, "unknown type %s", typ.Token.Literal)
+		return ANY
+	case *ast.ArrayType:
+		return &arrayType{element: c.annotation(typ.Element)}
+	case *ast.HashType:
+		return &hashType{key: c.annotation(typ.Key), value: c.annotation(typ.Value)}
+	case *ast.FunctionType:
+		function := &functionType{parameters: []Type{}, result: ANY}
+		for _, param := range typ.Parameters {
+			function.parameters = append(function.parameters, c.annotation(param))
+		}
+		if typ.Return != nil {
+			function.result = c.annotation(typ.Return)
+		}
+		return function
+	}
+	return ANY
+}
+
+func fn(result Type, parameters ...Type) *functionType {
+	return &functionType{parameters: parameters, result: result}
+}
+
+func array(element Type) Type {
+	return &arrayType{element: element}
+}
+
+// builtinTypes gives the types of the builtins whose signatures do not
+// depend on their arguments. Parameters past the end of the list, and those
+// of builtins missing here, accept any value.
+var builtinTypes = map[string]*functionType{
+	"len":            fn(INT, ANY),
+	"error":          fn(ERROR, STRING),
+	"to_int":         fn(INT, ANY, INT),
+	"to_float":       fn(FLOAT, ANY),
+	"to_string":      fn(STRING, ANY, INT),
+	"hex":            fn(STRING, INT),
+	"oct":            fn(STRING, INT),
+	"bin":            fn(STRING, INT),
+	"upper":          fn(STRING, STRING),
+	"lower":          fn(STRING, STRING),
+	"trim":           fn(STRING, STRING, STRING),
+	"repeat":         fn(STRING, STRING, INT),
+	"pad_left":       fn(STRING, STRING, INT, STRING),
+	"pad_right":      fn(STRING, STRING, INT, STRING),
+	"chars":          fn(array(STRING), STRING),
+	"split":          fn(array(STRING), STRING),
+	"join":           fn(STRING, array(ANY), STRING),
+	"starts_with":    fn(BOOL, STRING, STRING),
+	"ends_with":      fn(BOOL, STRING, STRING),
+	"contains":       fn(BOOL),
+	"index_of":       fn(INT),
+	"regex":          fn(REGEX, STRING, STRING),
+	"json_parse":     fn(ANY, STRING),
+	"json_stringify": fn(STRING),
+	"range":          fn(array(INT), INT, INT, INT),
+	"any":            fn(BOOL, array(ANY)),
+	"all":            fn(BOOL, array(ANY)),
+	"map":            fn(array(ANY), array(ANY)),
+	"zip":            fn(array(ANY)),
+	"concat":         fn(array(ANY)),
+	"flatten":        fn(array(ANY), array(ANY), INT),
+	"sqrt":           fn(FLOAT, FLOAT),
+	"sin":            fn(FLOAT, FLOAT),
+	"cos":            fn(FLOAT, FLOAT),
+	"tan":            fn(FLOAT, FLOAT),
+	"asin":           fn(FLOAT, FLOAT),
+	"acos":           fn(FLOAT, FLOAT),
+	"atan":           fn(FLOAT, FLOAT),
+	"atan2":          fn(FLOAT, FLOAT, FLOAT),
+	"exp":            fn(FLOAT, FLOAT),
+	"log":            fn(FLOAT, FLOAT),
+	"log2":           fn(FLOAT, FLOAT),
+	"log10":          fn(FLOAT, FLOAT),
+	"floor":          fn(INT, FLOAT),
+	"ceil":           fn(INT, FLOAT),
+}
+
+// arrayBuiltins return an array of the same type as their first argument,
+// or one of its elements; see arrayResult.
+var arrayBuiltins = map[string]bool{
+	"filter":  true,
+	"reverse": true,
+	"rest":    true,
+	"sort":    true,
+	"unique":  true,
+	"push":    true,
+	"first":   true,
+	"last":    true,
+	"find":    true,
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"monkey"
+	"monkey/ast"
+	"os"
+)
+
+// astCommand prints the syntax tree of a file, or of standard input when the
+// path is "-" or missing.
+func astCommand(args []string) {
+	flags := flag.NewFlagSet("ast", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the tree as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey ast [-json] [file]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	source, err := readSource(flags.Arg(0))
+	if err != nil {
+		printError(err)
+		os.Exit(1)
+	}
+	program, err := monkey.Parse(string(source))
+	if err != nil {
+		printError(err)
+		os.Exit(1)
+	}
+
+	if !*asJSON {
+		fmt.Println(program.String())
+		return
+	}
+
+	data, err := ast.EncodeJSON(program)
+	if err != nil {
+		printError(err)
+		os.Exit(1)
+	}
+	var out bytes.Buffer
+	json.Indent(&out, data, "", "  ")
+	out.WriteByte('\n')
+	out.WriteTo(os.Stdout)
+}
+
+func readSource(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
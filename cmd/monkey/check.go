@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey"
+	"monkey/checker"
+	"os"
+)
+
+// checkCommand reports type errors in source files, or in standard input
+// when no path is given, and exits with status 1 when there are any.
+func checkCommand(args []string) {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey check [path ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	failed := false
+	ok := eachSource(paths, func(path string) bool {
+		errs, ok := checkFile(path)
+		for _, err := range errs {
+			fmt.Printf("%s:%d:%d: %s\n", displayName(path), err.Position.Line, err.Position.Column, err.Message)
+		}
+		failed = failed || len(errs) > 0
+		return ok
+	})
+	if !ok || failed {
+		os.Exit(1)
+	}
+}
+
+func checkFile(path string) ([]*checker.Error, bool) {
+	source, err := readSource(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+	program, err := monkey.Parse(string(source))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, displayName(path)+":")
+		printError(err)
+		return nil, false
+	}
+	return checker.Check(program), true
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/dap"
+	"os"
+)
+
+// debugCommand runs a debug adapter speaking the Debug Adapter Protocol over
+// standard input and output. The program to debug and its breakpoints are
+// chosen by the client.
+func debugCommand(args []string) {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey debug")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if err := dap.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DIFF_CONTEXT is the number of unchanged lines shown around each change.
+const DIFF_CONTEXT = 3
+
+// unifiedDiff returns the changes turning before into after in unified diff
+// format, or "" when they are equal.
+func unifiedDiff(name string, before string, after string) string {
+	if before == after {
+		return ""
+	}
+	a, b := splitLines(before), splitLines(after)
+	edits := diffLines(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s (formatted)\n", name, name)
+	for start := 0; start < len(edits); {
+		// find the next change and the extent of its hunk
+		for start < len(edits) && edits[start].kind == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		first := start - DIFF_CONTEXT
+		if first < 0 {
+			first = 0
+		}
+		last := start
+		for i := start; i < len(edits) && i <= last+2*DIFF_CONTEXT; i++ {
+			if edits[i].kind != ' ' {
+				last = i
+			}
+		}
+		end := last + DIFF_CONTEXT + 1
+		if end > len(edits) {
+			end = len(edits)
+		}
+
+		hunk := edits[first:end]
+		aStart, bStart := hunk[0].a, hunk[0].b
+		aCount, bCount := 0, 0
+		for _, edit := range hunk {
+			if edit.kind != '+' {
+				aCount++
+			}
+			if edit.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, edit := range hunk {
+			out.WriteString(string(edit.kind) + edit.line + "\n")
+		}
+		start = end
+	}
+	return out.String()
+}
+
+type lineEdit struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	a, b int // lines of before and after reached, counted from 0
+}
+
+// diffLines computes a shortest edit script with a longest common
+// subsequence table, after skipping the common prefix and suffix.
+func diffLines(a []string, b []string) []lineEdit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	edits := []lineEdit{}
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, lineEdit{' ', a[i], i, i})
+	}
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			edits = append(edits, lineEdit{' ', midA[i], prefix + i, prefix + j})
+			i++
+			j++
+		case j < len(midB) && (i == len(midA) || lcs[i][j+1] >= lcs[i+1][j]):
+			edits = append(edits, lineEdit{'+', midB[j], prefix + i, prefix + j})
+			j++
+		default:
+			edits = append(edits, lineEdit{'-', midA[i], prefix + i, prefix + j})
+			i++
+		}
+	}
+	for k := 0; k < suffix; k++ {
+		edits = append(edits, lineEdit{' ', a[len(a)-suffix+k], len(a) - suffix + k, len(b) - suffix + k})
+	}
+	return edits
+}
+
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
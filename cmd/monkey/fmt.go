@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"monkey"
+	"monkey/formatter"
+	"os"
+)
+
+// fmtCommand formats source files, or standard input when no path is given.
+func fmtCommand(args []string) {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the file instead of standard output")
+	diff := flags.Bool("d", false, "print a diff instead of the formatted source")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey fmt [-w] [-d] [path ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "monkey fmt: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		if !formatFile("-", false, *diff) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	ok := eachSource(flags.Args(), func(path string) bool {
+		return formatFile(path, *write, *diff)
+	})
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// formatFile formats one file, reporting whether it could be parsed.
+func formatFile(path string, write bool, diff bool) bool {
+	source, err := readSource(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	program, err := monkey.Parse(string(source))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, displayName(path)+":")
+		printError(err)
+		return false
+	}
+
+	formatted := []byte(formatter.Format(program))
+	if bytes.Equal(source, formatted) && (write || diff) {
+		return true
+	}
+	if diff {
+		fmt.Print(unifiedDiff(displayName(path), string(source), string(formatted)))
+	}
+	if write {
+		if err := os.WriteFile(path, formatted, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+	}
+	if !write && !diff {
+		os.Stdout.Write(formatted)
+	}
+	return true
+}
+
+func displayName(path string) string {
+	if path == "-" {
+		return "<stdin>"
+	}
+	return path
+}
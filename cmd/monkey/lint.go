@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"monkey"
+	"monkey/lint"
+	"os"
+	"strings"
+)
+
+// lintCommand reports lint diagnostics for source files, or for standard
+// input when no path is given, and exits with status 1 when there are any.
+func lintCommand(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the diagnostics as a JSON array")
+	enable := flags.String("enable", "", "comma-separated rules to run instead of all of them")
+	disable := flags.String("disable", "", "comma-separated rules to skip")
+	list := flags.Bool("rules", false, "list the rules and exit")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: monkey lint [-json] [-enable rules] [-disable rules] [path ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *list {
+		for _, rule := range lint.Rules() {
+			fmt.Printf("%-20s %s\n", rule.Name, rule.Doc)
+		}
+		return
+	}
+
+	config := lint.Config{Enable: splitList(*enable), Disable: splitList(*disable)}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	reports := []lintReport{}
+	ok := eachSource(paths, func(path string) bool {
+		diagnostics, ok := lintFile(path, config)
+		for _, diagnostic := range diagnostics {
+			reports = append(reports, lintReport{
+				File:    displayName(path),
+				Line:    diagnostic.Position.Line,
+				Column:  diagnostic.Position.Column,
+				Rule:    diagnostic.Rule,
+				Message: diagnostic.Message,
+			})
+		}
+		return ok
+	})
+
+	if *asJSON {
+		data, _ := json.MarshalIndent(reports, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		for _, report := range reports {
+			fmt.Printf("%s:%d:%d: %s (%s)\n", report.File, report.Line, report.Column, report.Message, report.Rule)
+		}
+	}
+	if !ok || len(reports) > 0 {
+		os.Exit(1)
+	}
+}
+
+type lintReport struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func lintFile(path string, config lint.Config) ([]lint.Diagnostic, bool) {
+	source, err := readSource(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, false
+	}
+	program, err := monkey.Parse(string(source))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, displayName(path)+":")
+		printError(err)
+		return nil, false
+	}
+
+	diagnostics, err := lint.Lint(program, config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return diagnostics, true
+}
+
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
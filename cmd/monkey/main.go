@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"monkey"
+	"monkey/object"
+	"monkey/repl"
+	"monkey/tracer"
+	"os"
+	"path/filepath"
+)
+
+// commands maps subcommand names to their entry points, which receive the
+// arguments following the name.
+var commands = map[string]func(args []string){
+	"ast":   astCommand,
+	"check": checkCommand,
+	"debug": debugCommand,
+	"fmt":   fmtCommand,
+	"lint":  lintCommand,
+	"lsp":   lspCommand,
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
+	noPrelude := flag.Bool("no-prelude", false, "start without the standard prelude")
+	noOptimize := flag.Bool("no-optimize", false, "evaluate programs without folding constant expressions first")
+	trace := flag.String("trace", "", "write an execution trace of the script to stderr in `format` (text or json)")
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		runFile(flag.Arg(0), *noPrelude, *noOptimize, *trace)
+		return
+	}
+
+	fmt.Printf("MonkeyLang.\n")
+	repl.Start(os.Stdin, os.Stdout, !*noPrelude, !*noOptimize)
+}
+
+func runFile(path string, noPrelude bool, noOptimize bool, trace string) {
+	var options []monkey.Option
+	if noPrelude {
+		options = append(options, monkey.WithoutPrelude())
+	}
+	if noOptimize {
+		options = append(options, monkey.WithoutOptimizer())
+	}
+	switch trace {
+	case "":
+	case "text":
+		options = append(options, monkey.WithTracer(tracer.NewText(os.Stderr)))
+	case "json":
+		options = append(options, monkey.WithTracer(tracer.NewJSON(os.Stderr)))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown trace format %q, want text or json\n", trace)
+		os.Exit(2)
+	}
+
+	interp := monkey.New(options...)
+	if _, err := interp.RunFile(context.Background(), path); err != nil {
+		printError(err)
+		os.Exit(1)
+	}
+}
+
+func printError(err error) {
+	var parseErr *monkey.ParseError
+	if errors.As(err, &parseErr) {
+		for _, msg := range parseErr.Errors {
+			fmt.Fprintln(os.Stderr, "\t"+msg)
+		}
+		return
+	}
+
+	var runtimeErr *object.Error
+	if errors.As(err, &runtimeErr) {
+		fmt.Fprintln(os.Stderr, runtimeErr.Inspect())
+		fmt.Fprint(os.Stderr, runtimeErr.StackTrace())
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, err)
+}
+
+// eachSource calls fn for each path, searching directories for .monkey and
+// .mk files, and reports whether every call and directory read succeeded.
+func eachSource(paths []string, fn func(path string) bool) bool {
+	ok := true
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				return nil
+			}
+			if file == path || filepath.Ext(file) == ".monkey" || filepath.Ext(file) == ".mk" {
+				ok = fn(file) && ok
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			ok = false
+		}
+	}
+	return ok
+}
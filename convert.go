@@ -0,0 +1,474 @@
+package monkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"monkey/evaluator"
+	"monkey/object"
+	"reflect"
+)
+
+// MAX_CONVERT_DEPTH bounds the nesting of converted values so that cyclic
+// structures fail instead of recursing forever.
+const MAX_CONVERT_DEPTH = 100
+
+var (
+	objectType  = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	bigIntType  = reflect.TypeOf(big.Int{})
+)
+
+// ToObject converts a Go value to a Monkey object. Integers (including
+// *big.Int), floats, strings, bools, slices, arrays, maps, structs, pointers to those and
+// functions are supported; object.Object values are returned unchanged.
+// Struct fields are exposed under their name or their `monkey:"name"` tag.
+func ToObject(value interface{}) (object.Object, error) {
+	return toObject(reflect.ValueOf(value), 0)
+}
+
+// ToGo converts a Monkey object to its natural Go representation: int64,
+// *big.Int, float64, string, bool, nil, []interface{} or map[string]interface{}.
+// Functions and other objects without a Go counterpart are returned as is.
+func ToGo(obj object.Object) interface{} {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Value
+	case *object.BigInt:
+		return new(big.Int).Set(obj.Value)
+	case *object.Float:
+		return obj.Value
+	case *object.String:
+		return obj.Value
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return nil
+	case *object.Array:
+		elements := make([]interface{}, len(obj.Elements))
+		for i, elem := range obj.Elements {
+			elements[i] = ToGo(elem)
+		}
+		return elements
+	case *object.Hash:
+		pairs := make(map[string]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			pairs[pair.Key.Inspect()] = ToGo(pair.Value)
+		}
+		return pairs
+	default:
+		return obj
+	}
+}
+
+// Decode stores the Go conversion of obj in the value pointed to by target.
+func Decode(obj object.Object, target interface{}) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("monkey: Decode target must be a non-nil pointer, got %T", target)
+	}
+
+	value, err := fromObject(obj, ptr.Type().Elem(), 0)
+	if err != nil {
+		return err
+	}
+	ptr.Elem().Set(value)
+	return nil
+}
+
+// RegisterFunc registers a Go function as a builtin named name, which may be
+// namespaced as in "db.query". Arguments are converted from
+// Monkey objects to the function's parameter types and checked for arity;
+// a first parameter of type context.Context receives the running context.
+// The function may return nothing, a value, an error, or a value and an
+// error; a non-nil error is raised in the script.
+func (interp *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func {
+		return fmt.Errorf("monkey: RegisterFunc expects a function, got %T", fn)
+	}
+
+	builtin, err := wrapFunc(name, value)
+	if err != nil {
+		return err
+	}
+	interp.runtime.Builtins.Register(builtin)
+	return nil
+}
+
+func toObject(value reflect.Value, depth int) (object.Object, error) {
+	if depth > MAX_CONVERT_DEPTH {
+		return nil, errors.New("monkey: value is nested too deeply, is it cyclic?")
+	}
+
+	if !value.IsValid() {
+		return evaluator.NULL, nil
+	}
+	if value.Type().Implements(objectType) && !(value.Kind() == reflect.Ptr && value.IsNil()) {
+		return value.Interface().(object.Object), nil
+	}
+	if value.Type() == bigIntType {
+		integer := value.Interface().(big.Int)
+		return object.IntegerFromBig(new(big.Int).Set(&integer)), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		if value.Bool() {
+			return evaluator.TRUE, nil
+		}
+		return evaluator.FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: value.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return object.IntegerFromBig(new(big.Int).SetUint64(value.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: value.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: value.String()}, nil
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return toObject(value.Elem(), depth+1)
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return evaluator.NULL, nil
+		}
+		elements := make([]object.Object, value.Len())
+		for i := range elements {
+			elem, err := toObject(value.Index(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = elem
+		}
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		if value.IsNil() {
+			return evaluator.NULL, nil
+		}
+		hash := object.NewHash()
+		iter := value.MapRange()
+		for iter.Next() {
+			key, err := toObject(iter.Key(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := key.(object.Hashable); !ok {
+				return nil, fmt.Errorf("monkey: %s is unusable as hash key", key.Type())
+			}
+			elem, err := toObject(iter.Value(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(key, elem)
+		}
+		return hash, nil
+	case reflect.Struct:
+		hash := object.NewHash()
+		for i := 0; i < value.NumField(); i++ {
+			name, ok := fieldName(value.Type().Field(i))
+			if !ok {
+				continue
+			}
+			elem, err := toObject(value.Field(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(&object.String{Value: name}, elem)
+		}
+		return hash, nil
+	case reflect.Func:
+		if value.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return wrapFunc("<native>", value)
+	default:
+		return nil, fmt.Errorf("monkey: cannot convert %s to a Monkey value", value.Type())
+	}
+}
+
+func fromObject(obj object.Object, typ reflect.Type, depth int) (reflect.Value, error) {
+	if depth > MAX_CONVERT_DEPTH {
+		return reflect.Value{}, errors.New("monkey: value is nested too deeply, is it cyclic?")
+	}
+
+	if typ.Kind() == reflect.Interface && typ.NumMethod() == 0 {
+		natural := ToGo(obj)
+		if natural == nil {
+			return reflect.Zero(typ), nil
+		}
+		return reflect.ValueOf(natural), nil
+	}
+	if reflect.TypeOf(obj).AssignableTo(typ) {
+		return reflect.ValueOf(obj), nil
+	}
+	if obj.Type() == object.NULL_OBJ {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			return reflect.Zero(typ), nil
+		}
+	}
+
+	if typ == bigIntType {
+		switch number := obj.(type) {
+		case *object.Integer:
+			return reflect.ValueOf(big.NewInt(number.Value)).Elem(), nil
+		case *object.BigInt:
+			return reflect.ValueOf(new(big.Int).Set(number.Value)).Elem(), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot use %s as %s", obj.Type(), object.INTEGER_OBJ)
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		if b, ok := obj.(*object.Boolean); ok {
+			return reflect.ValueOf(b.Value).Convert(typ), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := obj.(*object.Integer); ok {
+			value := reflect.New(typ).Elem()
+			if value.OverflowInt(i.Value) {
+				return reflect.Value{}, fmt.Errorf("%d overflows %s", i.Value, typ)
+			}
+			value.SetInt(i.Value)
+			return value, nil
+		}
+		if i, ok := obj.(*object.BigInt); ok {
+			return reflect.Value{}, fmt.Errorf("%s overflows %s", i.Inspect(), typ)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := obj.(*object.Integer); ok {
+			value := reflect.New(typ).Elem()
+			if i.Value < 0 || value.OverflowUint(uint64(i.Value)) {
+				return reflect.Value{}, fmt.Errorf("%d overflows %s", i.Value, typ)
+			}
+			value.SetUint(uint64(i.Value))
+			return value, nil
+		}
+		if i, ok := obj.(*object.BigInt); ok {
+			value := reflect.New(typ).Elem()
+			if !i.Value.IsUint64() || value.OverflowUint(i.Value.Uint64()) {
+				return reflect.Value{}, fmt.Errorf("%s overflows %s", i.Inspect(), typ)
+			}
+			value.SetUint(i.Value.Uint64())
+			return value, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch number := obj.(type) {
+		case *object.Float:
+			return reflect.ValueOf(number.Value).Convert(typ), nil
+		case *object.Integer:
+			return reflect.ValueOf(float64(number.Value)).Convert(typ), nil
+		case *object.BigInt:
+			float, _ := new(big.Float).SetInt(number.Value).Float64()
+			return reflect.ValueOf(float).Convert(typ), nil
+		}
+	case reflect.String:
+		if s, ok := obj.(*object.String); ok {
+			return reflect.ValueOf(s.Value).Convert(typ), nil
+		}
+	case reflect.Ptr:
+		elem, err := fromObject(obj, typ.Elem(), depth+1)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	case reflect.Slice:
+		if arr, ok := obj.(*object.Array); ok {
+			slice := reflect.MakeSlice(typ, len(arr.Elements), len(arr.Elements))
+			for i, elem := range arr.Elements {
+				value, err := fromObject(elem, typ.Elem(), depth+1)
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				slice.Index(i).Set(value)
+			}
+			return slice, nil
+		}
+	case reflect.Array:
+		if arr, ok := obj.(*object.Array); ok {
+			if len(arr.Elements) != typ.Len() {
+				return reflect.Value{}, fmt.Errorf("cannot use ARRAY of length %d as %s", len(arr.Elements), typ)
+			}
+			array := reflect.New(typ).Elem()
+			for i, elem := range arr.Elements {
+				value, err := fromObject(elem, typ.Elem(), depth+1)
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				array.Index(i).Set(value)
+			}
+			return array, nil
+		}
+	case reflect.Map:
+		if hash, ok := obj.(*object.Hash); ok {
+			m := reflect.MakeMapWithSize(typ, len(hash.Pairs))
+			for _, pair := range hash.Pairs {
+				key, err := fromObject(pair.Key, typ.Key(), depth+1)
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				value, err := fromObject(pair.Value, typ.Elem(), depth+1)
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				m.SetMapIndex(key, value)
+			}
+			return m, nil
+		}
+	case reflect.Struct:
+		if hash, ok := obj.(*object.Hash); ok {
+			value := reflect.New(typ).Elem()
+			for i := 0; i < typ.NumField(); i++ {
+				name, ok := fieldName(typ.Field(i))
+				if !ok {
+					continue
+				}
+				elem, ok := hash.Get(&object.String{Value: name})
+				if !ok {
+					continue
+				}
+				field, err := fromObject(elem, typ.Field(i).Type, depth+1)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("field %s: %w", name, err)
+				}
+				value.Field(i).Set(field)
+			}
+			return value, nil
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", obj.Type(), typeName(typ))
+}
+
+func wrapFunc(name string, fn reflect.Value) (*object.Builtin, error) {
+	typ := fn.Type()
+
+	numOut := typ.NumOut()
+	returnsError := numOut > 0 && typ.Out(numOut-1) == errorType
+	if numOut > 2 || (numOut == 2 && !returnsError) {
+		return nil, fmt.Errorf("monkey: %s must return at most a value and an error, got %s", name, typ)
+	}
+
+	params := []reflect.Type{}
+	takesContext := typ.NumIn() > 0 && typ.In(0) == contextType
+	for i := 0; i < typ.NumIn(); i++ {
+		if i == 0 && takesContext {
+			continue
+		}
+		params = append(params, typ.In(i))
+	}
+
+	call := func(env *object.Environment, args ...object.Object) object.Object {
+		if typ.IsVariadic() {
+			if len(args) < len(params)-1 {
+				return newError(object.ARITY_ERROR, "wrong number of arguments to `%s`. got=%d, want at least %d", name, len(args), len(params)-1)
+			}
+		} else if len(args) != len(params) {
+			return newError(object.ARITY_ERROR, "wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), len(params))
+		}
+
+		in := []reflect.Value{}
+		if takesContext {
+			ctx := context.Background()
+			if runtime := env.Runtime(); runtime != nil && runtime.Context != nil {
+				ctx = runtime.Context
+			}
+			in = append(in, reflect.ValueOf(ctx))
+		}
+		for i, arg := range args {
+			var paramType reflect.Type
+			if typ.IsVariadic() && i >= len(params)-1 {
+				paramType = params[len(params)-1].Elem()
+			} else {
+				paramType = params[i]
+			}
+			value, err := fromObject(arg, paramType, 0)
+			if err != nil {
+				return newError(object.TYPE_ERROR, "argument %d to `%s`: %s", i+1, name, err)
+			}
+			in = append(in, value)
+		}
+
+		out := fn.Call(in)
+
+		if returnsError {
+			if err, _ := out[numOut-1].Interface().(error); err != nil {
+				return goError(err)
+			}
+			out = out[:numOut-1]
+		}
+		if len(out) == 0 {
+			return evaluator.NULL
+		}
+
+		result, err := toObject(out[0], 0)
+		if err != nil {
+			return newError(object.TYPE_ERROR, "result of `%s`: %s", name, err)
+		}
+		return result
+	}
+
+	return &object.Builtin{Name: name, Fn: call}, nil
+}
+
+// goError raises err in the script. Errors wrapping an object.ErrorKind, as
+// in fmt.Errorf("%w: no such user", object.NAME_ERROR), keep that kind.
+func goError(err error) *object.Error {
+	var objErr *object.Error
+	if errors.As(err, &objErr) {
+		// the evaluator appends stack frames to the error it is given, so a
+		// sentinel error returned by the host must not be shared
+		copied := *objErr
+		copied.Stack = append([]object.StackFrame(nil), objErr.Stack...)
+		return &copied
+	}
+
+	kind := object.RUNTIME_ERROR
+	errors.As(err, &kind)
+	return &object.Error{Kind: kind, Message: err.Error(), Cause: err}
+}
+
+func newError(kind object.ErrorKind, format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
+}
+
+func fieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	switch tag := field.Tag.Get("monkey"); tag {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	default:
+		return tag, true
+	}
+}
+
+func typeName(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.Bool:
+		return object.BOOLEAN_OBJ
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return object.INTEGER_OBJ
+	case reflect.Float32, reflect.Float64:
+		return object.FLOAT_OBJ
+	case reflect.String:
+		return object.STRING_OBJ
+	case reflect.Slice, reflect.Array:
+		return object.ARRAY_OBJ
+	case reflect.Map, reflect.Struct:
+		return object.HASH_OBJ
+	default:
+		return typ.String()
+	}
+}
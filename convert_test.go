@@ -0,0 +1,185 @@
+package monkey_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"monkey"
+	"monkey/object"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type user struct {
+	Name    string   `monkey:"name"`
+	Age     int      `monkey:"age"`
+	Tags    []string `monkey:"tags"`
+	Admin   bool
+	Secret  string `monkey:"-"`
+	private int
+}
+
+func TestToObject(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{2.5, "2.5"},
+		{float32(1), "1.0"},
+		{"hi", "hi"},
+		{true, "true"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]string{"a", "b"}, "[a, b]"},
+		{map[string]int{"b": 2, "a": 1}, "{a: 1, b: 2}"},
+		{map[int]bool{1: true}, "{1: true}"},
+		{&user{Name: "ann", Age: 30, Secret: "x"}, "{Admin: false, age: 30, name: ann, tags: null}"},
+		{(*user)(nil), "null"},
+		{[]interface{}{1, "a", nil}, "[1, a, null]"},
+	}
+
+	for _, tt := range tests {
+		obj, err := monkey.ToObject(tt.input)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, obj.Inspect(), tt.expected)
+	}
+
+	_, err := monkey.ToObject(make(chan int))
+	assert.EqualError(t, err, "monkey: cannot convert chan int to a Monkey value")
+
+	obj, err := monkey.ToObject(uint64(1 << 63))
+	assert.NoError(t, err)
+	assert.Equal(t, obj.Inspect(), "9223372036854775808")
+
+	cyclic := []interface{}{nil}
+	cyclic[0] = cyclic
+	_, err = monkey.ToObject(cyclic)
+	assert.Error(t, err)
+}
+
+func TestDecode(t *testing.T) {
+	interp := monkey.New()
+
+	result, err := interp.Run(context.Background(), `{"name": "bob", "age": 41, "tags": ["a", "b"], "Admin": true}`)
+	assert.NoError(t, err)
+
+	var u user
+	assert.NoError(t, monkey.Decode(result, &u))
+	assert.Equal(t, u, user{Name: "bob", Age: 41, Tags: []string{"a", "b"}, Admin: true})
+
+	var m map[string]interface{}
+	assert.NoError(t, monkey.Decode(result, &m))
+	assert.Equal(t, m["age"], int64(41))
+	assert.Equal(t, m["tags"], []interface{}{"a", "b"})
+
+	var small int8
+	assert.EqualError(t, monkey.Decode(&object.Integer{Value: 300}, &small), "300 overflows int8")
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	var n *big.Int
+	assert.NoError(t, monkey.Decode(&object.BigInt{Value: huge}, &n))
+	assert.Equal(t, n.String(), "123456789012345678901234567890")
+	assert.EqualError(t, monkey.Decode(&object.BigInt{Value: huge}, new(int64)), "123456789012345678901234567890 overflows int64")
+
+	var s string
+	assert.EqualError(t, monkey.Decode(&object.Integer{Value: 1}, &s), "cannot use INTEGER as STRING")
+	assert.Error(t, monkey.Decode(&object.Integer{Value: 1}, s))
+}
+
+var errNotFound = &object.Error{Kind: object.NAME_ERROR, Message: "not found"}
+
+func TestRegisterFuncSharedError(t *testing.T) {
+	interp := monkey.New()
+	assert.NoError(t, interp.RegisterFunc("find", func() error { return errNotFound }))
+
+	for i := 0; i < 2; i++ {
+		_, err := interp.Run(context.Background(), "let f = fn() { find() }; f()")
+		assert.EqualError(t, err, "NameError: not found")
+
+		var objErr *object.Error
+		assert.True(t, errors.As(err, &objErr))
+		assert.Len(t, objErr.Stack, 1)
+	}
+	assert.Empty(t, errNotFound.Stack)
+}
+
+func TestRegisterFunc(t *testing.T) {
+	interp := monkey.New()
+
+	assert.NoError(t, interp.RegisterFunc("upper", strings.ToUpper))
+	assert.NoError(t, interp.RegisterFunc("sum", func(xs ...int) int {
+		total := 0
+		for _, x := range xs {
+			total += x
+		}
+		return total
+	}))
+	assert.NoError(t, interp.RegisterFunc("lookup", func(id int) (*user, error) {
+		if id < 0 {
+			return nil, fmt.Errorf("invalid id %d: %w", id, object.INDEX_ERROR)
+		}
+		if id != 1 {
+			return nil, &object.Error{Kind: object.NAME_ERROR, Message: fmt.Sprintf("no user %d", id)}
+		}
+		return &user{Name: "ann", Age: 30}, nil
+	}))
+	assert.NoError(t, interp.RegisterFunc("describe", func(u user) string {
+		return fmt.Sprintf("%s (%d)", u.Name, u.Age)
+	}))
+	assert.NoError(t, interp.RegisterFunc("deadline", func(ctx context.Context) bool {
+		return ctx.Err() == nil
+	}))
+	assert.NoError(t, interp.RegisterFunc("half", func(x float64) float64 { return x / 2 }))
+	assert.NoError(t, interp.RegisterFunc("noop", func() {}))
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`upper("monkey")`, "MONKEY"},
+		{`sum()`, "0"},
+		{`sum(1, 2, 3)`, "6"},
+		{`lookup(1).name`, "ann"},
+		{`describe(lookup(1))`, "ann (30)"},
+		{`describe({"name": "bob", "age": 2})`, "bob (2)"},
+		{`deadline()`, "true"},
+		{`half(3)`, "1.5"},
+		{`noop()`, "null"},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Run(context.Background(), tt.input)
+		if !assert.NoError(t, err, tt.input) {
+			continue
+		}
+		assert.Equal(t, result.Inspect(), tt.expected)
+	}
+
+	errorTests := []struct {
+		input    string
+		kind     object.ErrorKind
+		expected string
+	}{
+		{`upper()`, object.ARITY_ERROR, "ArityError: wrong number of arguments to `upper`. got=0, want=1"},
+		{`upper(1)`, object.TYPE_ERROR, "TypeError: argument 1 to `upper`: cannot use INTEGER as STRING"},
+		{`sum(1, "a")`, object.TYPE_ERROR, "TypeError: argument 2 to `sum`: cannot use STRING as INTEGER"},
+		{`lookup(2)`, object.NAME_ERROR, "NameError: no user 2"},
+		{`lookup(-1)`, object.INDEX_ERROR, "IndexError: invalid id -1: IndexError"},
+	}
+
+	for _, tt := range errorTests {
+		_, err := interp.Run(context.Background(), tt.input)
+		assert.True(t, errors.Is(err, tt.kind), tt.input)
+		assert.EqualError(t, err, tt.expected)
+	}
+
+	assert.Error(t, interp.RegisterFunc("bad", 42))
+	assert.Error(t, interp.RegisterFunc("bad", func() (int, int) { return 1, 2 }))
+}
@@ -0,0 +1,390 @@
+// Package dap implements a Debug Adapter Protocol server for Monkey.
+//
+// The server debugs a single program per session, normally over the
+// standard input and output of `monkey debug`. It supports line breakpoints,
+// stepping in, over and out, pausing, and inspecting the call stack and the
+// environments of each frame. The program's output is sent to the client as
+// output events.
+package dap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"monkey"
+	"monkey/debugger"
+	"monkey/object"
+	"monkey/wire"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// THREAD is the id of the only thread a Monkey program runs on.
+const THREAD = 1
+
+// Server is a debug adapter for one client.
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	debugger *debugger.Debugger
+	launch   *LaunchArguments
+	started  bool
+	finished chan struct{} // closed once the program has stopped running
+
+	mu   sync.Mutex // guards the fields below, shared with the program
+	seq  int
+	stop *debugger.Stop // the current pause, nil while running
+
+	// references holds what each variablesReference handed out during the
+	// current pause stands for, at index reference-1. Each entry is either
+	// an environment or a value with elements.
+	references []interface{}
+}
+
+// NewServer returns a server reading requests from in and writing responses
+// and events to out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:       bufio.NewReader(in),
+		out:      out,
+		debugger: debugger.New(),
+		finished: make(chan struct{}),
+	}
+}
+
+// Serve handles requests until the client disconnects or closes the input,
+// terminating the program if it is still running.
+func (server *Server) Serve() error {
+	defer server.debugger.Terminate()
+
+	for {
+		body, err := wire.ReadMessage(server.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("dap: decoding request: %w", err)
+		}
+		if err := server.handle(&req); err != nil {
+			return err
+		}
+		if req.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+// handle dispatches req and responds to it. Only failures to write to the
+// client are returned.
+func (server *Server) handle(req *request) error {
+	var body interface{}
+	var err error
+	switch req.Command {
+	case "initialize":
+		body = Capabilities{SupportsConfigurationDoneRequest: true, SupportsTerminateRequest: true}
+		if err := server.respond(req, body, nil); err != nil {
+			return err
+		}
+		return server.send("initialized", nil)
+	case "launch":
+		var args LaunchArguments
+		if err = decode(req.Arguments, &args); err == nil {
+			err = server.prepare(&args)
+		}
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err = decode(req.Arguments, &args); err == nil {
+			body = server.setBreakpoints(args)
+		}
+	case "configurationDone":
+		err = server.start()
+	case "threads":
+		body = ThreadsResponseBody{Threads: []Thread{{ID: THREAD, Name: "main"}}}
+	case "stackTrace":
+		body = server.stackTrace()
+	case "scopes":
+		var args ScopesArguments
+		if err = decode(req.Arguments, &args); err == nil {
+			body, err = server.scopes(args.FrameID)
+		}
+	case "variables":
+		var args VariablesArguments
+		if err = decode(req.Arguments, &args); err == nil {
+			body, err = server.variables(args.VariablesReference)
+		}
+	case "continue":
+		return server.resume(req, ContinueResponseBody{AllThreadsContinued: true}, server.debugger.Continue)
+	case "next":
+		return server.resume(req, nil, server.debugger.StepOver)
+	case "stepIn":
+		return server.resume(req, nil, server.debugger.StepIn)
+	case "stepOut":
+		return server.resume(req, nil, server.debugger.StepOut)
+	case "pause":
+		server.debugger.Pause()
+	case "terminate", "disconnect":
+		server.debugger.Terminate()
+	default:
+		err = fmt.Errorf("unsupported command %q", req.Command)
+	}
+	return server.respond(req, body, err)
+}
+
+func decode(arguments json.RawMessage, v interface{}) error {
+	if len(arguments) == 0 {
+		return errors.New("missing arguments")
+	}
+	return json.Unmarshal(arguments, v)
+}
+
+// respond sends the response to req, which failed if err is not nil.
+func (server *Server) respond(req *request, body interface{}, err error) error {
+	res := response{RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+	if err != nil {
+		res.Message = err.Error()
+		res.Body = nil
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.seq++
+	res.message = message{Seq: server.seq, Type: "response"}
+	return wire.WriteMessage(server.out, res)
+}
+
+// send sends an event, which may happen while the program runs.
+func (server *Server) send(name string, body interface{}) error {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.seq++
+	return wire.WriteMessage(server.out, event{message: message{Seq: server.seq, Type: "event"}, Event: name, Body: body})
+}
+
+func (server *Server) prepare(args *LaunchArguments) error {
+	if server.launch != nil {
+		return errors.New("a program has already been launched")
+	}
+	if args.Program == "" {
+		return errors.New("missing program")
+	}
+
+	program, err := filepath.Abs(args.Program)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(program); err != nil {
+		return err
+	}
+	args.Program = program
+	server.launch = args
+	return nil
+}
+
+func (server *Server) setBreakpoints(args SetBreakpointsArguments) SetBreakpointsResponseBody {
+	path := filepath.Clean(args.Source.Path)
+	lines := []int{}
+	body := SetBreakpointsResponseBody{Breakpoints: []Breakpoint{}}
+	for _, bp := range args.Breakpoints {
+		lines = append(lines, bp.Line)
+		body.Breakpoints = append(body.Breakpoints, Breakpoint{Verified: true, Line: bp.Line, Source: &args.Source})
+	}
+	server.debugger.SetBreakpoints(path, lines)
+	return body
+}
+
+// start runs the launched program once the client has configured its
+// breakpoints.
+func (server *Server) start() error {
+	if server.launch == nil {
+		return errors.New("no program has been launched")
+	}
+	if server.started {
+		return nil
+	}
+	server.started = true
+
+	// Programs run as written, so that every line can be stepped through.
+	options := []monkey.Option{
+		monkey.WithDebugger(server.debugger),
+		monkey.WithOutput(&output{server: server, category: OUTPUT_STDOUT}),
+		monkey.WithoutOptimizer(),
+	}
+	if server.launch.NoPrelude {
+		options = append(options, monkey.WithoutPrelude())
+	}
+	if server.launch.StopOnEntry {
+		server.debugger.StopOnEntry()
+	}
+
+	interp := monkey.New(options...)
+	go server.forwardStops()
+	go func() {
+		_, err := interp.RunFile(context.Background(), server.launch.Program)
+		close(server.finished)
+
+		code := 0
+		if err != nil && !errors.Is(err, debugger.ErrTerminated) {
+			code = 1
+			server.send("output", OutputEventBody{Category: OUTPUT_STDERR, Output: describe(err)})
+		}
+		server.send("exited", ExitedEventBody{ExitCode: code})
+		server.send("terminated", nil)
+	}()
+	return nil
+}
+
+// forwardStops reports each pause of the program to the client until the
+// program has finished.
+func (server *Server) forwardStops() {
+	for {
+		select {
+		case stop := <-server.debugger.Stops():
+			server.mu.Lock()
+			server.stop, server.references = stop, nil
+			server.mu.Unlock()
+			server.send("stopped", StoppedEventBody{Reason: string(stop.Reason), ThreadID: THREAD, AllThreadsStopped: true})
+		case <-server.finished:
+			return
+		}
+	}
+}
+
+// resume forgets the current pause, responds to req and then lets the
+// program continue with proceed, so that the response precedes the next
+// stopped event.
+func (server *Server) resume(req *request, body interface{}, proceed func()) error {
+	server.mu.Lock()
+	server.stop, server.references = nil, nil
+	server.mu.Unlock()
+
+	if err := server.respond(req, body, nil); err != nil {
+		return err
+	}
+	proceed()
+	return nil
+}
+
+func (server *Server) stackTrace() StackTraceResponseBody {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	body := StackTraceResponseBody{StackFrames: []StackFrame{}}
+	if server.stop == nil {
+		return body
+	}
+	for i, frame := range server.stop.Frames {
+		sf := StackFrame{ID: i + 1, Name: frame.Function, Line: frame.Position.Line, Column: frame.Position.Column}
+		if frame.File != "" {
+			sf.Source = &Source{Name: filepath.Base(frame.File), Path: frame.File}
+		}
+		body.StackFrames = append(body.StackFrames, sf)
+	}
+	body.TotalFrames = len(body.StackFrames)
+	return body
+}
+
+func (server *Server) scopes(frameID int) (ScopesResponseBody, error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	body := ScopesResponseBody{Scopes: []Scope{}}
+	if server.stop == nil || frameID < 1 || frameID > len(server.stop.Frames) {
+		return body, fmt.Errorf("unknown frame %d", frameID)
+	}
+	for _, scope := range server.stop.Frames[frameID-1].Scopes() {
+		body.Scopes = append(body.Scopes, Scope{Name: scope.Name, VariablesReference: server.reference(scope.Env)})
+	}
+	return body, nil
+}
+
+func (server *Server) variables(reference int) (VariablesResponseBody, error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	body := VariablesResponseBody{Variables: []Variable{}}
+	if reference < 1 || reference > len(server.references) {
+		return body, fmt.Errorf("unknown variables reference %d", reference)
+	}
+
+	switch target := server.references[reference-1].(type) {
+	case *object.Environment:
+		for _, name := range target.Names() {
+			value, _ := target.Get(name)
+			body.Variables = append(body.Variables, server.variable(name, value))
+		}
+	case *object.Array:
+		for i, element := range target.Elements {
+			body.Variables = append(body.Variables, server.variable(fmt.Sprintf("[%d]", i), element))
+		}
+	case *object.Hash:
+		for _, pair := range target.Pairs {
+			body.Variables = append(body.Variables, server.variable(display(pair.Key), pair.Value))
+		}
+		sort.Slice(body.Variables, func(i, j int) bool { return body.Variables[i].Name < body.Variables[j].Name })
+	}
+	return body, nil
+}
+
+func (server *Server) variable(name string, value object.Object) Variable {
+	v := Variable{Name: name, Value: display(value), Type: strings.ToLower(string(value.Type()))}
+	switch value := value.(type) {
+	case *object.Array:
+		if len(value.Elements) > 0 {
+			v.VariablesReference = server.reference(value)
+		}
+	case *object.Hash:
+		if len(value.Pairs) > 0 {
+			v.VariablesReference = server.reference(value)
+		}
+	}
+	return v
+}
+
+// reference hands out a variablesReference for target, valid until the
+// program resumes.
+func (server *Server) reference(target interface{}) int {
+	server.references = append(server.references, target)
+	return len(server.references)
+}
+
+// display formats a value the way it would be written in source.
+func display(value object.Object) string {
+	if s, ok := value.(*object.String); ok {
+		return strconv.Quote(s.Value)
+	}
+	return value.Inspect()
+}
+
+// describe formats the error a program failed with, including its stack.
+func describe(err error) string {
+	var runtimeErr *object.Error
+	if errors.As(err, &runtimeErr) {
+		return runtimeErr.Inspect() + "\n" + runtimeErr.StackTrace()
+	}
+	return err.Error() + "\n"
+}
+
+// output sends what the program prints to the client.
+type output struct {
+	server   *Server
+	category string
+}
+
+func (out *output) Write(p []byte) (int, error) {
+	if err := out.server.send("output", OutputEventBody{Category: out.category, Output: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
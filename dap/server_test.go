@@ -0,0 +1,300 @@
+package dap_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"monkey/dap"
+	"monkey/wire"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// client drives a server over pipes the way an editor would. Events that
+// arrive while it waits for a response are queued for expect.
+type client struct {
+	t      *testing.T
+	in     io.WriteCloser
+	out    *bufio.Reader
+	seq    int
+	events []map[string]json.RawMessage
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	c := &client{t: t, in: inWriter, out: bufio.NewReader(outReader), done: make(chan error, 1)}
+	go func() {
+		c.done <- dap.NewServer(inReader, outWriter).Serve()
+	}()
+	return c
+}
+
+func (c *client) receive() map[string]json.RawMessage {
+	body, err := wire.ReadMessage(c.out)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		c.t.Fatalf("decoding %s: %v", body, err)
+	}
+	return message
+}
+
+// request sends a command and decodes the body of its response into body
+// unless it is nil. It returns the error message of a failed response.
+func (c *client) request(command string, arguments interface{}, body interface{}) string {
+	c.seq++
+	request := map[string]interface{}{"seq": c.seq, "type": "request", "command": command}
+	if arguments != nil {
+		request["arguments"] = arguments
+	}
+	if err := wire.WriteMessage(c.in, request); err != nil {
+		c.t.Fatal(err)
+	}
+
+	for {
+		message := c.receive()
+		if string(message["type"]) == `"event"` {
+			c.events = append(c.events, message)
+			continue
+		}
+		assert.Equal(c.t, string(message["command"]), `"`+command+`"`)
+		var failed string
+		if string(message["success"]) != "true" {
+			json.Unmarshal(message["message"], &failed)
+			return failed
+		}
+		if body != nil {
+			if err := json.Unmarshal(message["body"], body); err != nil {
+				c.t.Fatalf("decoding %s body %s: %v", command, message["body"], err)
+			}
+		}
+		return ""
+	}
+}
+
+// expect returns the body of the next event, which must be name.
+func (c *client) expect(name string) json.RawMessage {
+	var message map[string]json.RawMessage
+	if len(c.events) > 0 {
+		message, c.events = c.events[0], c.events[1:]
+	} else {
+		message = c.receive()
+	}
+	assert.Equal(c.t, string(message["event"]), `"`+name+`"`)
+	return message["body"]
+}
+
+func (c *client) stopped() string {
+	var body dap.StoppedEventBody
+	json.Unmarshal(c.expect("stopped"), &body)
+	return body.Reason
+}
+
+// launch starts debugging source saved to a file, stopping at the given
+// breakpoints, and returns the file's path.
+func (c *client) launch(source string, stopOnEntry bool, breakpoints ...int) string {
+	path := filepath.Join(c.t.TempDir(), "main.mk")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		c.t.Fatal(err)
+	}
+
+	var capabilities dap.Capabilities
+	c.request("initialize", map[string]interface{}{"adapterID": "monkey"}, &capabilities)
+	assert.True(c.t, capabilities.SupportsConfigurationDoneRequest)
+	c.expect("initialized")
+
+	assert.Equal(c.t, c.request("launch", dap.LaunchArguments{Program: path, StopOnEntry: stopOnEntry}, nil), "")
+	lines := []dap.SourceBreakpoint{}
+	for _, line := range breakpoints {
+		lines = append(lines, dap.SourceBreakpoint{Line: line})
+	}
+	var verified dap.SetBreakpointsResponseBody
+	c.request("setBreakpoints", dap.SetBreakpointsArguments{Source: dap.Source{Path: path}, Breakpoints: lines}, &verified)
+	assert.Equal(c.t, len(verified.Breakpoints), len(breakpoints))
+	c.request("configurationDone", nil, nil)
+	return path
+}
+
+func (c *client) frames() []dap.StackFrame {
+	var body dap.StackTraceResponseBody
+	c.request("stackTrace", map[string]interface{}{"threadId": dap.THREAD}, &body)
+	return body.StackFrames
+}
+
+func (c *client) scopes(frame int) []dap.Scope {
+	var body dap.ScopesResponseBody
+	c.request("scopes", dap.ScopesArguments{FrameID: frame}, &body)
+	return body.Scopes
+}
+
+func (c *client) variables(reference int) []dap.Variable {
+	var body dap.VariablesResponseBody
+	c.request("variables", dap.VariablesArguments{VariablesReference: reference}, &body)
+	return body.Variables
+}
+
+// output returns the next line the program wrote, which may arrive in
+// several output events.
+func (c *client) output() dap.OutputEventBody {
+	var line dap.OutputEventBody
+	for !strings.HasSuffix(line.Output, "\n") {
+		var body dap.OutputEventBody
+		json.Unmarshal(c.expect("output"), &body)
+		line.Category = body.Category
+		line.Output += body.Output
+	}
+	return line
+}
+
+func (c *client) exited() int {
+	var body dap.ExitedEventBody
+	json.Unmarshal(c.expect("exited"), &body)
+	c.expect("terminated")
+	return body.ExitCode
+}
+
+func (c *client) disconnect() {
+	c.request("disconnect", map[string]interface{}{}, nil)
+	assert.NoError(c.t, <-c.done)
+}
+
+const PROGRAM = `let greet = fn(name) {
+  let message = "hello " + name;
+  print(message);
+  message
+};
+let names = ["ann", "bob"];
+let scores = {"ann": 1};
+greet(names[0]);
+greet(names[1])`
+
+func TestBreakpointsAndVariables(t *testing.T) {
+	c := newClient(t)
+	defer c.disconnect()
+	path := c.launch(PROGRAM, false, 3)
+
+	assert.Equal(t, c.stopped(), "breakpoint")
+	var threads dap.ThreadsResponseBody
+	c.request("threads", nil, &threads)
+	assert.Equal(t, threads.Threads, []dap.Thread{{ID: dap.THREAD, Name: "main"}})
+
+	source := &dap.Source{Name: "main.mk", Path: path}
+	assert.Equal(t, c.frames(), []dap.StackFrame{
+		{ID: 1, Name: "greet", Source: source, Line: 3, Column: 3},
+		{ID: 2, Name: "<program>", Source: source, Line: 8, Column: 1},
+	})
+
+	scopes := c.scopes(1)
+	assert.Equal(t, len(scopes), 2)
+	assert.Equal(t, scopes[0].Name, "Locals")
+	assert.Equal(t, c.variables(scopes[0].VariablesReference), []dap.Variable{
+		{Name: "message", Value: `"hello ann"`, Type: "string"},
+		{Name: "name", Value: `"ann"`, Type: "string"},
+	})
+
+	assert.Equal(t, scopes[1].Name, "Globals")
+	globals := c.variables(scopes[1].VariablesReference)
+	assert.Equal(t, len(globals), 3)
+	assert.Equal(t, globals[0].Name, "greet")
+	assert.Equal(t, globals[0].Type, "function")
+	assert.Equal(t, globals[1].Value, "[ann, bob]")
+	assert.Equal(t, c.variables(globals[1].VariablesReference), []dap.Variable{
+		{Name: "[0]", Value: `"ann"`, Type: "string"},
+		{Name: "[1]", Value: `"bob"`, Type: "string"},
+	})
+	assert.Equal(t, c.variables(globals[2].VariablesReference), []dap.Variable{
+		{Name: `"ann"`, Value: "1", Type: "integer"},
+	})
+
+	c.request("continue", map[string]interface{}{"threadId": dap.THREAD}, nil)
+	assert.Equal(t, c.output(), dap.OutputEventBody{Category: dap.OUTPUT_STDOUT, Output: "hello ann\n"})
+
+	assert.Equal(t, c.stopped(), "breakpoint")
+	assert.Equal(t, c.frames()[1].Line, 9)
+	c.request("continue", map[string]interface{}{"threadId": dap.THREAD}, nil)
+	assert.Equal(t, c.output().Output, "hello bob\n")
+	assert.Equal(t, c.exited(), 0)
+}
+
+func TestStepping(t *testing.T) {
+	c := newClient(t)
+	defer c.disconnect()
+	c.launch(PROGRAM, true)
+
+	line := func() int { return c.frames()[0].Line }
+	thread := map[string]interface{}{"threadId": dap.THREAD}
+
+	assert.Equal(t, c.stopped(), "entry")
+	assert.Equal(t, line(), 1)
+
+	tests := []struct {
+		command string
+		line    int
+		frames  int
+	}{
+		{"next", 6, 1},
+		{"next", 7, 1},
+		{"next", 8, 1},
+		{"stepIn", 2, 2},
+		{"next", 3, 2},
+		{"stepOut", 9, 1},
+		{"next", 0, 0},
+	}
+
+	for _, tt := range tests {
+		c.request(tt.command, thread, nil)
+		if tt.line == 0 {
+			break
+		}
+		if tt.command == "stepOut" {
+			assert.Equal(t, c.output().Output, "hello ann\n")
+		}
+		assert.Equal(t, c.stopped(), "step", tt.command)
+		assert.Equal(t, line(), tt.line, tt.command)
+		assert.Equal(t, len(c.frames()), tt.frames, tt.command)
+	}
+
+	assert.Equal(t, c.output().Output, "hello bob\n")
+	assert.Equal(t, c.exited(), 0)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	c := newClient(t)
+	defer c.disconnect()
+	c.launch("let f = fn() { 1 + \"a\" };\nf()", false)
+
+	output := c.output()
+	assert.Equal(t, output.Category, dap.OUTPUT_STDERR)
+	assert.Equal(t, output.Output, "ERROR: TypeError: type mismatch: INTEGER + STRING\n\tat f (2:2)\n")
+	assert.Equal(t, c.exited(), 1)
+}
+
+func TestTerminate(t *testing.T) {
+	c := newClient(t)
+	defer c.disconnect()
+	c.launch(PROGRAM, false, 2)
+
+	assert.Equal(t, c.stopped(), "breakpoint")
+	c.request("terminate", map[string]interface{}{}, nil)
+	assert.Equal(t, c.exited(), 0)
+}
+
+func TestErrors(t *testing.T) {
+	c := newClient(t)
+	defer c.disconnect()
+
+	c.request("initialize", map[string]interface{}{}, nil)
+	c.expect("initialized")
+	assert.Equal(t, c.request("configurationDone", nil, nil), "no program has been launched")
+	assert.Contains(t, c.request("launch", dap.LaunchArguments{Program: filepath.Join(t.TempDir(), "missing.mk")}, nil), "no such file")
+	assert.Equal(t, c.request("scopes", dap.ScopesArguments{FrameID: 1}, nil), "unknown frame 1")
+	assert.Equal(t, c.request("evaluate", map[string]interface{}{"expression": "1"}, nil), `unsupported command "evaluate"`)
+}
@@ -0,0 +1,283 @@
+// Package debugger pauses running Monkey programs at breakpoints and while
+// stepping, so that their call stack and environments can be inspected.
+//
+// A Debugger is attached to an interpreter with monkey.WithDebugger. The
+// program then runs on its own goroutine, and each time it pauses a *Stop is
+// sent on Stops. The program stays paused, and its environments are safe to
+// read, until Continue, StepIn, StepOver or StepOut is called.
+package debugger
+
+import (
+	"errors"
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+	"sync"
+)
+
+// ErrTerminated stops the run of a program whose debugger was terminated.
+var ErrTerminated = errors.New("debugger terminated the program")
+
+// Reason explains why a program paused.
+type Reason string
+
+const (
+	ENTRY      Reason = "entry"
+	BREAKPOINT Reason = "breakpoint"
+	STEP       Reason = "step"
+	PAUSE      Reason = "pause"
+)
+
+// Frame is a function call in progress, or the program itself for the
+// outermost frame.
+type Frame struct {
+	Function string
+	File     string         // source file of the code, "" if unknown
+	Position token.Position // start of the statement running in the frame
+	Env      *object.Environment
+}
+
+// Stop describes a paused program.
+type Stop struct {
+	Reason Reason
+	Frames []Frame // innermost first
+}
+
+type mode int
+
+const (
+	RUNNING mode = iota
+	STEPPING_IN
+	STEPPING_OVER
+	STEPPING_OUT
+	STOPPING_ON_ENTRY
+)
+
+// location identifies where a statement runs, for deciding whether a step
+// or a breakpoint left the line it started on. Every call runs in a new
+// environment, so recursive or repeated calls are told apart by env.
+type location struct {
+	file  string
+	line  int
+	depth int
+	env   *object.Environment
+}
+
+type Debugger struct {
+	mu          sync.Mutex
+	breakpoints map[string]map[int]bool // lines by file
+	mode        mode
+	from        location // where the current step started
+	last        location // the statement seen last
+	pause       bool
+	paused      bool
+	frames      []Frame // outermost first
+
+	// roots holds the root environments of the programs and modules seen
+	// running at the top level; the debugger only stops in code enclosed by
+	// one of them, which leaves out the bodies of prelude functions.
+	roots map[*object.Environment]bool
+
+	stops     chan *Stop
+	resume    chan struct{}
+	done      chan struct{}
+	terminate sync.Once
+}
+
+func New() *Debugger {
+	return &Debugger{
+		breakpoints: map[string]map[int]bool{},
+		roots:       map[*object.Environment]bool{},
+		stops:       make(chan *Stop),
+		resume:      make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+}
+
+// Stops returns the channel on which the debugger reports each pause.
+func (d *Debugger) Stops() <-chan *Stop {
+	return d.stops
+}
+
+// SetBreakpoints replaces the breakpoints in file with the given lines.
+func (d *Debugger) SetBreakpoints(file string, lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	set := map[int]bool{}
+	for _, line := range lines {
+		set[line] = true
+	}
+	d.breakpoints[file] = set
+}
+
+// StopOnEntry makes the program pause before its first statement.
+func (d *Debugger) StopOnEntry() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mode = STOPPING_ON_ENTRY
+}
+
+// Pause makes a running program pause before its next statement.
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pause = true
+}
+
+// Continue resumes a paused program until it hits a breakpoint.
+func (d *Debugger) Continue() { d.resumeWith(RUNNING) }
+
+// StepIn resumes a paused program until the next line, entering calls.
+func (d *Debugger) StepIn() { d.resumeWith(STEPPING_IN) }
+
+// StepOver resumes a paused program until the next line of the current
+// function or of its callers.
+func (d *Debugger) StepOver() { d.resumeWith(STEPPING_OVER) }
+
+// StepOut resumes a paused program until the current function returns.
+func (d *Debugger) StepOut() { d.resumeWith(STEPPING_OUT) }
+
+// Terminate stops the program at its next statement, or right away when it
+// is paused.
+func (d *Debugger) Terminate() {
+	d.terminate.Do(func() { close(d.done) })
+}
+
+func (d *Debugger) resumeWith(mode mode) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.paused {
+		return
+	}
+	d.mode, d.from, d.paused = mode, d.last, false
+	d.resume <- struct{}{}
+}
+
+// Before implements object.Debugger. It records the statement about to run
+// and blocks while the program is paused.
+func (d *Debugger) Before(stmt ast.Statement, env *object.Environment) error {
+	select {
+	case <-d.done:
+		return ErrTerminated
+	default:
+	}
+
+	d.mu.Lock()
+	var calls []object.StackFrame
+	if runtime := env.Runtime(); runtime != nil {
+		calls = runtime.Calls
+	}
+	depth := len(calls)
+	if depth == 0 {
+		d.roots[root(env)] = true
+	}
+
+	for len(d.frames) <= depth {
+		d.frames = append(d.frames, Frame{Function: "<program>"})
+	}
+	d.frames = d.frames[:depth+1]
+	for i, call := range calls {
+		d.frames[i+1].Function = call.Function
+	}
+	position := ast.SpanOf(stmt).Start
+	d.frames[depth] = Frame{Function: d.frames[depth].Function, File: env.File(), Position: position, Env: env}
+
+	here := location{file: env.File(), line: position.Line, depth: depth, env: env}
+	reason, stop := d.reason(here)
+	d.last = here
+	if d.roots[root(env)] && stop {
+		d.pause, d.paused = false, true
+	} else {
+		stop = false
+	}
+
+	frames := make([]Frame, len(d.frames))
+	for i, frame := range d.frames {
+		frames[len(frames)-1-i] = frame
+	}
+	d.mu.Unlock()
+
+	if !stop {
+		return nil
+	}
+	select {
+	case d.stops <- &Stop{Reason: reason, Frames: frames}:
+	case <-d.done:
+		return ErrTerminated
+	}
+	select {
+	case <-d.resume:
+		return nil
+	case <-d.done:
+		return ErrTerminated
+	}
+}
+
+// reason decides whether to stop at here and why. Steps and breakpoints only
+// stop once execution has moved to another line.
+func (d *Debugger) reason(here location) (Reason, bool) {
+	if d.pause {
+		return PAUSE, true
+	}
+
+	switch d.mode {
+	case STOPPING_ON_ENTRY:
+		return ENTRY, true
+	case STEPPING_IN:
+		if here != d.from {
+			return STEP, true
+		}
+	case STEPPING_OVER:
+		if here.depth < d.from.depth || here.depth == d.from.depth && here != d.from {
+			return STEP, true
+		}
+	case STEPPING_OUT:
+		if here.depth < d.from.depth {
+			return STEP, true
+		}
+	}
+
+	if d.breakpoints[here.file][here.line] && here != d.last {
+		return BREAKPOINT, true
+	}
+	return "", false
+}
+
+// root returns the outermost environment enclosing env below the prelude.
+func root(env *object.Environment) *object.Environment {
+	var prelude *object.Environment
+	if runtime := env.Runtime(); runtime != nil {
+		prelude = runtime.Prelude
+	}
+	for env.Outer() != nil && env.Outer() != prelude {
+		env = env.Outer()
+	}
+	return env
+}
+
+// Scope is an environment visible from a frame.
+type Scope struct {
+	Name string
+	Env  *object.Environment
+}
+
+// Scopes returns the environments visible from the frame, innermost first:
+// its locals, the environments of the functions it closes over and the
+// globals of its program or module. The prelude is left out.
+func (f Frame) Scopes() []Scope {
+	if f.Env == nil {
+		return nil
+	}
+
+	global := root(f.Env)
+	scopes := []Scope{}
+	for env := f.Env; env != global; env = env.Outer() {
+		name := "Closure"
+		if env == f.Env {
+			name = "Locals"
+		}
+		scopes = append(scopes, Scope{Name: name, Env: env})
+	}
+	return append(scopes, Scope{Name: "Globals", Env: global})
+}
@@ -0,0 +1,161 @@
+package debugger_test
+
+import (
+	"context"
+	"errors"
+	"monkey"
+	"monkey/debugger"
+	"monkey/object"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const PROGRAM = `let add = fn(a, b) {
+  let sum = a + b;
+  sum
+};
+let x = add(1, 2);
+let y = times(2, fn(i) {
+  i * x
+});
+x + len(y)`
+
+type run struct {
+	result object.Object
+	err    error
+}
+
+func start(d *debugger.Debugger) chan run {
+	done := make(chan run, 1)
+	go func() {
+		result, err := monkey.New(monkey.WithDebugger(d)).Run(context.Background(), PROGRAM)
+		done <- run{result, err}
+	}()
+	return done
+}
+
+// where describes a stop as its reason followed by the function and line of
+// each frame, innermost first.
+func where(stop *debugger.Stop) []interface{} {
+	description := []interface{}{stop.Reason}
+	for _, frame := range stop.Frames {
+		description = append(description, frame.Function, frame.Position.Line)
+	}
+	return description
+}
+
+func names(scope debugger.Scope) []string {
+	return scope.Env.Names()
+}
+
+func TestStepping(t *testing.T) {
+	d := debugger.New()
+	d.StopOnEntry()
+	done := start(d)
+
+	assert.Equal(t, where(<-d.Stops()), []interface{}{debugger.ENTRY, "<program>", 1})
+	d.StepOver()
+	assert.Equal(t, where(<-d.Stops()), []interface{}{debugger.STEP, "<program>", 5})
+	d.StepIn()
+	stop := <-d.Stops()
+	assert.Equal(t, where(stop), []interface{}{debugger.STEP, "add", 2, "<program>", 5})
+
+	scopes := stop.Frames[0].Scopes()
+	assert.Equal(t, len(scopes), 2)
+	assert.Equal(t, scopes[0].Name, "Locals")
+	assert.Equal(t, names(scopes[0]), []string{"a", "b"})
+	assert.Equal(t, scopes[1].Name, "Globals")
+	assert.Equal(t, names(scopes[1]), []string{"add"})
+
+	d.StepOver()
+	stop = <-d.Stops()
+	assert.Equal(t, where(stop), []interface{}{debugger.STEP, "add", 3, "<program>", 5})
+	sum, _ := stop.Frames[0].Env.Get("sum")
+	assert.Equal(t, sum.Inspect(), "3")
+
+	d.StepOut()
+	assert.Equal(t, where(<-d.Stops()), []interface{}{debugger.STEP, "<program>", 6})
+
+	// the body of times comes from the prelude and is stepped through
+	d.StepIn()
+	stop = <-d.Stops()
+	assert.Equal(t, where(stop), []interface{}{debugger.STEP, "<anonymous>", 7, "times", 18, "<program>", 6})
+	scopes = stop.Frames[0].Scopes()
+	assert.Equal(t, []string{scopes[0].Name, scopes[1].Name}, []string{"Locals", "Globals"})
+	assert.Equal(t, names(scopes[0]), []string{"i"})
+	assert.Equal(t, names(scopes[1]), []string{"add", "x"})
+
+	// the next line of the callback is its second call
+	d.StepOver()
+	stop = <-d.Stops()
+	assert.Equal(t, where(stop), []interface{}{debugger.STEP, "<anonymous>", 7, "times", 18, "<program>", 6})
+	i, _ := stop.Frames[0].Env.Get("i")
+	assert.Equal(t, i.Inspect(), "1")
+
+	d.StepOut()
+	assert.Equal(t, where(<-d.Stops()), []interface{}{debugger.STEP, "<program>", 9})
+	d.Continue()
+
+	result := <-done
+	assert.NoError(t, result.err)
+	assert.Equal(t, result.result.Inspect(), "5")
+}
+
+func TestBreakpoints(t *testing.T) {
+	d := debugger.New()
+	d.SetBreakpoints("", []int{3, 7})
+	done := start(d)
+
+	assert.Equal(t, where(<-d.Stops()), []interface{}{debugger.BREAKPOINT, "add", 3, "<program>", 5})
+	d.Continue()
+	for _, expected := range []string{"0", "1"} {
+		stop := <-d.Stops()
+		assert.Equal(t, where(stop), []interface{}{debugger.BREAKPOINT, "<anonymous>", 7, "times", 18, "<program>", 6})
+		i, _ := stop.Frames[0].Env.Get("i")
+		assert.Equal(t, i.Inspect(), expected)
+		d.Continue()
+	}
+
+	result := <-done
+	assert.NoError(t, result.err)
+	assert.Equal(t, result.result.Inspect(), "5")
+}
+
+func TestBreakpointsOnLinesWithSeveralStatements(t *testing.T) {
+	d := debugger.New()
+	d.SetBreakpoints("", []int{1})
+	done := make(chan error, 1)
+	go func() {
+		_, err := monkey.New(monkey.WithDebugger(d)).Run(context.Background(), "let a = 1; let b = if (a) { a } else { 0 };\na + b")
+		done <- err
+	}()
+
+	assert.Equal(t, where(<-d.Stops()), []interface{}{debugger.BREAKPOINT, "<program>", 1})
+	d.Continue()
+	assert.NoError(t, <-done)
+}
+
+func TestPause(t *testing.T) {
+	d := debugger.New()
+	d.Pause()
+	done := start(d)
+
+	assert.Equal(t, where(<-d.Stops()), []interface{}{debugger.PAUSE, "<program>", 1})
+	d.Continue()
+	assert.NoError(t, (<-done).err)
+}
+
+func TestTerminate(t *testing.T) {
+	d := debugger.New()
+	d.SetBreakpoints("", []int{2})
+	done := start(d)
+
+	<-d.Stops()
+	d.Terminate()
+
+	result := <-done
+	assert.True(t, errors.Is(result.err, object.INTERRUPTED))
+	assert.False(t, errors.Is(result.err, object.TIMEOUT))
+	assert.True(t, errors.Is(result.err, debugger.ErrTerminated))
+}
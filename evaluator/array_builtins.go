@@ -0,0 +1,536 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"monkey/object"
+	"monkey/token"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// MAX_RANGE_LENGTH bounds the number of elements range produces, so that a
+// typo in a bound cannot exhaust memory.
+const MAX_RANGE_LENGTH = 1 << 24
+
+var arrayBuiltins = []*object.Builtin{
+	{
+		Name:   "map",
+		Params: []string{"array", "fn"},
+		Doc:    "Returns a new array with fn applied to every element.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArguments("map", args)
+			if err != nil {
+				return err
+			}
+
+			result := make([]object.Object, len(arr.Elements))
+			for i, element := range arr.Elements {
+				mapped := callFunction(fn, env, element)
+				if isError(mapped) {
+					return mapped
+				}
+				result[i] = mapped
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+	{
+		Name:   "filter",
+		Params: []string{"array", "predicate"},
+		Doc:    "Returns a new array with the elements for which predicate is truthy.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArguments("filter", args)
+			if err != nil {
+				return err
+			}
+
+			result := []object.Object{}
+			for _, element := range arr.Elements {
+				keep := callFunction(fn, env, element)
+				if isError(keep) {
+					return keep
+				}
+				if isTruthy(keep) {
+					result = append(result, element)
+				}
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+	{
+		Name:   "reduce",
+		Params: []string{"array", "initial", "fn"},
+		Doc:    "Folds the array from the left, calling fn(accumulator, element) for every element.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArguments("reduce", []object.Object{args[0], args[2]})
+			if err != nil {
+				return err
+			}
+
+			acc := args[1]
+			for _, element := range arr.Elements {
+				acc = callFunction(fn, env, acc, element)
+				if isError(acc) {
+					return acc
+				}
+			}
+			return acc
+		},
+	},
+	{
+		Name:   "each",
+		Params: []string{"array", "fn"},
+		Doc:    "Calls fn with every element, for its side effects.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArguments("each", args)
+			if err != nil {
+				return err
+			}
+
+			for _, element := range arr.Elements {
+				if result := callFunction(fn, env, element); isError(result) {
+					return result
+				}
+			}
+			return NULL
+		},
+	},
+	{
+		Name:   "find",
+		Params: []string{"array", "predicate"},
+		Doc:    "Returns the first element for which predicate is truthy, or null.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArguments("find", args)
+			if err != nil {
+				return err
+			}
+
+			for _, element := range arr.Elements {
+				found := callFunction(fn, env, element)
+				if isError(found) {
+					return found
+				}
+				if isTruthy(found) {
+					return element
+				}
+			}
+			return NULL
+		},
+	},
+	{
+		Name:   "any",
+		Params: []string{"array", "predicate"},
+		Doc:    "Reports whether predicate is truthy for at least one element.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArguments("any", args)
+			if err != nil {
+				return err
+			}
+
+			for _, element := range arr.Elements {
+				result := callFunction(fn, env, element)
+				if isError(result) {
+					return result
+				}
+				if isTruthy(result) {
+					return TRUE
+				}
+			}
+			return FALSE
+		},
+	},
+	{
+		Name:   "all",
+		Params: []string{"array", "predicate"},
+		Doc:    "Reports whether predicate is truthy for every element.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, fn, err := arrayAndFunctionArguments("all", args)
+			if err != nil {
+				return err
+			}
+
+			for _, element := range arr.Elements {
+				result := callFunction(fn, env, element)
+				if isError(result) {
+					return result
+				}
+				if !isTruthy(result) {
+					return FALSE
+				}
+			}
+			return TRUE
+		},
+	},
+	{
+		Name:   "zip",
+		Params: []string{"arrays..."},
+		Doc:    "Returns an array of tuples pairing up the elements of the arrays, as long as the shortest one.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arrays := make([]*object.Array, len(args))
+			length := -1
+			for i, arg := range args {
+				arr, err := arrayArgument("zip", arg)
+				if err != nil {
+					return err
+				}
+				arrays[i] = arr
+				if length < 0 || len(arr.Elements) < length {
+					length = len(arr.Elements)
+				}
+			}
+
+			result := []object.Object{}
+			for i := 0; i < length; i++ {
+				tuple := make([]object.Object, len(arrays))
+				for j, arr := range arrays {
+					tuple[j] = arr.Elements[i]
+				}
+				result = append(result, &object.Array{Elements: tuple})
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+	{
+		Name:   "flatten",
+		Params: []string{"array", "depth?"},
+		Doc:    "Returns a new array with nested arrays spliced in, depth levels deep (1 by default).",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, err := arrayArgument("flatten", args[0])
+			if err != nil {
+				return err
+			}
+
+			depth := int64(1)
+			if len(args) > 1 {
+				integer, ok := args[1].(*object.Integer)
+				if !ok {
+					return newError(object.TYPE_ERROR, "depth passed to `flatten` must be INTEGER, got %s", args[1].Type())
+				}
+				depth = integer.Value
+			}
+			return &object.Array{Elements: flatten([]object.Object{}, arr.Elements, depth)}
+		},
+	},
+	{
+		Name:   "range",
+		Params: []string{"start", "stop?", "step?"},
+		Doc:    "Returns the integers from start up to but excluding stop. With one argument, start is 0.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			bounds := []int64{0, 0, 1}
+			for i, arg := range args {
+				integer, ok := arg.(*object.Integer)
+				if !ok {
+					return newError(object.TYPE_ERROR, "arguments to `range` must be INTEGER, got %s", arg.Type())
+				}
+				bounds[i] = integer.Value
+			}
+			if len(args) == 1 {
+				bounds[0], bounds[1] = 0, bounds[0]
+			}
+
+			start, stop, step := bounds[0], bounds[1], bounds[2]
+			if step == 0 {
+				return newError(object.VALUE_ERROR, "step passed to `range` must not be 0")
+			}
+
+			length := rangeLength(start, stop, step)
+			if length > MAX_RANGE_LENGTH {
+				return newError(object.LIMIT_ERROR, "result of `range` would exceed %d elements", MAX_RANGE_LENGTH)
+			}
+
+			result := make([]object.Object, length)
+			for i := range result {
+				result[i] = &object.Integer{Value: start + int64(i)*step}
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+	{
+		Name:   "reverse",
+		Params: []string{"array"},
+		Doc:    "Returns a new array with the elements in reverse order.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, err := arrayArgument("reverse", args[0])
+			if err != nil {
+				return err
+			}
+
+			length := len(arr.Elements)
+			result := make([]object.Object, length)
+			for i, element := range arr.Elements {
+				result[length-1-i] = element
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+	{
+		Name:   "concat",
+		Params: []string{"arrays..."},
+		Doc:    "Returns a new array with the elements of every array in order.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			result := []object.Object{}
+			for _, arg := range args {
+				arr, err := arrayArgument("concat", arg)
+				if err != nil {
+					return err
+				}
+				result = append(result, arr.Elements...)
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+	{
+		Name:   "index_of",
+		Params: []string{"collection", "value"},
+		Doc:    "Returns the index of the first array element equal to value, or of the first character of a substring, or -1.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if str, ok := args[0].(*object.String); ok {
+				strs, err := stringArguments("index_of", args[1:])
+				if err != nil {
+					return err
+				}
+				index := strings.Index(str.Value, strs[0])
+				if index > 0 {
+					index = utf8.RuneCountInString(str.Value[:index])
+				}
+				return &object.Integer{Value: int64(index)}
+			}
+
+			arr, err := arrayArgument("index_of", args[0])
+			if err != nil {
+				return err
+			}
+
+			for i, element := range arr.Elements {
+				if objectsEqual(element, args[1]) {
+					return &object.Integer{Value: int64(i)}
+				}
+			}
+			return &object.Integer{Value: -1}
+		},
+	},
+	{
+		Name:   "sort",
+		Params: []string{"array", "less?"},
+		Doc:    "Returns a new, stably sorted array. Without less, elements must all be numbers or all be strings.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, err := arrayArgument("sort", args[0])
+			if err != nil {
+				return err
+			}
+
+			result := make([]object.Object, len(arr.Elements))
+			copy(result, arr.Elements)
+
+			var failure object.Object
+			less := func(i, j int) bool {
+				if failure != nil {
+					return false
+				}
+				ordered, err := compareObjects(result[i], result[j])
+				if err != nil {
+					failure = err
+				}
+				return ordered < 0
+			}
+
+			if len(args) > 1 {
+				fn, err := functionArgument("sort", args[1])
+				if err != nil {
+					return err
+				}
+				less = func(i, j int) bool {
+					if failure != nil {
+						return false
+					}
+					ordered := callFunction(fn, env, result[i], result[j])
+					if isError(ordered) {
+						failure = ordered
+					}
+					return isTruthy(ordered)
+				}
+			}
+
+			sort.SliceStable(result, less)
+			if failure != nil {
+				return failure
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+	{
+		Name:   "unique",
+		Params: []string{"array"},
+		Doc:    "Returns a new array without repeated elements, keeping the first occurrence of each.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, err := arrayArgument("unique", args[0])
+			if err != nil {
+				return err
+			}
+
+			seen := make(map[object.HashKey]bool)
+			unhashable := []object.Object{}
+			result := []object.Object{}
+			for _, element := range arr.Elements {
+				if key, ok := uniqueKey(element); ok {
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+				} else if containsObject(unhashable, element) {
+					continue
+				} else {
+					unhashable = append(unhashable, element)
+				}
+				result = append(result, element)
+			}
+			return &object.Array{Elements: result}
+		},
+	},
+}
+
+// callFunction calls fn on behalf of a builtin. The callback has no call site
+// of its own; the builtin's frame is added when the error leaves it.
+func callFunction(fn object.Object, env *object.Environment, args ...object.Object) object.Object {
+	return applyFunction(fn, args, env, token.Position{})
+}
+
+func arrayArgument(builtin string, arg object.Object) (*object.Array, *object.Error) {
+	arr, ok := arg.(*object.Array)
+	if !ok {
+		return nil, newError(object.TYPE_ERROR, "argument to `%s` must be ARRAY, got %s", builtin, arg.Type())
+	}
+	return arr, nil
+}
+
+func functionArgument(builtin string, arg object.Object) (object.Object, *object.Error) {
+	switch arg.(type) {
+	case *object.Function, *object.Builtin:
+		return arg, nil
+	default:
+		return nil, newError(object.TYPE_ERROR, "argument to `%s` must be FUNCTION, got %s", builtin, arg.Type())
+	}
+}
+
+func arrayAndFunctionArguments(builtin string, args []object.Object) (*object.Array, object.Object, *object.Error) {
+	arr, err := arrayArgument(builtin, args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	fn, err := functionArgument(builtin, args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return arr, fn, nil
+}
+
+// uniqueKey returns the key unique deduplicates element by. A float holding
+// an integer gets the key of that integer, so that 1 and 1.0 are one value.
+// NaN, arrays and hashes have no key and are compared with objectsEqual.
+func uniqueKey(element object.Object) (object.HashKey, bool) {
+	if float, ok := element.(*object.Float); ok {
+		switch {
+		case math.IsNaN(float.Value):
+			return object.HashKey{}, false
+		case math.IsInf(float.Value, 0) || float.Value != math.Trunc(float.Value):
+			return object.HashKey{Type: object.FLOAT_OBJ, Value: math.Float64bits(float.Value)}, true
+		}
+		integer, _ := big.NewFloat(float.Value).Int(nil)
+		element = object.IntegerFromBig(integer)
+	}
+	hashable, ok := element.(object.Hashable)
+	if !ok {
+		return object.HashKey{}, false
+	}
+	return hashable.HashKey(), true
+}
+
+// rangeLength counts the integers from start towards stop in steps of step
+// without overflowing when the bounds are far apart.
+func rangeLength(start, stop, step int64) uint64 {
+	switch {
+	case step > 0 && start < stop:
+		return (uint64(stop-start)-1)/uint64(step) + 1
+	case step < 0 && start > stop:
+		return (uint64(start-stop)-1)/uint64(-step) + 1
+	default:
+		return 0
+	}
+}
+
+func flatten(result []object.Object, elements []object.Object, depth int64) []object.Object {
+	for _, element := range elements {
+		if nested, ok := element.(*object.Array); ok && depth > 0 {
+			result = flatten(result, nested.Elements, depth-1)
+			continue
+		}
+		result = append(result, element)
+	}
+	return result
+}
+
+// objectsEqual compares numbers, strings and arrays by value and every
+// other object by identity, like the == operator does.
+func objectsEqual(a, b object.Object) bool {
+	if isNumber(a) && isNumber(b) {
+		return evalInfixExpression("==", a, b) == TRUE
+	}
+
+	switch a := a.(type) {
+	case *object.String:
+		b, ok := b.(*object.String)
+		return ok && a.Value == b.Value
+	case *object.Array:
+		b, ok := b.(*object.Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !objectsEqual(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func containsObject(elements []object.Object, obj object.Object) bool {
+	for _, element := range elements {
+		if objectsEqual(element, obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareObjects orders two numbers or two strings, returning a negative
+// number, zero or a positive number like strings.Compare.
+func compareObjects(a, b object.Object) (int, *object.Error) {
+	if isNumber(a) && isNumber(b) {
+		switch {
+		case evalInfixExpression("<", a, b) == TRUE:
+			return -1, nil
+		case evalInfixExpression(">", a, b) == TRUE:
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	switch a := a.(type) {
+	case *object.String:
+		if b, ok := b.(*object.String); ok {
+			switch {
+			case a.Value < b.Value:
+				return -1, nil
+			case a.Value > b.Value:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, newError(object.TYPE_ERROR, "cannot compare %s and %s", a.Type(), b.Type())
+}
@@ -0,0 +1,93 @@
+package evaluator_test
+
+import (
+	"monkey/object"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrayBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, "[2, 4, 6]"},
+		{`map([], fn(x) { x * 2 })`, "[]"},
+		{`map([[1], [1, 2]], len)`, "[1, 2]"},
+		{`map([1], 5)`, "ERROR: TypeError: argument to `map` must be FUNCTION, got INTEGER"},
+		{`map(1, len)`, "ERROR: TypeError: argument to `map` must be ARRAY, got INTEGER"},
+		{`map([1], fn(x, y) { x })`, "ERROR: ArityError: wrong number of arguments. got=1, want=2"},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, "[3, 4]"},
+		{`filter([1, 0, 2], fn(x) { x })`, "[1, 2]"},
+		{`reduce([1, 2, 3], 10, fn(acc, x) { acc + x })`, "16"},
+		{`reduce([], 10, fn(acc, x) { acc + x })`, "10"},
+		{`reduce(["a", "b"], "", fn(acc, x) { acc + x })`, "ab"},
+		{`each([1, 2], fn(x) { x })`, "null"},
+		{`find([1, 2, 3], fn(x) { x > 1 })`, "2"},
+		{`find([1, 2, 3], fn(x) { x > 5 })`, "null"},
+		{`any([1, 2, 3], fn(x) { x > 2 })`, "true"},
+		{`any([], fn(x) { true })`, "false"},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, "true"},
+		{`all([1, 2, 3], fn(x) { x > 1 })`, "false"},
+		{`all([], fn(x) { false })`, "true"},
+		{`zip([1, 2, 3], ["a", "b"])`, `[[1, a], [2, b]]`},
+		{`zip([1], [2], [3])`, "[[1, 2, 3]]"},
+		{`zip()`, "[]"},
+		{`flatten([1, [2, [3]], []])`, "[1, 2, [3]]"},
+		{`flatten([1, [2, [3]]], 5)`, "[1, 2, 3]"},
+		{`flatten([1, [2]], 0)`, "[1, [2]]"},
+		{`range(3)`, "[0, 1, 2]"},
+		{`range(2, 5)`, "[2, 3, 4]"},
+		{`range(5, 0, -2)`, "[5, 3, 1]"},
+		{`range(5, 0)`, "[]"},
+		{`range(0, 10, 3)`, "[0, 3, 6, 9]"},
+		{`range(9223372036854775806, 9223372036854775807, 5)`, "[9223372036854775806]"},
+		{`range(0, 1000000000000)`, "ERROR: LimitError: result of `range` would exceed 16777216 elements"},
+		{`range(-9223372036854775807, 9223372036854775807)`, "ERROR: LimitError: result of `range` would exceed 16777216 elements"},
+		{`range(0, 5, 0)`, "ERROR: ValueError: step passed to `range` must not be 0"},
+		{`range("a")`, "ERROR: TypeError: arguments to `range` must be INTEGER, got STRING"},
+		{`reverse([1, 2, 3])`, "[3, 2, 1]"},
+		{`reverse([])`, "[]"},
+		{`concat([1], [], [2, 3])`, "[1, 2, 3]"},
+		{`concat()`, "[]"},
+		{`index_of([1, 2, 3], 3)`, "2"},
+		{`index_of([[1], "a"], "a")`, "1"},
+		{`index_of([[1], "a"], [1])`, "0"},
+		{`index_of([1], 5)`, "-1"},
+		{`sort([3, 1, 2])`, "[1, 2, 3]"},
+		{`sort(["b", "c", "a"])`, "[a, b, c]"},
+		{`sort([3, 1, 2], fn(a, b) { a > b })`, "[3, 2, 1]"},
+		{`sort([[1, 2], [0], [3]], fn(a, b) { len(a) < len(b) })`, "[[0], [3], [1, 2]]"},
+		{`sort([1, "a"])`, "ERROR: TypeError: cannot compare STRING and INTEGER"},
+		{`sort([2, 1], fn(a, b) { a + true })`, "ERROR: TypeError: type mismatch: INTEGER + BOOLEAN"},
+		{`unique([1, 2, 1, 3, 2])`, "[1, 2, 3]"},
+		{`unique(["a", [1], "a", [1]])`, "[a, [1]]"},
+		{`unique([1, 1.0, 2.0, 2])`, "[1, 2.0]"},
+		{`unique([0.5, 0.5, -0.0, 0, 18446744073709551616, 18446744073709551616.0])`, "[0.5, -0.0, 18446744073709551616]"},
+		{`unique([[1], [1.0], {}, true, true])`, "[[1], {}, true]"},
+		{`len(unique(range(0, 50000)))`, "50000"},
+		{`let xs = [3, 1, 2]; sort(xs); xs`, "[3, 1, 2]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		assert.Equal(t, evaluated.Inspect(), tt.expected, tt.input)
+	}
+}
+
+func TestArrayBuiltinCallbackStack(t *testing.T) {
+	input := `let check = fn(x) { x + "a" };
+map([1], fn(x) { check(x) })`
+
+	errObj, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned")
+	}
+
+	frames := []string{}
+	for _, frame := range errObj.Stack {
+		frames = append(frames, frame.String())
+	}
+	assert.Equal(t, frames, []string{"at check (2:23)", "at <anonymous>", "at map (2:4)"})
+}
@@ -0,0 +1,211 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey/object"
+	"unicode/utf8"
+)
+
+// defaultBuiltins serves environments without a runtime registry. It must
+// not be modified; use NewBuiltins for a registry of your own.
+var defaultBuiltins *object.Builtins
+
+// Builtins such as map call back into the evaluator, so the registry is
+// built in init to break the initialization cycle through Eval.
+func init() {
+	defaultBuiltins = NewBuiltins()
+}
+
+// NewBuiltins returns a registry holding the standard builtins.
+func NewBuiltins() *object.Builtins {
+	builtins := object.NewBuiltins()
+	builtins.Register(coreBuiltins...)
+	builtins.Register(arrayBuiltins...)
+	builtins.Register(stringBuiltins...)
+	builtins.Register(regexBuiltins...)
+	builtins.Register(jsonBuiltins...)
+	builtins.Register(mathBuiltins...)
+	return builtins
+}
+
+var coreBuiltins = []*object.Builtin{
+	{
+		Name:   "len",
+		Params: []string{"value"},
+		Doc:    "Returns the number of characters in a string or elements in an array.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			switch arg := args[0].(type) {
+			case *object.String:
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+			case *object.Array:
+				return &object.Integer{Value: int64(len(arg.Elements))}
+			default:
+				return newError(object.TYPE_ERROR, "argument to `len` not supported. got %s", args[0].Type())
+			}
+		},
+	},
+	{
+		Name:   "first",
+		Params: []string{"array"},
+		Doc:    "Returns the first element of an array, or null if it is empty.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got=%s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+			if len(arr.Elements) > 0 {
+				return arr.Elements[0]
+			}
+
+			return NULL
+		},
+	},
+	{
+		Name:   "last",
+		Params: []string{"array"},
+		Doc:    "Returns the last element of an array, or null if it is empty.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+			length := len(arr.Elements)
+			if length > 0 {
+				return arr.Elements[length-1]
+			}
+			return NULL
+		},
+	},
+	{
+		Name:   "rest",
+		Params: []string{"array"},
+		Doc:    "Returns a new array with every element but the first, or null if it is empty.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError(object.TYPE_ERROR, "argument to `rest` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+			length := len(arr.Elements)
+			if length > 0 {
+				newElements := make([]object.Object, length-1)
+				copy(newElements, arr.Elements[1:length])
+				return &object.Array{Elements: newElements}
+			}
+
+			return NULL
+		},
+	},
+	{
+		Name:   "push",
+		Params: []string{"array", "value"},
+		Doc:    "Returns a new array with value appended.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError(object.TYPE_ERROR, "argument to `push` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+			length := len(arr.Elements)
+
+			newElements := make([]object.Object, length+1)
+			copy(newElements, arr.Elements)
+			newElements[length] = args[1]
+
+			return &object.Array{Elements: newElements}
+		},
+	},
+	{
+		Name:   "error",
+		Params: []string{"message"},
+		Doc:    "Creates an error value that can be passed to throw.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			msg, ok := args[0].(*object.String)
+			if !ok {
+				return newError(object.TYPE_ERROR, "argument to `error` must be STRING, got %s", args[0].Type())
+			}
+			return &object.ErrorValue{Error: &object.Error{Kind: object.USER_ERROR, Message: msg.Value}}
+		},
+	},
+	{
+		Name:   "throw",
+		Params: []string{"error"},
+		Doc:    "Raises a message or error value. Rethrown errors keep their kind and stack trace and record the original as cause.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			switch arg := args[0].(type) {
+			case *object.String:
+				return &object.Error{Kind: object.USER_ERROR, Message: arg.Value}
+			case *object.ErrorValue:
+				stack := append([]object.StackFrame(nil), arg.Error.Stack...)
+				return &object.Error{Kind: arg.Error.Kind, Message: arg.Error.Message, Cause: arg.Error, Stack: stack}
+			default:
+				return newError(object.TYPE_ERROR, "argument to `throw` must be STRING or ERROR_VALUE, got %s", args[0].Type())
+			}
+		},
+	},
+	{
+		Name:   "print",
+		Params: []string{"format?", "args..."},
+		Doc:    "Prints a formatted line. The format uses Go fmt verbs.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			out := output(env)
+			if len(args) == 0 {
+				fmt.Fprintln(out)
+			} else {
+				var nativeArgs []interface{}
+				var format string
+				switch arg := args[0].(type) {
+				case *object.String:
+					format = arg.Value
+				default:
+					return newError(object.TYPE_ERROR, "format must be STRING. got=%s", arg.Type())
+				}
+				for _, arg := range args[1:] {
+					switch arg := arg.(type) {
+					case *object.Boolean:
+						nativeArgs = append(nativeArgs, arg.Value)
+					case *object.Integer:
+						nativeArgs = append(nativeArgs, arg.Value)
+					case *object.BigInt:
+						nativeArgs = append(nativeArgs, arg.Value)
+					case *object.Float:
+						nativeArgs = append(nativeArgs, arg.Value)
+					case *object.String:
+						nativeArgs = append(nativeArgs, arg.Value)
+					default:
+						return newError(object.TYPE_ERROR, "non-printable type. got=%s", arg.Type())
+					}
+				}
+				fmt.Fprintf(out, format, nativeArgs...)
+				fmt.Fprintln(out)
+			}
+			return NULL
+		},
+	},
+}
+
+func builtinsFor(env *object.Environment) *object.Builtins {
+	if runtime := env.Runtime(); runtime != nil && runtime.Builtins != nil {
+		return runtime.Builtins
+	}
+	return defaultBuiltins
+}
+
+func checkArity(builtin *object.Builtin, got int) *object.Error {
+	if builtin.Params == nil {
+		return nil
+	}
+
+	min, max := builtin.Arity()
+	switch {
+	case max < 0 && got < min:
+		return newError(object.ARITY_ERROR, "wrong number of arguments. got=%d, want at least %d", got, min)
+	case max >= 0 && min == max && got != min:
+		return newError(object.ARITY_ERROR, "wrong number of arguments. got=%d, want=%d", got, min)
+	case max >= 0 && (got < min || got > max):
+		return newError(object.ARITY_ERROR, "wrong number of arguments. got=%d, want %d to %d", got, min, max)
+	}
+	return nil
+}
@@ -2,8 +2,14 @@ package evaluator
 
 import (
 	"fmt"
+	"io"
+	"math"
+	"math/big"
 	"monkey/ast"
 	"monkey/object"
+	"monkey/resolver"
+	"monkey/token"
+	"os"
 )
 
 var (
@@ -12,129 +18,6 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
-var builtins = map[string]*object.Builtin{
-	"len": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			switch arg := args[0].(type) {
-			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
-			case *object.Array:
-				return &object.Integer{Value: int64(len(arg.Elements))}
-			default:
-				return newError("argument to `len` not supported. got %s", args[0].Type())
-			}
-		},
-	},
-	"first": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY, got=%s", args[0].Type())
-			}
-
-			arr := args[0].(*object.Array)
-			if len(arr.Elements) > 0 {
-				return arr.Elements[0]
-			}
-
-			return NULL
-		},
-	},
-	"last": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY, got %s", args[0].Type())
-			}
-
-			arr := args[0].(*object.Array)
-			length := len(arr.Elements)
-			if length > 0 {
-				return arr.Elements[length-1]
-			}
-			return NULL
-		},
-	},
-	"rest": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
-			}
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `rest` must be ARRAY, got %s", args[0].Type())
-			}
-
-			arr := args[0].(*object.Array)
-			length := len(arr.Elements)
-			if length > 0 {
-				newElements := make([]object.Object, length-1)
-				copy(newElements, arr.Elements[1:length])
-				return &object.Array{Elements: newElements}
-			}
-
-			return NULL
-		},
-	},
-	"push": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=2", len(args))
-			}
-
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `push` must be ARRAY, got %s", args[0].Type())
-			}
-
-			arr := args[0].(*object.Array)
-			length := len(arr.Elements)
-
-			newElements := make([]object.Object, length+1)
-			copy(newElements, arr.Elements)
-			newElements[length] = args[1]
-
-			return &object.Array{Elements: newElements}
-		},
-	},
-	"print": {
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) == 0 {
-				fmt.Println()
-			} else {
-				var nativeArgs []interface{}
-				var format string
-				switch arg := args[0].(type) {
-				case *object.String:
-					format = arg.Value
-				default:
-					return newError("format must be STRING. got=%s", arg.Type())
-				}
-				for _, arg := range args[1:] {
-					switch arg := arg.(type) {
-					case *object.Boolean:
-						nativeArgs = append(nativeArgs, arg.Value)
-					case *object.Integer:
-						nativeArgs = append(nativeArgs, arg.Value)
-					case *object.String:
-						nativeArgs = append(nativeArgs, arg.Value)
-					default:
-						return newError("non-printable type. got=%s", arg.Type())
-					}
-				}
-				fmt.Printf(format, nativeArgs...)
-				fmt.Println()
-			}
-			return NULL
-		},
-	},
-}
-
 func Eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
@@ -151,14 +34,23 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return val
 		}
 		env.Set(node.Name.Value, val)
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
 
 	// Expressions
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			return &object.BigInt{Value: node.Big}
+		}
 		return &object.Integer{Value: node.Value}
 	case *ast.BooleanLiteral:
 		return nativeBoolToBooleanObject(node.Value)
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.RegexLiteral:
+		return compileRegex(node.Pattern, node.Flags)
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
 		if isError(right) {
@@ -177,6 +69,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalInfixExpression(node.Operator, left, right)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
 		if isError(val) {
@@ -188,7 +82,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Name: node.Name, Parameters: params, Env: env, Body: body}
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
 		if isError(function) {
@@ -198,13 +92,15 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		return applyFunction(function, args, env, node.Token.Position)
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(node.Elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
+	case *ast.HashLiteral:
+		return evalHashLiteral(node, env)
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
@@ -215,6 +111,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return index
 		}
 		return evalIndexExpression(left, index)
+	case *ast.MemberExpression:
+		left := Eval(node.Object, env)
+		if isError(left) {
+			return left
+		}
+		return evalMemberExpression(left, node.Property.Value)
 	}
 	return nil
 }
@@ -222,12 +124,18 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 	for _, stmt := range stmts {
+		if err := step(stmt, env); err != nil {
+			traceError(err, stmt, env)
+			return err
+		}
+
 		result = Eval(stmt, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
 			return result.Value
 		case *object.Error:
+			traceError(result, stmt, env)
 			return result
 		}
 	}
@@ -238,7 +146,15 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	var result object.Object
 
 	for _, statement := range block.Statements {
+		if err := step(statement, env); err != nil {
+			traceError(err, statement, env)
+			return err
+		}
+
 		result = Eval(statement, env)
+		if err, ok := result.(*object.Error); ok {
+			traceError(err, statement, env)
+		}
 
 		if result != nil {
 			rt := result.Type()
@@ -258,7 +174,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -266,16 +182,20 @@ func evalInfixExpression(operator string, left object.Object, right object.Objec
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isInteger(left) && isInteger(right):
+		return evalBigIntInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
 		return nativeBoolToBooleanObject(left != right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TYPE_ERROR, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -293,6 +213,35 @@ func evalIfExpression(expr *ast.IfExpression, env *object.Environment) object.Ob
 	}
 }
 
+func evalTryExpression(expr *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(expr.Block, env)
+
+	if err, ok := result.(*object.Error); ok && expr.CatchBlock != nil {
+		catchEnv := object.NewEnclosedEnvironment(env)
+		if expr.CatchParameter != nil {
+			catchEnv.Set(expr.CatchParameter.Value, &object.ErrorValue{Error: err})
+		}
+		result = Eval(expr.CatchBlock, catchEnv)
+	}
+
+	if expr.FinallyBlock != nil {
+		// an error or return inside finally overrides the pending result
+		finally := Eval(expr.FinallyBlock, env)
+		if finally != nil {
+			ft := finally.Type()
+			if ft == object.RETURN_VALUE_OBJ || ft == object.ERROR_OBJ {
+				return finally
+			}
+		}
+	}
+
+	// an empty block evaluates to nothing, but the expression needs a value
+	if result == nil {
+		return NULL
+	}
+	return result
+}
+
 func evalBangOperatorExpression(right object.Object) object.Object {
 	switch right {
 	case TRUE:
@@ -307,26 +256,53 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			return object.IntegerFromBig(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.BigInt:
+		return object.IntegerFromBig(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalIntegerInfixExpression(operator string, left object.Object, right object.Object) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
+	// results that overflow int64 are computed again with big integers
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		if sum := leftVal + rightVal; (sum > leftVal) == (rightVal > 0) {
+			return &object.Integer{Value: sum}
+		}
+		return evalBigIntInfixExpression(operator, left, right)
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		if difference := leftVal - rightVal; (difference < leftVal) == (rightVal > 0) {
+			return &object.Integer{Value: difference}
+		}
+		return evalBigIntInfixExpression(operator, left, right)
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		if leftVal == 0 || rightVal == 0 {
+			return &object.Integer{Value: 0}
+		}
+		product := leftVal * rightVal
+		if product/rightVal == leftVal && !(leftVal == -1 && rightVal == math.MinInt64) && !(rightVal == -1 && leftVal == math.MinInt64) {
+			return &object.Integer{Value: product}
+		}
+		return evalBigIntInfixExpression(operator, left, right)
 	case "/":
+		if rightVal == 0 {
+			return newError(object.ZERO_DIVISION, "division by zero")
+		}
+		if leftVal == math.MinInt64 && rightVal == -1 {
+			return evalBigIntInfixExpression(operator, left, right)
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
@@ -337,7 +313,105 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalBigIntInfixExpression handles integer arithmetic and comparisons in
+// arbitrary precision. Division truncates toward zero like int64 division.
+func evalBigIntInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftVal := toBigInt(left)
+	rightVal := toBigInt(right)
+
+	switch operator {
+	case "+":
+		return object.IntegerFromBig(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return object.IntegerFromBig(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return object.IntegerFromBig(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError(object.ZERO_DIVISION, "division by zero")
+		}
+		return object.IntegerFromBig(new(big.Int).Quo(leftVal, rightVal))
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalFloatInfixExpression handles arithmetic and comparisons where at least
+// one operand is a float; integer operands are promoted.
+func evalFloatInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError(object.ZERO_DIVISION, "division by zero")
+		}
+		return &object.Float{Value: leftVal / rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isInteger(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.BIGINT_OBJ
+}
+
+func isNumber(obj object.Object) bool {
+	return isInteger(obj) || obj.Type() == object.FLOAT_OBJ
+}
+
+// toFloat converts an integer or float object to a float64.
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
+
+// toBigInt converts an integer object to a big.Int that callers must not
+// modify.
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInt:
+		return obj.Value
+	default:
+		return new(big.Int)
 	}
 }
 
@@ -357,27 +431,106 @@ func evalStringInfixExpression(operator string, left object.Object, right object
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
+	val, scope := env.Resolve(node.Value)
+	if scope != nil && !isPrelude(scope) {
 		return val
 	}
-	if builtin, ok := builtins[node.Value]; ok {
+	// Builtins take precedence over the prelude so that hosts can replace
+	// its functions with native ones.
+	if builtin, ok := builtinsFor(env).Lookup(node.Value); ok {
 		return builtin
 	}
-	return newError("identifier not found: " + node.Value)
+	if scope != nil {
+		return val
+	}
+	return newError(object.NAME_ERROR, "identifier not found: %s", node.Value)
+}
+
+// Resolve checks the names used by program before it is evaluated in env,
+// returning the first problem found as a NameError.
+func Resolve(program *ast.Program, env *object.Environment) *object.Error {
+	errs := resolver.Resolve(program, func(name string) bool {
+		if _, ok := env.Get(name); ok {
+			return true
+		}
+		_, ok := builtinsFor(env).Lookup(name)
+		return ok
+	})
+	if len(errs) == 0 {
+		return nil
+	}
+	err := newError(object.NAME_ERROR, "%s", errs[0].Message)
+	err.Stack = []object.StackFrame{{Function: "<program>", Position: errs[0].Position}}
+	return err
+}
+
+func isPrelude(env *object.Environment) bool {
+	runtime := env.Runtime()
+	return runtime != nil && runtime.Prelude == env
 }
 
 func evalIndexExpression(left object.Object, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.ARRAY_OBJ:
+		return newError(object.INDEX_ERROR, "array index must be INTEGER, got %s", index.Type())
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+	default:
+		return newError(object.TYPE_ERROR, "index operator not supported: %s", left.Type())
+	}
+}
+
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	switch obj := obj.(type) {
+	case *object.ErrorValue:
+		return evalErrorValueMember(obj, name)
+	case *object.Hash:
+		if value, ok := obj.Get(&object.String{Value: name}); ok {
+			return value
+		}
+		return NULL
+	case *object.Namespace:
+		if member, ok := obj.Member(name); ok {
+			return member
+		}
+		return newError(object.NAME_ERROR, "unknown member of %s: %s", obj.Name, name)
+	case *object.Module:
+		if value, ok := obj.Exports[name]; ok {
+			return value
+		}
+		return newError(object.NAME_ERROR, "module %s has no export %s", obj.Name, name)
+	default:
+		return newError(object.TYPE_ERROR, "member access not supported: %s.%s", obj.Type(), name)
+	}
+}
+
+func evalErrorValueMember(ev *object.ErrorValue, name string) object.Object {
+	switch name {
+	case "message":
+		return &object.String{Value: ev.Error.Message}
+	case "kind":
+		return &object.String{Value: string(ev.Error.Kind)}
+	case "cause":
+		if cause, ok := ev.Error.Cause.(*object.Error); ok {
+			return &object.ErrorValue{Error: cause}
+		}
+		return NULL
+	case "stack":
+		frames := make([]object.Object, len(ev.Error.Stack))
+		for i, frame := range ev.Error.Stack {
+			frames[i] = &object.String{Value: frame.String()}
+		}
+		return &object.Array{Elements: frames}
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newError(object.NAME_ERROR, "unknown member of ERROR_VALUE: %s", name)
 	}
 }
 
@@ -405,16 +558,104 @@ func evalArrayIndexExpression(array object.Object, index object.Object) object.O
 	return arrayObject.Elements[idx]
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+// ApplyFunction calls a function or builtin object with already evaluated
+// arguments. env supplies the runtime the call is subject to.
+func ApplyFunction(fn object.Object, args []object.Object, env *object.Environment) object.Object {
+	return applyFunction(fn, args, env, token.Position{})
+}
+
+func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+
+		if _, ok := key.(object.Hashable); !ok {
+			return newError(object.TYPE_ERROR, "unusable as hash key: %s", key.Type())
+		}
+
+		value := Eval(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		hash.Set(key, value)
+	}
+
+	return hash
+}
+
+func evalHashIndexExpression(hash object.Object, index object.Object) object.Object {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
+	}
+
+	if value, ok := hashObject.Get(key); ok {
+		return value
+	}
+	return NULL
+}
+
+func applyFunction(fn object.Object, args []object.Object, env *object.Environment, callSite token.Position) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
+		if len(args) != len(fn.Parameters) {
+			err := newError(object.ARITY_ERROR, "wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters))
+			pushStackFrame(err, fn, callSite)
+			return err
+		}
+		if runtime := env.Runtime(); runtime != nil {
+			if runtime.MaxDepth > 0 && runtime.Depth >= runtime.MaxDepth {
+				return newError(object.LIMIT_ERROR, "maximum call depth of %d exceeded", runtime.MaxDepth)
+			}
+			runtime.Depth++
+			defer func() { runtime.Depth-- }()
+
+			if runtime.Debugger != nil {
+				runtime.Calls = append(runtime.Calls, object.StackFrame{Function: functionName(fn), Position: callSite})
+				defer func() { runtime.Calls = runtime.Calls[:len(runtime.Calls)-1] }()
+			}
+		}
 		extendedEnv := extendFunctionEnv(fn, args)
+		trace := tracer(env)
+		if trace != nil {
+			trace.Enter(fn, args, callSite)
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		if err, ok := evaluated.(*object.Error); ok {
+			pushStackFrame(err, fn, callSite)
+		}
+		result := unwrapReturnValue(evaluated)
+		if trace != nil {
+			trace.Exit(fn, result)
+		}
+		return result
 	case *object.Builtin:
-		return fn.Fn(args...)
+		if err := checkArity(fn, len(args)); err != nil {
+			return err
+		}
+		trace := tracer(env)
+		if trace != nil {
+			trace.Enter(fn, args, callSite)
+		}
+		result := fn.Fn(env, args...)
+		if trace != nil {
+			trace.Exit(fn, result)
+		}
+		// Errors raised by functions the builtin called back into already
+		// carry a stack; record the builtin as their caller.
+		if err, ok := result.(*object.Error); ok && len(err.Stack) > 0 {
+			err.Stack = append(err.Stack, object.StackFrame{Function: fn.Name, Position: callSite})
+		}
+		return result
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newError(object.TYPE_ERROR, "not a function: %s", fn.Type())
 	}
 }
 
@@ -426,6 +667,78 @@ func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Enviro
 	return env
 }
 
+// step accounts for stmt against the runtime limits of env, gives an
+// attached debugger the chance to pause before it, and returns an error once
+// the run has been cancelled or exhausted its budget.
+func step(stmt ast.Statement, env *object.Environment) *object.Error {
+	runtime := env.Runtime()
+	if runtime == nil {
+		return nil
+	}
+
+	runtime.Steps++
+	if runtime.MaxSteps > 0 && runtime.Steps > runtime.MaxSteps {
+		return newError(object.LIMIT_ERROR, "maximum of %d steps exceeded", runtime.MaxSteps)
+	}
+
+	if runtime.Debugger != nil {
+		if err := runtime.Debugger.Before(stmt, env); err != nil {
+			stopped := newError(object.INTERRUPTED, "execution stopped: %s", err)
+			stopped.Cause = err
+			return stopped
+		}
+	}
+
+	if runtime.Context != nil {
+		if err := runtime.Context.Err(); err != nil {
+			timeout := newError(object.TIMEOUT, "execution stopped: %s", err)
+			timeout.Cause = err
+			return timeout
+		}
+	}
+
+	if runtime.Tracer != nil {
+		runtime.Tracer.Statement(stmt, env)
+	}
+	return nil
+}
+
+func tracer(env *object.Environment) object.Tracer {
+	if runtime := env.Runtime(); runtime != nil {
+		return runtime.Tracer
+	}
+	return nil
+}
+
+// traceError reports err to the tracer the first time a statement fails
+// with it, which is the statement that raised it.
+func traceError(err *object.Error, stmt ast.Statement, env *object.Environment) {
+	runtime := env.Runtime()
+	if runtime == nil || runtime.Tracer == nil || runtime.Raised == err {
+		return
+	}
+	runtime.Raised = err
+	runtime.Tracer.Error(err, stmt)
+}
+
+func output(env *object.Environment) io.Writer {
+	if runtime := env.Runtime(); runtime != nil && runtime.Output != nil {
+		return runtime.Output
+	}
+	return os.Stdout
+}
+
+func pushStackFrame(err *object.Error, fn *object.Function, callSite token.Position) {
+	err.Stack = append(err.Stack, object.StackFrame{Function: functionName(fn), Position: callSite})
+}
+
+func functionName(fn *object.Function) string {
+	if fn.Name == "" {
+		return "<anonymous>"
+	}
+	return fn.Name
+}
+
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
 		return returnValue.Value
@@ -452,14 +765,16 @@ func isTruthy(obj object.Object) bool {
 		switch obj := obj.(type) {
 		case *object.Integer:
 			return obj.Value != 0
+		case *object.Float:
+			return obj.Value != 0
 		default:
 			return true
 		}
 	}
 }
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+func newError(kind object.ErrorKind, format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
 }
 
 func isError(obj object.Object) bool {
@@ -1,6 +1,7 @@
 package evaluator_test
 
 import (
+	"errors"
 	"monkey/evaluator"
 	"monkey/lexer"
 	"monkey/object"
@@ -38,6 +39,66 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2.5", "2.5"},
+		{"-2.5", "-2.5"},
+		{"1.5 + 1.5", "3.0"},
+		{"1 + 0.5", "1.5"},
+		{"0.5 * 4", "2.0"},
+		{"7 / 2", "3"},
+		{"7 / 2.0", "3.5"},
+		{"1.5 / 2 / 3", "0.25"},
+		{"let x = 9; let y = 1.5; x / 2.0 / y", "3.0"},
+		{"1e3 - 1", "999.0"},
+		{"1.0 / 0", "ERROR: ZeroDivision: division by zero"},
+		{"1.5 < 2", "true"},
+		{"2 > 1.5", "true"},
+		{"1 == 1.0", "true"},
+		{"1.5 != 1.5", "false"},
+		{"if (0.0) { 1 } else { 2 }", "2"},
+		{`1.5 + "a"`, "ERROR: TypeError: type mismatch: FLOAT + STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		assert.Equal(t, evaluated.Inspect(), tt.expected, tt.input)
+	}
+}
+
+func TestEvalBigIntExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"9223372036854775807 + 1 - 1", "9223372036854775807"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"-9223372036854775808", "-9223372036854775808"},
+		{"-(-9223372036854775807 - 1)", "9223372036854775808"},
+		{"4294967296 * 4294967296", "18446744073709551616"},
+		{"123456789012345678901234567890 / 10", "12345678901234567890123456789"},
+		{"123456789012345678901234567890 / 0", "ERROR: ZeroDivision: division by zero"},
+		{"let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(25)", "15511210043330985984000000"},
+		{"18446744073709551616 > 1", "true"},
+		{"18446744073709551616 == 4294967296 * 4294967296", "true"},
+		{"18446744073709551616 != 18446744073709551617", "true"},
+		{"18446744073709551616 + 0.5", "1.8446744073709552e+19"},
+		{"abs(-9223372036854775807 - 1)", "9223372036854775808"},
+		{`to_int("99999999999999999999")`, "99999999999999999999"},
+		{`json_stringify(json_parse("[18446744073709551616]"))`, "[18446744073709551616]"},
+		{`{18446744073709551616: "big"}[4294967296 * 4294967296]`, "big"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		assert.Equal(t, evaluated.Inspect(), tt.expected, tt.input)
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -220,6 +281,14 @@ func TestErrorHandling(t *testing.T) {
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			"let n = 5; n.message",
+			"member access not supported: INTEGER.message",
+		},
+		{
+			`error("a").foo`,
+			"unknown member of ERROR_VALUE: foo",
+		},
 	}
 
 	for _, tt := range tests {
@@ -235,6 +304,159 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorStackTrace(t *testing.T) {
+	input := `let inner = fn(x) { x + "a" };
+let outer = fn(x) {
+  inner(x)
+};
+let run = fn() { fn() { outer(1) }() };
+run();`
+
+	evaluated := testEval(input)
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	assert.Equal(t, errObj.Message, "type mismatch: INTEGER + STRING")
+
+	expected := []string{
+		"at inner (3:8)",
+		"at outer (5:30)",
+		"at <anonymous> (5:35)",
+		"at run (6:4)",
+	}
+	frames := []string{}
+	for _, frame := range errObj.Stack {
+		frames = append(frames, frame.String())
+	}
+	assert.Equal(t, frames, expected)
+}
+
+func TestTryCatchFinally(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { 1 + true } catch (e) { 2 }`, 2},
+		{`try { throw("boom") } catch (e) { e.message }`, "boom"},
+		{`try { throw("boom") } catch (e) { e.kind }`, "UserError"},
+		{`try { 1 + true } catch (e) { e.kind }`, "TypeError"},
+		{`try { throw(error("boom")) } catch (e) { e.message }`, "boom"},
+		{`try { try { throw("a") } catch (e) { throw(e) } } catch (e) { e.message }`, "a"},
+		{`try { try { 1 / 0 } catch (e) { throw(e) } } catch (e) { e.cause.kind }`, "ZeroDivision"},
+		{`let x = 1; try { x } finally { let x = 2; }; x`, 2},
+		{`let x = 1; try { throw("a") } catch { let x = 3; } finally { let x = x * 2; }; x`, 2},
+		{`let f = fn() { try { return 1; } finally { return 2; } }; f()`, 2},
+		{`let f = fn() { try { return 1; } finally { 2 } }; f()`, 1},
+		{`try { throw("a") } finally { 1 }`, "a"},
+		{`try { 1 } finally { throw("b") }`, "b"},
+		{`try { throw("a") } catch { throw("c") } finally { 1 }`, "c"},
+		{`try {} catch (e) {}`, nil},
+		{`try { throw("a") } catch (e) {}`, nil},
+		{`try {} finally {}`, nil},
+		{`let x = try { throw("a") } catch (e) {}; x`, nil},
+		{`let x = try { throw("a") } catch (e) {}; x + 1`, "type mismatch: NULL + INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				assert.Equal(t, obj.Value, expected)
+			case *object.Error:
+				assert.Equal(t, obj.Message, expected)
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedKind object.ErrorKind
+	}{
+		{"5 + true;", object.TYPE_ERROR},
+		{"-true", object.TYPE_ERROR},
+		{"foobar", object.NAME_ERROR},
+		{"1 / 0", object.ZERO_DIVISION},
+		{"len(1, 2)", object.ARITY_ERROR},
+		{"fn(x) { x }()", object.ARITY_ERROR},
+		{"fn(x) { x }(1, 2)", object.ARITY_ERROR},
+		{"5()", object.TYPE_ERROR},
+		{`throw("boom")`, object.USER_ERROR},
+		{`[1, 2]["a"]`, object.INDEX_ERROR},
+		{`[1, 2][1.0]`, object.INDEX_ERROR},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		assert.Equal(t, errObj.Kind, tt.expectedKind)
+	}
+}
+
+func TestErrorGoBridge(t *testing.T) {
+	evaluated := testEval(`try { 1 / 0 } catch (e) { throw(e) }`)
+
+	var err error = evaluated.(*object.Error)
+	assert.EqualError(t, err, "ZeroDivision: division by zero")
+	assert.True(t, errors.Is(err, object.ZERO_DIVISION))
+	assert.False(t, errors.Is(err, object.TYPE_ERROR))
+
+	var cause *object.Error
+	assert.True(t, errors.As(errors.Unwrap(err), &cause))
+	assert.Equal(t, cause.Message, "division by zero")
+}
+
+func TestCaughtErrorStack(t *testing.T) {
+	input := `let fail = fn() { throw("boom") };
+try { fail() } catch (e) { e.stack }`
+
+	evaluated := testEval(input)
+	arr, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	assert.Len(t, arr.Elements, 1)
+	assert.Equal(t, arr.Elements[0].Inspect(), "at fail (2:11)")
+}
+
+func TestRethrownErrorStack(t *testing.T) {
+	input := `let fail = fn() { throw("boom") };
+let rethrow = fn() { try { fail() } catch (e) { throw(e) } };
+try { rethrow() } catch (e) { e.stack }`
+
+	evaluated := testEval(input)
+	arr, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	frames := []string{}
+	for _, frame := range arr.Elements {
+		frames = append(frames, frame.Inspect())
+	}
+	assert.Equal(t, frames, []string{"at fail (2:32)", "at throw (2:54)", "at rethrow (3:14)"})
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -304,6 +526,11 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`push([1], 2)`, []int{1, 2}},
 		{`push([1])`, "wrong number of arguments. got=1, want=2"},
 		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
+		{`throw("boom")`, "boom"},
+		{`throw(1)`, "argument to `throw` must be STRING or ERROR_VALUE, got INTEGER"},
+		{`error(1)`, "argument to `error` must be STRING, got INTEGER"},
+		{`throw()`, "wrong number of arguments. got=0, want=1"},
+		{`print(1)`, "format must be STRING. got=INTEGER"},
 	}
 
 	for _, tt := range tests {
@@ -400,6 +627,132 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		evaluator.TRUE.HashKey():                   5,
+		evaluator.FALSE.HashKey():                  6,
+	}
+
+	assert.Equal(t, len(result.Pairs), len(expected))
+
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf("no pair for given key in Pairs")
+		}
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{"foo": 5}.foo`, 5},
+		{`{"foo": 5}.bar`, nil},
+		{`{"foo": {"bar": 6}}.foo.bar`, 6},
+		{`{"foo": 5}[fn(x) { x }]`, "unusable as hash key: FUNCTION"},
+		{`{fn(x) { x }: 5}`, "unusable as hash key: FUNCTION"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			assert.Equal(t, errObj.Message, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestFloatObject(t *testing.T) {
+	tests := []struct {
+		input    string
+		value    float64
+		expected string
+	}{
+		{"x", 0.5, "0.5"},
+		{"x", 2, "2.0"},
+		{"x", -3, "-3.0"},
+		{"x", 1e21, "1e+21"},
+		{"[x][0]", 1.25, "1.25"},
+		{`{"x": x}.x`, 1.25, "1.25"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.Set("x", &object.Float{Value: tt.value})
+		evaluated := evaluator.Eval(program, env)
+
+		result, ok := evaluated.(*object.Float)
+		if !ok {
+			t.Errorf("object is not Float. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		assert.Equal(t, result.Value, tt.value)
+		assert.Equal(t, result.Inspect(), tt.expected)
+	}
+}
+
+func TestResolvedIdentifiers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1; let f = fn(c) { if (c) { let x = 2; }; x }; [f(true), f(false)]", "[2, 1]"},
+		{"let x = 1; let f = fn() { let g = fn() { x }; let x = 2; g() }; f()", "2"},
+		{"let e = 1; try { error(\"boom\") } catch (e) { e.message } finally { e }", "UserError: boom"},
+		{"let len = fn(x) { 0 }; len([1, 2])", "0"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		assert.Nil(t, evaluator.Resolve(program, env), tt.input)
+		assert.Equal(t, evaluator.Eval(program, env).Inspect(), tt.expected, tt.input)
+	}
+
+	program := parser.New(lexer.New("fn(a, a) { a }")).ParseProgram()
+	err := evaluator.Resolve(program, object.NewEnvironment())
+	assert.Equal(t, err.Error(), "NameError: duplicate parameter a")
+}
+
 func testEval(input string) object.Object {
 	lexer := lexer.New(input)
 	parser := parser.New(lexer)
@@ -0,0 +1,201 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"math/big"
+	"monkey/object"
+	"sort"
+	"strings"
+)
+
+// MAX_JSON_INDENT is the longest indentation json_stringify accepts, as a
+// number of spaces or as a string.
+const MAX_JSON_INDENT = 10
+
+var jsonBuiltins = []*object.Builtin{
+	{
+		Name:   "json_parse",
+		Params: []string{"string"},
+		Doc:    "Parses a JSON document. Objects become hashes and numbers become integers unless they have a fraction or exponent.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			strs, err := stringArguments("json_parse", args)
+			if err != nil {
+				return err
+			}
+
+			decoder := json.NewDecoder(strings.NewReader(strs[0]))
+			decoder.UseNumber()
+
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return newError(object.VALUE_ERROR, "invalid JSON: %s", err)
+			}
+			if _, err := decoder.Token(); err != io.EOF {
+				return newError(object.VALUE_ERROR, "invalid JSON: unexpected data after top-level value")
+			}
+			return fromJSON(value)
+		},
+	},
+	{
+		Name:   "json_stringify",
+		Params: []string{"value", "indent?"},
+		Doc:    "Encodes a value as JSON with hash keys sorted. Indent is a number of spaces or an indentation string.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			indent := ""
+			if len(args) > 1 {
+				switch arg := args[1].(type) {
+				case *object.Integer:
+					if arg.Value < 0 || arg.Value > MAX_JSON_INDENT {
+						return newError(object.VALUE_ERROR, "indent passed to `json_stringify` must be between 0 and %d, got %d", MAX_JSON_INDENT, arg.Value)
+					}
+					indent = strings.Repeat(" ", int(arg.Value))
+				case *object.String:
+					if len(arg.Value) > MAX_JSON_INDENT {
+						return newError(object.VALUE_ERROR, "indent passed to `json_stringify` must be at most %d characters, got %d", MAX_JSON_INDENT, len(arg.Value))
+					}
+					if strings.Trim(arg.Value, " \t\n\r") != "" {
+						return newError(object.VALUE_ERROR, "indent passed to `json_stringify` must only contain whitespace, got %q", arg.Value)
+					}
+					indent = arg.Value
+				default:
+					return newError(object.TYPE_ERROR, "indent passed to `json_stringify` must be INTEGER or STRING, got %s", arg.Type())
+				}
+			}
+
+			var out bytes.Buffer
+			if err := toJSON(&out, args[0], map[object.Object]bool{}); err != nil {
+				return err
+			}
+			if indent == "" {
+				return &object.String{Value: out.String()}
+			}
+
+			var indented bytes.Buffer
+			json.Indent(&indented, out.Bytes(), "", indent)
+			return &object.String{Value: indented.String()}
+		},
+	},
+}
+
+func fromJSON(value interface{}) object.Object {
+	switch value := value.(type) {
+	case nil:
+		return NULL
+	case bool:
+		return nativeBoolToBooleanObject(value)
+	case string:
+		return &object.String{Value: value}
+	case json.Number:
+		if integer, err := value.Int64(); err == nil {
+			return &object.Integer{Value: integer}
+		}
+		if integer, ok := new(big.Int).SetString(value.String(), 10); ok {
+			return object.IntegerFromBig(integer)
+		}
+		float, err := value.Float64()
+		if err != nil {
+			return newError(object.VALUE_ERROR, "JSON number %s is out of range", value)
+		}
+		return &object.Float{Value: float}
+	case []interface{}:
+		elements := make([]object.Object, len(value))
+		for i, element := range value {
+			elements[i] = fromJSON(element)
+			if isError(elements[i]) {
+				return elements[i]
+			}
+		}
+		return &object.Array{Elements: elements}
+	case map[string]interface{}:
+		hash := object.NewHash()
+		for key, element := range value {
+			converted := fromJSON(element)
+			if isError(converted) {
+				return converted
+			}
+			hash.Set(&object.String{Value: key}, converted)
+		}
+		return hash
+	default:
+		return newError(object.VALUE_ERROR, "invalid JSON value %v", value)
+	}
+}
+
+// toJSON writes the compact encoding of obj to out. Arrays and hashes being
+// encoded are tracked in visiting to reject cyclic values.
+func toJSON(out *bytes.Buffer, obj object.Object, visiting map[object.Object]bool) *object.Error {
+	switch obj := obj.(type) {
+	case *object.Null:
+		out.WriteString("null")
+	case *object.Boolean, *object.Integer, *object.BigInt:
+		out.WriteString(obj.Inspect())
+	case *object.Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return newError(object.VALUE_ERROR, "cannot encode %s as JSON", obj.Inspect())
+		}
+		out.WriteString(obj.Inspect())
+	case *object.String:
+		writeJSONString(out, obj.Value)
+	case *object.Array:
+		if visiting[obj] {
+			return newError(object.VALUE_ERROR, "cannot encode cyclic value as JSON")
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		out.WriteByte('[')
+		for i, element := range obj.Elements {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := toJSON(out, element, visiting); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	case *object.Hash:
+		if visiting[obj] {
+			return newError(object.VALUE_ERROR, "cannot encode cyclic value as JSON")
+		}
+		visiting[obj] = true
+		defer delete(visiting, obj)
+
+		pairs := make(map[string]object.Object, len(obj.Pairs))
+		keys := make([]string, 0, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key := pair.Key.Inspect()
+			if _, ok := pairs[key]; ok {
+				return newError(object.VALUE_ERROR, "cannot encode hash with duplicate JSON key %q", key)
+			}
+			pairs[key] = pair.Value
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		out.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			writeJSONString(out, key)
+			out.WriteByte(':')
+			if err := toJSON(out, pairs[key], visiting); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	default:
+		return newError(object.TYPE_ERROR, "cannot encode %s as JSON", obj.Type())
+	}
+	return nil
+}
+
+func writeJSONString(out *bytes.Buffer, str string) {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(str)
+	out.Truncate(out.Len() - 1) // Encode terminates the value with a newline
+}
@@ -0,0 +1,69 @@
+package evaluator_test
+
+import (
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONBuiltins(t *testing.T) {
+	tests := []struct {
+		document string
+		input    string
+		expected string
+	}{
+		{`42`, `json_parse(doc)`, "42"},
+		{`-1.5e3`, `json_parse(doc)`, "-1500.0"},
+		{`2.0`, `json_parse(doc)`, "2.0"},
+		{`[1, "a", true, null]`, `json_parse(doc)`, "[1, a, true, null]"},
+		{`{"b": {"c": []}, "a": 1}`, `json_parse(doc)`, "{a: 1, b: {c: []}}"},
+		{`{"a": 1}`, `json_parse(doc)["a"]`, "1"},
+		{`[1,`, `json_parse(doc)`, "ERROR: ValueError: invalid JSON: unexpected EOF"},
+		{`1e400`, `json_parse(doc)`, "ERROR: ValueError: JSON number 1e400 is out of range"},
+		{`{"a": [-1e400]}`, `json_parse(doc)`, "ERROR: ValueError: JSON number -1e400 is out of range"},
+		{`1 2`, `json_parse(doc)`, "ERROR: ValueError: invalid JSON: unexpected data after top-level value"},
+		{``, `json_parse(1)`, "ERROR: TypeError: argument to `json_parse` must be STRING, got INTEGER"},
+		{`a"<b>`, `json_stringify(doc)`, `"a\"<b>"`},
+		{`{"z": [1.5, "x"]}`, `json_stringify(json_parse(doc))`, `{"z":[1.5,"x"]}`},
+		{``, `json_stringify(42)`, "42"},
+		{``, `json_stringify([1, "a", true, first([])])`, `[1,"a",true,null]`},
+		{``, `json_stringify({"b": 1, "a": [2], 3: false})`, `{"3":false,"a":[2],"b":1}`},
+		{``, `json_stringify({"a": [1]}, 2)`, "{\n  \"a\": [\n    1\n  ]\n}"},
+		{``, `json_stringify([], "  ")`, "[]"},
+		{``, "json_stringify([1], \"\t\")", "[\n\t1\n]"},
+		{``, `json_stringify([1], repeat(" ", 11))`, "ERROR: ValueError: indent passed to `json_stringify` must be at most 10 characters, got 11"},
+		{``, `json_stringify([1], "--")`, `ERROR: ValueError: indent passed to ` + "`json_stringify`" + ` must only contain whitespace, got "--"`},
+		{``, `json_stringify([1], -1)`, "ERROR: ValueError: indent passed to `json_stringify` must be between 0 and 10, got -1"},
+		{``, `json_stringify([1], 9223372036854775807)`, "ERROR: ValueError: indent passed to `json_stringify` must be between 0 and 10, got 9223372036854775807"},
+		{``, `json_stringify({1: 1, "1": 2})`, `ERROR: ValueError: cannot encode hash with duplicate JSON key "1"`},
+		{``, `json_stringify([fn(x) { x }])`, "ERROR: TypeError: cannot encode FUNCTION as JSON"},
+		{``, `json_stringify(len)`, "ERROR: TypeError: cannot encode BUILTIN as JSON"},
+		{``, `json_stringify(1, true)`, "ERROR: TypeError: indent passed to `json_stringify` must be INTEGER or STRING, got BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		env.Set("doc", &object.String{Value: tt.document})
+
+		evaluated := evaluator.Eval(program, env)
+		assert.Equal(t, evaluated.Inspect(), tt.expected, tt.input)
+	}
+}
+
+func TestJSONStringifyCycle(t *testing.T) {
+	hash := object.NewHash()
+	hash.Set(&object.String{Value: "self"}, &object.Array{Elements: []object.Object{hash}})
+
+	stringify, _ := evaluator.NewBuiltins().Get("json_stringify")
+	evaluated := stringify.Fn(object.NewEnvironment(), hash)
+	assert.Equal(t, evaluated.Inspect(), "ERROR: ValueError: cannot encode cyclic value as JSON")
+
+	shared := &object.Array{Elements: []object.Object{}}
+	evaluated = stringify.Fn(object.NewEnvironment(), &object.Array{Elements: []object.Object{shared, shared}})
+	assert.Equal(t, evaluated.Inspect(), "[[],[]]")
+}
@@ -0,0 +1,382 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"monkey/object"
+	"strconv"
+	"strings"
+)
+
+// MAX_POW_BITS bounds the size of integers produced by pow, so that a typo
+// in an exponent cannot exhaust memory.
+const MAX_POW_BITS = 1 << 20
+
+// MAX_ROUND_DIGITS is the most decimals round accepts; a float64 holds no
+// more than 17 significant digits.
+const MAX_ROUND_DIGITS = 17
+
+var mathBuiltins = []*object.Builtin{
+	{
+		Name:  "PI",
+		Doc:   "The ratio of a circle's circumference to its diameter.",
+		Value: &object.Float{Value: math.Pi},
+	},
+	{
+		Name:  "E",
+		Doc:   "Euler's number, the base of natural logarithms.",
+		Value: &object.Float{Value: math.E},
+	},
+	{
+		Name:   "abs",
+		Params: []string{"x"},
+		Doc:    "Returns the absolute value of x.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				if arg.Value < 0 {
+					return evalMinusPrefixOperatorExpression(arg)
+				}
+				return arg
+			case *object.BigInt:
+				return &object.BigInt{Value: new(big.Int).Abs(arg.Value)}
+			case *object.Float:
+				return &object.Float{Value: math.Abs(arg.Value)}
+			default:
+				return numberTypeError("abs", arg)
+			}
+		},
+	},
+	{
+		Name:   "min",
+		Params: []string{"values..."},
+		Doc:    "Returns the smallest of the arguments, or of the elements of a single array argument.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			return extremum("min", args, -1)
+		},
+	},
+	{
+		Name:   "max",
+		Params: []string{"values..."},
+		Doc:    "Returns the largest of the arguments, or of the elements of a single array argument.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			return extremum("max", args, 1)
+		},
+	},
+	{
+		Name:   "sum",
+		Params: []string{"array"},
+		Doc:    "Adds up an array of numbers. The result is a float if any element is.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, err := arrayArgument("sum", args[0])
+			if err != nil {
+				return err
+			}
+
+			var total object.Object = &object.Integer{Value: 0}
+			for _, element := range arr.Elements {
+				if !isNumber(element) {
+					return numberTypeError("sum", element)
+				}
+				total = evalInfixExpression("+", total, element)
+			}
+			return total
+		},
+	},
+	{
+		Name:   "pow",
+		Params: []string{"base", "exponent"},
+		Doc:    "Returns base raised to exponent. Integers with a non-negative integer exponent give an integer.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if err := numberArguments("pow", args); err != nil {
+				return err
+			}
+
+			exponent, exponentIsInt := args[1].(*object.Integer)
+			if isInteger(args[0]) && exponentIsInt && exponent.Value >= 0 {
+				base := toBigInt(args[0])
+				if bits := int64(base.BitLen()); bits > 1 && exponent.Value > MAX_POW_BITS/bits {
+					return newError(object.LIMIT_ERROR, "result of `pow` would exceed %d bits", MAX_POW_BITS)
+				}
+				return object.IntegerFromBig(new(big.Int).Exp(base, big.NewInt(exponent.Value), nil))
+			}
+
+			if toFloat(args[0]) == 0 && toFloat(args[1]) < 0 {
+				return newError(object.ZERO_DIVISION, "0 cannot be raised to a negative power")
+			}
+			return floatResult("pow", math.Pow(toFloat(args[0]), toFloat(args[1])))
+		},
+	},
+	{
+		Name:   "sqrt",
+		Params: []string{"x"},
+		Doc:    "Returns the square root of x.",
+		Fn:     floatFunction("sqrt", math.Sqrt, func(x float64) bool { return x >= 0 }),
+	},
+	{
+		Name:   "floor",
+		Params: []string{"x"},
+		Doc:    "Returns the greatest integer less than or equal to x.",
+		Fn:     integerFunction("floor", math.Floor),
+	},
+	{
+		Name:   "ceil",
+		Params: []string{"x"},
+		Doc:    "Returns the least integer greater than or equal to x.",
+		Fn:     integerFunction("ceil", math.Ceil),
+	},
+	{
+		Name:   "round",
+		Params: []string{"x", "digits?"},
+		Doc:    "Rounds x to the nearest integer, halves away from zero. With digits, returns a float rounded to that many decimals.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if len(args) == 1 {
+				return integerFunction("round", math.Round)(env, args...)
+			}
+
+			if err := numberArguments("round", args[:1]); err != nil {
+				return err
+			}
+			digits, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError(object.TYPE_ERROR, "digits passed to `round` must be INTEGER, got %s", args[1].Type())
+			}
+			if digits.Value < 0 || digits.Value > MAX_ROUND_DIGITS {
+				return newError(object.VALUE_ERROR, "digits passed to `round` must be between 0 and %d, got %d", MAX_ROUND_DIGITS, digits.Value)
+			}
+
+			// formatting to the requested precision avoids the representation
+			// error of scaling by a power of ten
+			rounded := strconv.FormatFloat(toFloat(args[0]), 'f', int(digits.Value), 64)
+			value, _ := strconv.ParseFloat(rounded, 64)
+			return &object.Float{Value: value}
+		},
+	},
+	{
+		Name:   "clamp",
+		Params: []string{"x", "low", "high"},
+		Doc:    "Limits x to the range from low to high.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if err := numberArguments("clamp", args); err != nil {
+				return err
+			}
+
+			x, low, high := args[0], args[1], args[2]
+			if evalInfixExpression(">", low, high) == TRUE {
+				return newError(object.VALUE_ERROR, "low passed to `clamp` must not exceed high, got %s > %s", low.Inspect(), high.Inspect())
+			}
+			switch {
+			case evalInfixExpression("<", x, low) == TRUE:
+				return low
+			case evalInfixExpression(">", x, high) == TRUE:
+				return high
+			}
+			return x
+		},
+	},
+	{
+		Name:   "sin",
+		Params: []string{"x"},
+		Doc:    "Returns the sine of x radians.",
+		Fn:     floatFunction("sin", math.Sin, nil),
+	},
+	{
+		Name:   "cos",
+		Params: []string{"x"},
+		Doc:    "Returns the cosine of x radians.",
+		Fn:     floatFunction("cos", math.Cos, nil),
+	},
+	{
+		Name:   "tan",
+		Params: []string{"x"},
+		Doc:    "Returns the tangent of x radians.",
+		Fn:     floatFunction("tan", math.Tan, nil),
+	},
+	{
+		Name:   "asin",
+		Params: []string{"x"},
+		Doc:    "Returns the arcsine of x in radians.",
+		Fn:     floatFunction("asin", math.Asin, func(x float64) bool { return x >= -1 && x <= 1 }),
+	},
+	{
+		Name:   "acos",
+		Params: []string{"x"},
+		Doc:    "Returns the arccosine of x in radians.",
+		Fn:     floatFunction("acos", math.Acos, func(x float64) bool { return x >= -1 && x <= 1 }),
+	},
+	{
+		Name:   "atan",
+		Params: []string{"x"},
+		Doc:    "Returns the arctangent of x in radians.",
+		Fn:     floatFunction("atan", math.Atan, nil),
+	},
+	{
+		Name:   "atan2",
+		Params: []string{"y", "x"},
+		Doc:    "Returns the angle in radians of the point (x, y) from the positive x axis.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if err := numberArguments("atan2", args); err != nil {
+				return err
+			}
+			return &object.Float{Value: math.Atan2(toFloat(args[0]), toFloat(args[1]))}
+		},
+	},
+	{
+		Name:   "exp",
+		Params: []string{"x"},
+		Doc:    "Returns E raised to x.",
+		Fn:     floatFunction("exp", math.Exp, nil),
+	},
+	{
+		Name:   "log",
+		Params: []string{"x"},
+		Doc:    "Returns the natural logarithm of x.",
+		Fn:     floatFunction("log", math.Log, func(x float64) bool { return x > 0 }),
+	},
+	{
+		Name:   "log2",
+		Params: []string{"x"},
+		Doc:    "Returns the base 2 logarithm of x.",
+		Fn:     floatFunction("log2", math.Log2, func(x float64) bool { return x > 0 }),
+	},
+	{
+		Name:   "log10",
+		Params: []string{"x"},
+		Doc:    "Returns the base 10 logarithm of x.",
+		Fn:     floatFunction("log10", math.Log10, func(x float64) bool { return x > 0 }),
+	},
+	{
+		Name:   "hex",
+		Params: []string{"integer"},
+		Doc:    "Writes an integer as a hexadecimal literal, such as 0xff.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			return formatInteger("hex", args[0], 16, "0x")
+		},
+	},
+	{
+		Name:   "oct",
+		Params: []string{"integer"},
+		Doc:    "Writes an integer as a octal literal, such as 0o755.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			return formatInteger("oct", args[0], 8, "0o")
+		},
+	},
+	{
+		Name:   "bin",
+		Params: []string{"integer"},
+		Doc:    "Writes an integer as a binary literal, such as 0b1010.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			return formatInteger("bin", args[0], 2, "0b")
+		},
+	},
+	{
+		Name:   "to_float",
+		Params: []string{"value"},
+		Doc:    "Converts an integer or a numeric string to a float.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInt, *object.Float:
+				return &object.Float{Value: toFloat(arg)}
+			case *object.String:
+				value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+				if err != nil {
+					return newError(object.VALUE_ERROR, "cannot convert %q to FLOAT", arg.Value)
+				}
+				return &object.Float{Value: value}
+			default:
+				return newError(object.TYPE_ERROR, "argument to `to_float` must be STRING, INTEGER or FLOAT, got %s", arg.Type())
+			}
+		},
+	},
+}
+
+func numberTypeError(builtin string, arg object.Object) *object.Error {
+	return newError(object.TYPE_ERROR, "argument to `%s` must be INTEGER or FLOAT, got %s", builtin, arg.Type())
+}
+
+func numberArguments(builtin string, args []object.Object) *object.Error {
+	for _, arg := range args {
+		if !isNumber(arg) {
+			return numberTypeError(builtin, arg)
+		}
+	}
+	return nil
+}
+
+// floatFunction adapts fn to a builtin taking one number. Arguments outside
+// of domain, when given, are rejected with a ValueError.
+func floatFunction(builtin string, fn func(float64) float64, domain func(float64) bool) object.BuiltinFunction {
+	return func(env *object.Environment, args ...object.Object) object.Object {
+		if err := numberArguments(builtin, args); err != nil {
+			return err
+		}
+
+		x := toFloat(args[0])
+		if domain != nil && !domain(x) {
+			return newError(object.VALUE_ERROR, "math domain error: `%s` is undefined for %s", builtin, args[0].Inspect())
+		}
+		return floatResult(builtin, fn(x))
+	}
+}
+
+// integerFunction adapts a rounding function to a builtin that returns an
+// integer. Integer arguments are returned unchanged.
+func integerFunction(builtin string, fn func(float64) float64) object.BuiltinFunction {
+	return func(env *object.Environment, args ...object.Object) object.Object {
+		switch arg := args[0].(type) {
+		case *object.Integer, *object.BigInt:
+			return arg
+		case *object.Float:
+			return floatToInteger(fn(arg.Value))
+		default:
+			return numberTypeError(builtin, arg)
+		}
+	}
+}
+
+// floatToInteger converts an integral float to an Integer, or to a BigInt
+// when it is out of the int64 range.
+func floatToInteger(value float64) object.Object {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return newError(object.VALUE_ERROR, "cannot convert %s to INTEGER", (&object.Float{Value: value}).Inspect())
+	}
+	integer, _ := big.NewFloat(value).Int(nil)
+	return object.IntegerFromBig(integer)
+}
+
+// floatResult reports NaN and infinite results as errors rather than letting
+// them flow into later arithmetic.
+func floatResult(builtin string, value float64) object.Object {
+	switch {
+	case math.IsNaN(value):
+		return newError(object.VALUE_ERROR, "math domain error in `%s`", builtin)
+	case math.IsInf(value, 0):
+		return newError(object.VALUE_ERROR, "math range error: `%s` overflowed", builtin)
+	}
+	return &object.Float{Value: value}
+}
+
+// extremum returns the smallest (sign -1) or largest (sign 1) value.
+func extremum(builtin string, args []object.Object, sign int) object.Object {
+	values := args
+	if len(args) == 1 {
+		if arr, ok := args[0].(*object.Array); ok {
+			values = arr.Elements
+		}
+	}
+	if len(values) == 0 {
+		return newError(object.VALUE_ERROR, "`%s` of an empty sequence", builtin)
+	}
+
+	best := values[0]
+	for _, value := range values[1:] {
+		ordered, err := compareObjects(value, best)
+		if err != nil {
+			return err
+		}
+		if ordered*sign > 0 {
+			best = value
+		}
+	}
+	return best
+}
@@ -0,0 +1,85 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`PI`, "3.141592653589793"},
+		{`E`, "2.718281828459045"},
+		{`PI()`, "ERROR: TypeError: not a function: FLOAT"},
+		{`abs(-3)`, "3"},
+		{`abs(-2.5)`, "2.5"},
+		{`abs("a")`, "ERROR: TypeError: argument to `abs` must be INTEGER or FLOAT, got STRING"},
+		{`min(3, 1.5, 2)`, "1.5"},
+		{`max([3, 9, 2])`, "9"},
+		{`max(["b", "a"])`, "b"},
+		{`min([])`, "ERROR: ValueError: `min` of an empty sequence"},
+		{`min(1, "a")`, "ERROR: TypeError: cannot compare STRING and INTEGER"},
+		{`sum([1, 2, 3])`, "6"},
+		{`sum([1, 2.5])`, "3.5"},
+		{`sum([])`, "0"},
+		{`sum([1, "a"])`, "ERROR: TypeError: argument to `sum` must be INTEGER or FLOAT, got STRING"},
+		{`pow(2, 10)`, "1024"},
+		{`pow(2, 100)`, "1267650600228229401496703205376"},
+		{`pow(10, 10000000)`, "ERROR: LimitError: result of `pow` would exceed 1048576 bits"},
+		{`pow(2, -1)`, "0.5"},
+		{`pow(2.0, 3)`, "8.0"},
+		{`pow(0, -1)`, "ERROR: ZeroDivision: 0 cannot be raised to a negative power"},
+		{`pow(-8, 0.5)`, "ERROR: ValueError: math domain error in `pow`"},
+		{`sqrt(16)`, "4.0"},
+		{`sqrt(-1)`, "ERROR: ValueError: math domain error: `sqrt` is undefined for -1"},
+		{`floor(2.7)`, "2"},
+		{`floor(-2.5)`, "-3"},
+		{`ceil(2.1)`, "3"},
+		{`ceil(4)`, "4"},
+		{`round(2.5)`, "3"},
+		{`round(-2.5)`, "-3"},
+		{`round(2.675, 2)`, "2.67"},
+		{`round(1.23456, 3)`, "1.235"},
+		{`round(1.5, 0)`, "2.0"},
+		{`round(0.1, 17)`, "0.1"},
+		{`round(1234.5678, -2)`, "ERROR: ValueError: digits passed to `round` must be between 0 and 17, got -2"},
+		{`round(1.5, 18)`, "ERROR: ValueError: digits passed to `round` must be between 0 and 17, got 18"},
+		{`round(1.5, 9223372036854775807)`, "ERROR: ValueError: digits passed to `round` must be between 0 and 17, got 9223372036854775807"},
+		{`floor(1e20)`, "100000000000000000000"},
+		{`clamp(5, 0, 3)`, "3"},
+		{`clamp(-1, 0, 3)`, "0"},
+		{`clamp(1.5, 0, 3)`, "1.5"},
+		{`clamp(1, 3, 0)`, "ERROR: ValueError: low passed to `clamp` must not exceed high, got 3 > 0"},
+		{`sin(0)`, "0.0"},
+		{`cos(0)`, "1.0"},
+		{`round(tan(PI / 4), 6)`, "1.0"},
+		{`asin(2)`, "ERROR: ValueError: math domain error: `asin` is undefined for 2"},
+		{`acos(1)`, "0.0"},
+		{`atan(0)`, "0.0"},
+		{`atan2(1, 1) * 4 == PI`, "true"},
+		{`exp(0)`, "1.0"},
+		{`exp(1000)`, "ERROR: ValueError: math range error: `exp` overflowed"},
+		{`log(E)`, "1.0"},
+		{`log(0)`, "ERROR: ValueError: math domain error: `log` is undefined for 0"},
+		{`log2(8)`, "3.0"},
+		{`log10(1000)`, "3.0"},
+		{`hex(255)`, "0xff"},
+		{`oct(-8)`, "-0o10"},
+		{`bin(0b1010)`, "0b1010"},
+		{`hex(pow(2, 64))`, "0x10000000000000000"},
+		{`hex(1.5)`, "ERROR: TypeError: argument to `hex` must be INTEGER, got FLOAT"},
+		{`to_float(2)`, "2.0"},
+		{`to_float("2.5")`, "2.5"},
+		{`to_float("x")`, `ERROR: ValueError: cannot convert "x" to FLOAT`},
+		{`to_int(2.9)`, "2"},
+		{`sort([2, 1.5, 3])`, "[1.5, 2, 3]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		assert.Equal(t, evaluated.Inspect(), tt.expected, tt.input)
+	}
+}
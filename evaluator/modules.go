@@ -0,0 +1,152 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/optimizer"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ModuleExtension is appended to import paths that do not have one.
+const ModuleExtension = ".monkey"
+
+func evalImportStatement(stmt *ast.ImportStatement, env *object.Environment) object.Object {
+	module := importModule(stmt, env)
+	if isError(module) {
+		return module
+	}
+
+	mod := module.(*object.Module)
+	if stmt.Names == nil {
+		env.Set(mod.Name, mod)
+		return nil
+	}
+
+	for _, name := range stmt.Names {
+		value, ok := mod.Exports[name.Value]
+		if !ok {
+			return newError(object.IMPORT_ERROR, "module %s has no export %s", mod.Name, name.Value)
+		}
+		env.Set(name.Value, value)
+	}
+	return nil
+}
+
+func importModule(stmt *ast.ImportStatement, env *object.Environment) object.Object {
+	runtime := env.Runtime()
+	if runtime == nil {
+		return newError(object.IMPORT_ERROR, "cannot import %q without a runtime", stmt.Path.Value)
+	}
+
+	path, ok := resolveModule(stmt.Path.Value, env.File(), runtime)
+	if !ok {
+		return newError(object.IMPORT_ERROR, "module not found: %s", stmt.Path.Value)
+	}
+
+	if module, ok := runtime.Modules[path]; ok {
+		return module
+	}
+
+	for i, importing := range runtime.Importing {
+		if importing == path {
+			cycle := []string{}
+			for _, p := range append(runtime.Importing[i:], path) {
+				cycle = append(cycle, filepath.Base(p))
+			}
+			return newError(object.IMPORT_ERROR, "import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	runtime.Importing = append(runtime.Importing, path)
+	defer func() { runtime.Importing = runtime.Importing[:len(runtime.Importing)-1] }()
+
+	module, err := loadModule(path, runtime)
+	if err != nil {
+		err.Stack = append(err.Stack, object.StackFrame{Function: "<module " + filepath.Base(path) + ">", Position: stmt.Token.Position})
+		return err
+	}
+
+	if runtime.Modules == nil {
+		runtime.Modules = make(map[string]*object.Module)
+	}
+	runtime.Modules[path] = module
+	return module
+}
+
+func loadModule(path string, runtime *object.Runtime) (*object.Module, *object.Error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &object.Error{Kind: object.IMPORT_ERROR, Message: err.Error(), Cause: err}
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, newError(object.IMPORT_ERROR, "cannot parse %s: %s", filepath.Base(path), strings.Join(p.Errors(), "; "))
+	}
+
+	env := object.NewRuntimeEnvironment(runtime)
+	if runtime.Prelude != nil {
+		env = object.NewEnclosedEnvironment(runtime.Prelude)
+	}
+	env.SetFile(path)
+
+	if err := Resolve(program, env); err != nil {
+		return nil, err
+	}
+	if runtime.Optimize {
+		optimizer.Optimize(program)
+	}
+	if err, ok := Eval(program, env).(*object.Error); ok {
+		return nil, err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	module := &object.Module{Name: name, Path: path, Exports: make(map[string]object.Object)}
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok && let.Exported {
+			module.Exports[let.Name.Value], _ = env.Get(let.Name.Value)
+		}
+	}
+	return module, nil
+}
+
+// resolveModule finds the file an import refers to, first relative to the
+// importing file (or the working directory) and then along the module path.
+func resolveModule(name string, importer string, runtime *object.Runtime) (string, bool) {
+	if filepath.Ext(name) == "" {
+		name += ModuleExtension
+	}
+
+	candidates := []string{}
+	if filepath.IsAbs(name) {
+		candidates = append(candidates, name)
+	} else {
+		candidates = append(candidates, filepath.Join(filepath.Dir(importer), name))
+		if !strings.HasPrefix(name, "./") && !strings.HasPrefix(name, "../") {
+			for _, dir := range modulePath(runtime) {
+				candidates = append(candidates, filepath.Join(dir, name))
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			if abs, err := filepath.Abs(candidate); err == nil {
+				return abs, true
+			}
+		}
+	}
+	return "", false
+}
+
+func modulePath(runtime *object.Runtime) []string {
+	if runtime.ModulePath != nil {
+		return runtime.ModulePath
+	}
+	return filepath.SplitList(os.Getenv("MONKEYPATH"))
+}
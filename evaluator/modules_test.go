@@ -0,0 +1,98 @@
+package evaluator_test
+
+import (
+	"bytes"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImports(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "util"; util.double(2)`, 4},
+		{`import "util"; util.quadruple(3)`, 12},
+		{`import { double, quadruple } from "util"; double(quadruple(1))`, 8},
+		{`import "./util.monkey"; util.loads`, 1},
+		{`import "util"; util.secret`, "module util has no export secret"},
+		{`import { secret } from "util"; secret`, "module util has no export secret"},
+		{`import "missing"`, "module not found: missing"},
+		{`import "cycle/a"`, "import cycle: a.monkey -> b.monkey -> a.monkey"},
+		{`import "broken"`, "type mismatch: INTEGER + STRING"},
+		{`import "shared"; shared.greeting`, "module not found: shared"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalModule(tt.input, &object.Runtime{Output: &bytes.Buffer{}, ModulePath: []string{}})
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			assert.Equal(t, errObj.Message, expected)
+		}
+	}
+}
+
+func TestImportSearchPath(t *testing.T) {
+	runtime := &object.Runtime{ModulePath: []string{filepath.Join("testdata", "path")}}
+
+	evaluated := testEvalModule(`import { greeting } from "shared"; greeting`, runtime)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+	assert.Equal(t, str.Value, "hello")
+
+	evaluated = testEvalModule(`import "./shared"`, runtime)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	assert.Equal(t, errObj.Kind, object.IMPORT_ERROR)
+}
+
+func TestModulesLoadOnce(t *testing.T) {
+	var out bytes.Buffer
+	runtime := &object.Runtime{Output: &out, ModulePath: []string{}}
+
+	testEvalModule(`import "util"; import "lib/counter"; import { double } from "util";`, runtime)
+
+	assert.Equal(t, out.String(), "loading counter\n")
+	assert.Len(t, runtime.Modules, 2)
+}
+
+func TestModuleErrorStack(t *testing.T) {
+	evaluated := testEvalModule("\nimport \"broken\"", &object.Runtime{ModulePath: []string{}})
+
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	frames := []string{}
+	for _, frame := range errObj.Stack {
+		frames = append(frames, frame.String())
+	}
+	assert.Equal(t, frames, []string{"at fail (2:24)", "at <module broken.monkey> (2:1)"})
+}
+
+func testEvalModule(input string, runtime *object.Runtime) object.Object {
+	lexer := lexer.New(input)
+	parser := parser.New(lexer)
+	program := parser.ParseProgram()
+	env := object.NewRuntimeEnvironment(runtime)
+	env.SetFile(filepath.Join("testdata", "modules", "main.monkey"))
+	return evaluator.Eval(program, env)
+}
@@ -0,0 +1,194 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// regexFlags lists the flags allowed after a regex literal. They are passed
+// to Go's regexp as an inline (?flags) group.
+const regexFlags = "imsU"
+
+// MAX_CACHED_REGEXES bounds the compile cache so that scripts building
+// patterns dynamically cannot grow it without limit.
+const MAX_CACHED_REGEXES = 1024
+
+// Compiled regexes are immutable, so they are shared across interpreters.
+var (
+	regexCache     = make(map[string]*object.Regex)
+	regexCacheLock sync.Mutex
+)
+
+var regexBuiltins = []*object.Builtin{
+	{
+		Name:   "regex",
+		Params: []string{"pattern", "flags?"},
+		Doc:    "Compiles a regular expression in Go's RE2 syntax. Flags may contain i, m, s and U.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			strs, err := stringArguments("regex", args)
+			if err != nil {
+				return err
+			}
+
+			flags := ""
+			if len(strs) > 1 {
+				flags = strs[1]
+			}
+			return compileRegex(strs[0], flags)
+		},
+	},
+	{
+		Name:   "match",
+		Params: []string{"regex", "string"},
+		Doc:    "Reports whether the string contains a match of regex.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			re, str, err := regexAndStringArguments("match", args)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(re.Regexp.MatchString(str))
+		},
+	},
+	{
+		Name:   "find_all",
+		Params: []string{"regex", "string", "limit?"},
+		Doc:    "Returns every match of regex in the string, or the first limit of them.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			limit := -1
+			if len(args) > 2 {
+				integer, ok := args[2].(*object.Integer)
+				if !ok {
+					return newError(object.TYPE_ERROR, "limit passed to `find_all` must be INTEGER, got %s", args[2].Type())
+				}
+				limit = int(integer.Value)
+			}
+
+			re, str, err := regexAndStringArguments("find_all", args[:2])
+			if err != nil {
+				return err
+			}
+			return stringArray(re.Regexp.FindAllString(str, limit))
+		},
+	},
+	{
+		Name:   "captures",
+		Params: []string{"regex", "string"},
+		Doc:    "Returns the groups of the first match keyed by index and by name, or null when nothing matches.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			re, str, err := regexAndStringArguments("captures", args)
+			if err != nil {
+				return err
+			}
+
+			indexes := re.Regexp.FindStringSubmatchIndex(str)
+			if indexes == nil {
+				return NULL
+			}
+
+			hash := object.NewHash()
+			for i, name := range re.Regexp.SubexpNames() {
+				var group object.Object = NULL
+				if start, end := indexes[2*i], indexes[2*i+1]; start >= 0 {
+					group = &object.String{Value: str[start:end]}
+				}
+				hash.Set(&object.Integer{Value: int64(i)}, group)
+				if name != "" {
+					hash.Set(&object.String{Value: name}, group)
+				}
+			}
+			return hash
+		},
+	},
+}
+
+// compileRegex returns the cached regex for pattern and flags, compiling it
+// on first use.
+func compileRegex(pattern string, flags string) object.Object {
+	key := flags + "/" + pattern
+	regexCacheLock.Lock()
+	defer regexCacheLock.Unlock()
+	if cached, ok := regexCache[key]; ok {
+		return cached
+	}
+
+	source := pattern
+	if flags != "" {
+		for _, flag := range flags {
+			if !strings.ContainsRune(regexFlags, flag) {
+				return newError(object.VALUE_ERROR, "invalid regex flag %q in /%s/%s", flag, pattern, flags)
+			}
+		}
+		source = "(?" + flags + ")" + pattern
+	}
+
+	compiled, err := regexp.Compile(source)
+	if err != nil {
+		return newError(object.VALUE_ERROR, "invalid regex /%s/%s: %s", pattern, flags, err)
+	}
+	re := &object.Regex{Pattern: pattern, Flags: flags, Regexp: compiled}
+	if len(regexCache) < MAX_CACHED_REGEXES {
+		regexCache[key] = re
+	}
+	return re
+}
+
+// regexArgument accepts a regex or a string holding a pattern without flags.
+func regexArgument(builtin string, arg object.Object) (*object.Regex, *object.Error) {
+	switch arg := arg.(type) {
+	case *object.Regex:
+		return arg, nil
+	case *object.String:
+		compiled := compileRegex(arg.Value, "")
+		if err, ok := compiled.(*object.Error); ok {
+			return nil, err
+		}
+		return compiled.(*object.Regex), nil
+	default:
+		return nil, newError(object.TYPE_ERROR, "argument to `%s` must be REGEX or STRING, got %s", builtin, arg.Type())
+	}
+}
+
+func regexAndStringArguments(builtin string, args []object.Object) (*object.Regex, string, *object.Error) {
+	re, err := regexArgument(builtin, args[0])
+	if err != nil {
+		return nil, "", err
+	}
+	strs, err := stringArguments(builtin, args[1:])
+	if err != nil {
+		return nil, "", err
+	}
+	return re, strs[0], nil
+}
+
+// replaceRegex implements replace(string, regex, replacement, count?). The
+// replacement is either a template that may refer to groups as $1 or
+// ${name}, or a function called with each matched text.
+func replaceRegex(env *object.Environment, str string, re *object.Regex, replacement object.Object, count int) object.Object {
+	var out strings.Builder
+	last := 0
+	for _, match := range re.Regexp.FindAllStringSubmatchIndex(str, count) {
+		out.WriteString(str[last:match[0]])
+		last = match[1]
+
+		switch replacement := replacement.(type) {
+		case *object.String:
+			out.Write(re.Regexp.ExpandString(nil, replacement.Value, str, match))
+		case *object.Function, *object.Builtin:
+			result := callFunction(replacement, env, &object.String{Value: str[match[0]:match[1]]})
+			if isError(result) {
+				return result
+			}
+			replaced, ok := result.(*object.String)
+			if !ok {
+				return newError(object.TYPE_ERROR, "replacement function passed to `replace` must return STRING, got %s", result.Type())
+			}
+			out.WriteString(replaced.Value)
+		default:
+			return newError(object.TYPE_ERROR, "replacement passed to `replace` must be STRING or FUNCTION, got %s", replacement.Type())
+		}
+	}
+	out.WriteString(str[last:])
+	return &object.String{Value: out.String()}
+}
@@ -0,0 +1,45 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegexBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`/a+b/i`, "/a+b/i"},
+		{`regex("a+b", "i")`, "/a+b/i"},
+		{`/a/ == /a/`, "true"},
+		{`/(/`, "ERROR: ValueError: invalid regex /(/: error parsing regexp: missing closing ): `(`"},
+		{`/a/x`, `ERROR: ValueError: invalid regex flag 'x' in /a/x`},
+		{`match(/^\d+$/, "123")`, "true"},
+		{`match(/^\d+$/, "12a")`, "false"},
+		{`match(/HELLO/i, "say hello")`, "true"},
+		{`match("l+", "hello")`, "true"},
+		{`match(1, "a")`, "ERROR: TypeError: argument to `match` must be REGEX or STRING, got INTEGER"},
+		{`match(/a/, 1)`, "ERROR: TypeError: argument to `match` must be STRING, got INTEGER"},
+		{`find_all(/\d+/, "a1 b22 c333")`, "[1, 22, 333]"},
+		{`find_all(/\d+/, "a1 b22 c333", 2)`, "[1, 22]"},
+		{`find_all(/x/, "abc")`, "[]"},
+		{`captures(/(?P<key>\w+)=(?P<value>\w*)/, "level=warn")`, "{0: level=warn, 1: level, 2: warn, key: level, value: warn}"},
+		{`captures(/(a)|(b)/, "b")[1]`, "null"},
+		{`captures(/x/, "abc")`, "null"},
+		{`let c = captures(/(?P<user>\w+)@/, "root@host"); c["user"]`, "root"},
+		{`replace("a1b22", /\d+/, "#")`, "a#b#"},
+		{`replace("a1b22", /\d+/, "#", 1)`, "a#b22"},
+		{`replace("john smith", /(?P<first>\w+) (?P<last>\w+)/, "${last}, $first")`, "smith, john"},
+		{`replace("a1b22", /\d+/, fn(m) { to_string(len(m)) })`, "a1b2"},
+		{`replace("a1", /\d/, fn(m) { 1 })`, "ERROR: TypeError: replacement function passed to `replace` must return STRING, got INTEGER"},
+		{`replace("a1", /\d/, fn(m) { m + 1 })`, "ERROR: TypeError: type mismatch: STRING + INTEGER"},
+		{`split("a1b22c", /\d+/)`, "[a, b, c]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		assert.Equal(t, evaluated.Inspect(), tt.expected, tt.input)
+	}
+}
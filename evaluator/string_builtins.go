@@ -0,0 +1,372 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"monkey/object"
+	"strings"
+	"unicode/utf8"
+)
+
+// MAX_STRING_LENGTH bounds the size in bytes of strings built by repeat,
+// pad_left and pad_right, so that a bad count cannot exhaust memory.
+const MAX_STRING_LENGTH = 1 << 26
+
+var stringBuiltins = []*object.Builtin{
+	{
+		Name:   "split",
+		Params: []string{"string", "separator?"},
+		Doc:    "Splits a string around separator, which may be a regex, or around runs of whitespace when it is omitted.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if len(args) > 1 {
+				if re, ok := args[1].(*object.Regex); ok {
+					strs, err := stringArguments("split", args[:1])
+					if err != nil {
+						return err
+					}
+					return stringArray(re.Regexp.Split(strs[0], -1))
+				}
+			}
+
+			strs, err := stringArguments("split", args)
+			if err != nil {
+				return err
+			}
+
+			var parts []string
+			if len(strs) == 1 {
+				parts = strings.Fields(strs[0])
+			} else {
+				parts = strings.Split(strs[0], strs[1])
+			}
+			return stringArray(parts)
+		},
+	},
+	{
+		Name:   "join",
+		Params: []string{"array", "separator?"},
+		Doc:    "Concatenates an array of strings, placing separator between them.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			arr, err := arrayArgument("join", args[0])
+			if err != nil {
+				return err
+			}
+
+			separator := ""
+			if len(args) > 1 {
+				strs, err := stringArguments("join", args[1:])
+				if err != nil {
+					return err
+				}
+				separator = strs[0]
+			}
+
+			parts := make([]string, len(arr.Elements))
+			for i, element := range arr.Elements {
+				str, ok := element.(*object.String)
+				if !ok {
+					return newError(object.TYPE_ERROR, "element %d passed to `join` must be STRING, got %s", i, element.Type())
+				}
+				parts[i] = str.Value
+			}
+			return &object.String{Value: strings.Join(parts, separator)}
+		},
+	},
+	{
+		Name:   "trim",
+		Params: []string{"string", "cutset?"},
+		Doc:    "Removes leading and trailing whitespace, or the characters in cutset.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			strs, err := stringArguments("trim", args)
+			if err != nil {
+				return err
+			}
+
+			if len(strs) == 1 {
+				return &object.String{Value: strings.TrimSpace(strs[0])}
+			}
+			return &object.String{Value: strings.Trim(strs[0], strs[1])}
+		},
+	},
+	{
+		Name:   "upper",
+		Params: []string{"string"},
+		Doc:    "Returns the string with every letter mapped to upper case.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			strs, err := stringArguments("upper", args)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: strings.ToUpper(strs[0])}
+		},
+	},
+	{
+		Name:   "lower",
+		Params: []string{"string"},
+		Doc:    "Returns the string with every letter mapped to lower case.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			strs, err := stringArguments("lower", args)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: strings.ToLower(strs[0])}
+		},
+	},
+	{
+		Name:   "contains",
+		Params: []string{"collection", "value"},
+		Doc:    "Reports whether a string contains a substring or an array contains an element.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if arr, ok := args[0].(*object.Array); ok {
+				return nativeBoolToBooleanObject(containsObject(arr.Elements, args[1]))
+			}
+
+			strs, err := stringArguments("contains", args)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(strings.Contains(strs[0], strs[1]))
+		},
+	},
+	{
+		Name:   "starts_with",
+		Params: []string{"string", "prefix"},
+		Doc:    "Reports whether the string begins with prefix.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			strs, err := stringArguments("starts_with", args)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(strings.HasPrefix(strs[0], strs[1]))
+		},
+	},
+	{
+		Name:   "ends_with",
+		Params: []string{"string", "suffix"},
+		Doc:    "Reports whether the string ends with suffix.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			strs, err := stringArguments("ends_with", args)
+			if err != nil {
+				return err
+			}
+			return nativeBoolToBooleanObject(strings.HasSuffix(strs[0], strs[1]))
+		},
+	},
+	{
+		Name:   "replace",
+		Params: []string{"string", "old", "new", "count?"},
+		Doc:    "Replaces the first count occurrences of old with new, or all of them when count is omitted. When old is a regex, new may refer to groups as $1 or ${name}, or be a function called with each match.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			count := -1
+			if len(args) > 3 {
+				integer, ok := args[3].(*object.Integer)
+				if !ok {
+					return newError(object.TYPE_ERROR, "count passed to `replace` must be INTEGER, got %s", args[3].Type())
+				}
+				count = int(integer.Value)
+				args = args[:3]
+			}
+
+			if re, ok := args[1].(*object.Regex); ok {
+				strs, err := stringArguments("replace", args[:1])
+				if err != nil {
+					return err
+				}
+				return replaceRegex(env, strs[0], re, args[2], count)
+			}
+
+			strs, err := stringArguments("replace", args)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: strings.Replace(strs[0], strs[1], strs[2], count)}
+		},
+	},
+	{
+		Name:   "repeat",
+		Params: []string{"string", "count"},
+		Doc:    "Returns count copies of the string concatenated.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			strs, err := stringArguments("repeat", args[:1])
+			if err != nil {
+				return err
+			}
+			count, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError(object.TYPE_ERROR, "count passed to `repeat` must be INTEGER, got %s", args[1].Type())
+			}
+			if count.Value < 0 {
+				return newError(object.VALUE_ERROR, "count passed to `repeat` must not be negative, got %d", count.Value)
+			}
+			if len(strs[0]) > 0 && count.Value > MAX_STRING_LENGTH/int64(len(strs[0])) {
+				return newError(object.LIMIT_ERROR, "result of `repeat` would exceed %d bytes", MAX_STRING_LENGTH)
+			}
+			return &object.String{Value: strings.Repeat(strs[0], int(count.Value))}
+		},
+	},
+	{
+		Name:   "pad_left",
+		Params: []string{"string", "width", "pad?"},
+		Doc:    "Prepends pad (a space by default) until the string is width characters long.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			return padString("pad_left", args, true)
+		},
+	},
+	{
+		Name:   "pad_right",
+		Params: []string{"string", "width", "pad?"},
+		Doc:    "Appends pad (a space by default) until the string is width characters long.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			return padString("pad_right", args, false)
+		},
+	},
+	{
+		Name:   "chars",
+		Params: []string{"string"},
+		Doc:    "Returns the characters of the string as an array of strings.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			strs, err := stringArguments("chars", args)
+			if err != nil {
+				return err
+			}
+
+			chars := []string{}
+			for _, char := range strs[0] {
+				chars = append(chars, string(char))
+			}
+			return stringArray(chars)
+		},
+	},
+	{
+		Name:   "to_int",
+		Params: []string{"value", "base?"},
+		Doc:    "Parses a string as an integer in base (10 by default). Base 0 accepts the 0x, 0o and 0b prefixes and underscores of integer literals. Floats are truncated and integers returned unchanged.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			base := 10
+			if len(args) > 1 {
+				var err *object.Error
+				if base, err = baseArgument("to_int", args[1], true); err != nil {
+					return err
+				}
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInt:
+				return arg
+			case *object.Float:
+				return floatToInteger(math.Trunc(arg.Value))
+			case *object.String:
+				value, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), base)
+				if !ok {
+					return newError(object.VALUE_ERROR, "cannot convert %q to INTEGER", arg.Value)
+				}
+				return object.IntegerFromBig(value)
+			default:
+				return newError(object.TYPE_ERROR, "argument to `to_int` must be STRING, INTEGER or FLOAT, got %s", args[0].Type())
+			}
+		},
+	},
+	{
+		Name:   "to_string",
+		Params: []string{"value", "base?"},
+		Doc:    "Returns the printed representation of a value, writing integers in base (10 by default). Strings are returned unchanged.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			if len(args) > 1 {
+				base, err := baseArgument("to_string", args[1], false)
+				if err != nil {
+					return err
+				}
+				return formatInteger("to_string", args[0], base, "")
+			}
+
+			if str, ok := args[0].(*object.String); ok {
+				return str
+			}
+			return &object.String{Value: args[0].Inspect()}
+		},
+	},
+}
+
+// baseArgument unwraps a number base between 2 and 36, or 0 when allowAuto
+// is set.
+func baseArgument(builtin string, arg object.Object, allowAuto bool) (int, *object.Error) {
+	integer, ok := arg.(*object.Integer)
+	if !ok {
+		return 0, newError(object.TYPE_ERROR, "base passed to `%s` must be INTEGER, got %s", builtin, arg.Type())
+	}
+	if allowAuto && integer.Value == 0 {
+		return 0, nil
+	}
+	if integer.Value < 2 || integer.Value > 36 {
+		return 0, newError(object.VALUE_ERROR, "base passed to `%s` must be between 2 and 36, got %d", builtin, integer.Value)
+	}
+	return int(integer.Value), nil
+}
+
+// formatInteger writes an integer in base, with prefix between the sign and
+// the digits.
+func formatInteger(builtin string, arg object.Object, base int, prefix string) object.Object {
+	if !isInteger(arg) {
+		return newError(object.TYPE_ERROR, "argument to `%s` must be INTEGER, got %s", builtin, arg.Type())
+	}
+
+	digits := toBigInt(arg).Text(base)
+	if strings.HasPrefix(digits, "-") {
+		return &object.String{Value: "-" + prefix + digits[1:]}
+	}
+	return &object.String{Value: prefix + digits}
+}
+
+// stringArguments unwraps args, which must all be strings.
+func stringArguments(builtin string, args []object.Object) ([]string, *object.Error) {
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		str, ok := arg.(*object.String)
+		if !ok {
+			return nil, newError(object.TYPE_ERROR, "argument to `%s` must be STRING, got %s", builtin, arg.Type())
+		}
+		strs[i] = str.Value
+	}
+	return strs, nil
+}
+
+func stringArray(strs []string) *object.Array {
+	elements := make([]object.Object, len(strs))
+	for i, str := range strs {
+		elements[i] = &object.String{Value: str}
+	}
+	return &object.Array{Elements: elements}
+}
+
+func padString(builtin string, args []object.Object, left bool) object.Object {
+	width, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError(object.TYPE_ERROR, "width passed to `%s` must be INTEGER, got %s", builtin, args[1].Type())
+	}
+
+	strs, err := stringArguments(builtin, append([]object.Object{args[0]}, args[2:]...))
+	if err != nil {
+		return err
+	}
+	str, pad := strs[0], " "
+	if len(strs) > 1 {
+		pad = strs[1]
+	}
+	if pad == "" {
+		return newError(object.VALUE_ERROR, "pad passed to `%s` must not be empty", builtin)
+	}
+
+	missing := width.Value - int64(utf8.RuneCountInString(str))
+	if missing <= 0 {
+		return &object.String{Value: str}
+	}
+	if missing > MAX_STRING_LENGTH/int64(len(pad)) {
+		return newError(object.LIMIT_ERROR, "result of `%s` would exceed %d bytes", builtin, MAX_STRING_LENGTH)
+	}
+
+	padding := []rune(strings.Repeat(pad, int(missing)))[:missing]
+	if left {
+		return &object.String{Value: string(padding) + str}
+	}
+	return &object.String{Value: str + string(padding)}
+}
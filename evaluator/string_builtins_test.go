@@ -0,0 +1,77 @@
+package evaluator_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len("héllo")`, "5"},
+		{`split("a,b,,c", ",")`, "[a, b, , c]"},
+		{`split("  a b	c ")`, "[a, b, c]"},
+		{`split("añb", "")`, "[a, ñ, b]"},
+		{`split(1, ",")`, "ERROR: TypeError: argument to `split` must be STRING, got INTEGER"},
+		{`join(["a", "b", "c"], ", ")`, "a, b, c"},
+		{`join(["a", "b"])`, "ab"},
+		{`join([])`, ""},
+		{`join(["a", 1], "-")`, "ERROR: TypeError: element 1 passed to `join` must be STRING, got INTEGER"},
+		{`trim("  a b  ")`, "a b"},
+		{`trim("xxaxx", "x")`, "a"},
+		{`upper("élan")`, "ÉLAN"},
+		{`lower("ÀB")`, "àb"},
+		{`contains("hello", "ell")`, "true"},
+		{`contains("hello", "x")`, "false"},
+		{`contains([1, "a"], "a")`, "true"},
+		{`contains([1, "a"], 2)`, "false"},
+		{`contains(1, 2)`, "ERROR: TypeError: argument to `contains` must be STRING, got INTEGER"},
+		{`starts_with("hello", "he")`, "true"},
+		{`starts_with("hello", "lo")`, "false"},
+		{`ends_with("hello", "lo")`, "true"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`replace("a-b-c", "-", "+", 1)`, "a+b-c"},
+		{`replace("a-b-c", "-", 1)`, "ERROR: TypeError: argument to `replace` must be STRING, got INTEGER"},
+		{`index_of("héllo", "l")`, "2"},
+		{`index_of("hello", "h")`, "0"},
+		{`index_of("hello", "x")`, "-1"},
+		{`index_of("hello", 1)`, "ERROR: TypeError: argument to `index_of` must be STRING, got INTEGER"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", 0)`, ""},
+		{`repeat("ab", -1)`, "ERROR: ValueError: count passed to `repeat` must not be negative, got -1"},
+		{`repeat("ab", 9223372036854775807)`, "ERROR: LimitError: result of `repeat` would exceed 67108864 bytes"},
+		{`repeat("", 9223372036854775807)`, ""},
+		{`pad_left("7", 3, "0")`, "007"},
+		{`pad_left("é", 3)`, "  é"},
+		{`pad_right("ab", 5, "xy")`, "abxyx"},
+		{`pad_right("abc", 2)`, "abc"},
+		{`pad_left("a", 9223372036854775807)`, "ERROR: LimitError: result of `pad_left` would exceed 67108864 bytes"},
+		{`pad_right("a", 9223372036854775807, "xy")`, "ERROR: LimitError: result of `pad_right` would exceed 67108864 bytes"},
+		{`pad_left("a", 3, "")`, "ERROR: ValueError: pad passed to `pad_left` must not be empty"},
+		{`chars("añ")`, "[a, ñ]"},
+		{`chars("")`, "[]"},
+		{`to_int("42")`, "42"},
+		{`to_int(" -7 ")`, "-7"},
+		{`to_int("ff", 16)`, "255"},
+		{`to_int(3)`, "3"},
+		{`to_int("4x")`, `ERROR: ValueError: cannot convert "4x" to INTEGER`},
+		{`to_int("1", 1)`, "ERROR: ValueError: base passed to `to_int` must be between 2 and 36, got 1"},
+		{`to_int(true)`, "ERROR: TypeError: argument to `to_int` must be STRING, INTEGER or FLOAT, got BOOLEAN"},
+		{`to_int("0x_ff", 0)`, "255"},
+		{`to_int("0b102", 0)`, `ERROR: ValueError: cannot convert "0b102" to INTEGER`},
+		{`to_string(42)`, "42"},
+		{`to_string(255, 2)`, "11111111"},
+		{`to_string(-255, 16)`, "-ff"},
+		{`to_string("a", 16)`, "ERROR: TypeError: argument to `to_string` must be INTEGER, got STRING"},
+		{`to_string([1, "a"])`, "[1, a]"},
+		{`to_string("a")`, "a"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		assert.Equal(t, evaluated.Inspect(), tt.expected, tt.input)
+	}
+}
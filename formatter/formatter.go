@@ -0,0 +1,406 @@
+// Package formatter prints Monkey programs in a canonical layout.
+//
+// Statements are indented by two spaces and terminated by semicolons, except
+// for an expression ending a block. Parentheses are only written where the
+// parser's precedences require them. Calls, arrays and hashes that do not fit
+// in MAX_WIDTH columns are broken one element per line, and blocks written on
+// one line in the source stay on one line while they fit. Comments are kept
+// in place, and formatting formatted source leaves it unchanged.
+package formatter
+
+import (
+	"math"
+	"monkey/ast"
+	"monkey/parser"
+	"monkey/token"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	INDENT    = "  "
+	MAX_WIDTH = 80
+)
+
+// Format returns the canonical source of program, ending with a newline
+// unless it is empty.
+func Format(program *ast.Program) string {
+	p := &printer{comments: program.Comments, used: make([]bool, len(program.Comments))}
+	end := token.Position{Line: math.MaxInt32}
+	return p.statements(program.Statements, 0, token.Position{}, end, true)
+}
+
+type printer struct {
+	comments []*ast.Comment
+	used     []bool // comments already written
+}
+
+// statements writes a statement list, one per line, together with the
+// comments between from and to.
+func (p *printer) statements(stmts []ast.Statement, indent int, from token.Position, to token.Position, topLevel bool) string {
+	var out strings.Builder
+	prefix := strings.Repeat(INDENT, indent)
+
+	// lastLine is the source line of the last statement or comment written,
+	// used to keep a single blank line where the source had any
+	lastLine := 0
+	separate := func(line int) {
+		if lastLine > 0 && line > lastLine+1 {
+			out.WriteString("\n")
+		}
+	}
+	writeComments := func(from token.Position, to token.Position) {
+		for i, comment := range p.comments {
+			if p.used[i] || before(comment.Token.Position, from) || !before(comment.Token.Position, to) {
+				continue
+			}
+			p.used[i] = true
+			separate(comment.Token.Line)
+			out.WriteString(prefix + comment.Token.Literal + "\n")
+			lastLine = comment.Token.Line
+		}
+	}
+
+	for i, stmt := range stmts {
+		span := ast.SpanOf(stmt)
+		writeComments(from, span.Start)
+		separate(span.Start.Line)
+
+		out.WriteString(prefix + p.statement(stmt, indent))
+		if needsSemicolon(stmts, i, topLevel) {
+			out.WriteString(";")
+		}
+		for j, comment := range p.comments {
+			position := comment.Token.Position
+			if !p.used[j] && position.Line == span.End.Line && !before(position, span.End) && before(position, to) {
+				p.used[j] = true
+				out.WriteString(" " + comment.Token.Literal)
+			}
+		}
+		out.WriteString("\n")
+		lastLine = span.End.Line
+
+		// comments inside the statement but outside of its blocks cannot be
+		// kept in place, so they follow it
+		writeComments(span.Start, span.End)
+		if lastLine < span.End.Line {
+			lastLine = span.End.Line
+		}
+		from = span.End
+	}
+	writeComments(from, to)
+
+	return out.String()
+}
+
+// needsSemicolon reports whether stmts[i] is terminated by a semicolon. It is
+// left out after an expression ending a block and after if and try
+// statements, unless the next statement could be read as continuing them.
+func needsSemicolon(stmts []ast.Statement, i int, topLevel bool) bool {
+	stmt, ok := stmts[i].(*ast.ExpressionStatement)
+	if !ok {
+		return true
+	}
+	switch stmt.Expression.(type) {
+	case *ast.IfExpression, *ast.TryExpression:
+		return i < len(stmts)-1 && strings.ContainsAny(stmts[i+1].String()[:1], "([-+*/<>=.")
+	}
+	return topLevel || i < len(stmts)-1
+}
+
+func (p *printer) statement(stmt ast.Statement, indent int) string {
+	column := len(INDENT) * indent
+
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		head := "let " + stmt.Name.Value + " = "
+		if stmt.Type != nil {
+			head = "let " + stmt.Name.Value + ": " + stmt.Type.String() + " = "
+		}
+		if stmt.Exported {
+			head = "export " + head
+		}
+		return head + p.expression(stmt.Value, indent, column+len(head))
+	case *ast.ReturnStatement:
+		if stmt.ReturnValue == nil {
+			return "return"
+		}
+		return "return " + p.expression(stmt.ReturnValue, indent, column+len("return "))
+	case *ast.ImportStatement:
+		path := strconv.Quote(stmt.Path.Value)
+		if stmt.Names == nil {
+			return "import " + path
+		}
+		names := make([]string, len(stmt.Names))
+		for i, name := range stmt.Names {
+			names[i] = name.Value
+		}
+		return "import { " + strings.Join(names, ", ") + " } from " + path
+	case *ast.ExpressionStatement:
+		return p.expression(stmt.Expression, indent, column)
+	default:
+		return stmt.String()
+	}
+}
+
+// expression prints expr starting at column. Lines after the first carry
+// their own indentation.
+func (p *printer) expression(expr ast.Expression, indent int, column int) string {
+	switch expr := expr.(type) {
+	case *ast.Identifier:
+		return expr.Value
+	case *ast.IntegerLiteral:
+		if expr.Token.Literal != "" {
+			return expr.Token.Literal
+		}
+		if expr.Big != nil {
+			return expr.Big.String()
+		}
+		return strconv.FormatInt(expr.Value, 10)
+	case *ast.FloatLiteral:
+		if expr.Token.Literal != "" {
+			return expr.Token.Literal
+		}
+		return strconv.FormatFloat(expr.Value, 'g', -1, 64)
+	case *ast.BooleanLiteral:
+		return strconv.FormatBool(expr.Value)
+	case *ast.StringLiteral:
+		return "\"" + expr.Value + "\""
+	case *ast.RegexLiteral:
+		return "/" + expr.Pattern + "/" + expr.Flags
+	case *ast.PrefixExpression:
+		// -(-x) rather than --x, which reads as a decrement
+		right, ok := expr.Right.(*ast.PrefixExpression)
+		nested := ok && right.Operator == "-" && expr.Operator == "-"
+		return expr.Operator + p.operand(expr.Right, parser.PREFIX, nested, indent, column+len(expr.Operator))
+	case *ast.InfixExpression:
+		precedence := parser.Precedence(token.TokenType(expr.Operator))
+		left := p.operand(expr.Left, precedence, false, indent, column)
+		head := left + " " + expr.Operator + " "
+		return head + p.operand(expr.Right, precedence, true, indent, advance(column, head))
+	case *ast.CallExpression:
+		callee := p.operand(expr.Function, parser.CALL, false, indent, column)
+		return callee + p.list("(", ")", expr.Arguments, indent, advance(column, callee))
+	case *ast.IndexExpression:
+		left := p.operand(expr.Left, parser.CALL, false, indent, column) + "["
+		return left + p.expression(expr.Index, indent, advance(column, left)) + "]"
+	case *ast.MemberExpression:
+		return p.operand(expr.Object, parser.CALL, false, indent, column) + "." + expr.Property.Value
+	case *ast.ArrayLiteral:
+		return p.list("[", "]", expr.Elements, indent, column)
+	case *ast.HashLiteral:
+		return p.hash(expr, indent, column)
+	case *ast.FunctionLiteral:
+		params := make([]string, len(expr.Parameters))
+		for i, param := range expr.Parameters {
+			params[i] = param.Value
+			if i < len(expr.ParameterTypes) && expr.ParameterTypes[i] != nil {
+				params[i] += ": " + expr.ParameterTypes[i].String()
+			}
+		}
+		head := "fn(" + strings.Join(params, ", ") + ") "
+		if expr.ReturnType != nil {
+			head = "fn(" + strings.Join(params, ", ") + "): " + expr.ReturnType.String() + " "
+		}
+		return head + p.block(expr.Body, indent, column+width(head))
+	case *ast.IfExpression:
+		head := "if (" + p.expression(expr.Condition, indent, column+len("if (")) + ") "
+		out := head + p.block(expr.ThenBranch, indent, advance(column, head))
+		if expr.ElseBranch != nil {
+			out += " else "
+			out += p.block(expr.ElseBranch, indent, advance(column, out))
+		}
+		return out
+	case *ast.TryExpression:
+		out := "try " + p.block(expr.Block, indent, column+len("try "))
+		if expr.CatchBlock != nil {
+			out += " catch "
+			if expr.CatchParameter != nil {
+				out += "(" + expr.CatchParameter.Value + ") "
+			}
+			out += p.block(expr.CatchBlock, indent, advance(column, out))
+		}
+		if expr.FinallyBlock != nil {
+			out += " finally "
+			out += p.block(expr.FinallyBlock, indent, advance(column, out))
+		}
+		return out
+	default:
+		return expr.String()
+	}
+}
+
+// operand prints an operand of an operator with the given precedence,
+// adding parentheses when the operand binds less tightly. Operators are left
+// associative, so a right operand of equal precedence needs them too.
+func (p *printer) operand(expr ast.Expression, precedence int, right bool, indent int, column int) string {
+	own := parser.INDEX + 1
+	switch expr := expr.(type) {
+	case *ast.InfixExpression:
+		own = parser.Precedence(token.TokenType(expr.Operator))
+	case *ast.PrefixExpression:
+		own = parser.PREFIX
+	}
+
+	if own < precedence || (right && own == precedence) {
+		return "(" + p.expression(expr, indent, column+1) + ")"
+	}
+	return p.expression(expr, indent, column)
+}
+
+// list prints call arguments or array elements. They stay on one line when
+// they fit; a function spanning several lines may follow the others on the
+// first line when it is the last element. Otherwise each goes on its own
+// line.
+func (p *printer) list(open string, close string, elements []ast.Expression, indent int, column int) string {
+	if len(elements) == 0 {
+		return open + close
+	}
+
+	saved := p.save()
+	out := open
+	for i, element := range elements {
+		if i > 0 {
+			out += ", "
+		}
+		out += p.expression(element, indent, advance(column, out))
+	}
+	out += close
+
+	firstLine, _, multiline := strings.Cut(out, "\n")
+	if column+width(firstLine) <= MAX_WIDTH && (!multiline || p.canHug(elements, indent, column, open)) {
+		return out
+	}
+	p.restore(saved)
+
+	return p.broken(open, close, len(elements), indent, func(i int, column int) string {
+		return p.expression(elements[i], indent+1, column)
+	})
+}
+
+// canHug reports whether the last element is a function and all the others
+// print on one line.
+func (p *printer) canHug(elements []ast.Expression, indent int, column int, open string) bool {
+	if _, ok := elements[len(elements)-1].(*ast.FunctionLiteral); !ok {
+		return false
+	}
+
+	saved := p.save()
+	defer p.restore(saved)
+
+	out := open
+	for _, element := range elements[:len(elements)-1] {
+		out += p.expression(element, indent, advance(column, out)) + ", "
+		if strings.Contains(out, "\n") {
+			return false
+		}
+	}
+	return true
+}
+
+// hash prints a hash literal on one line when it fits and was written on one
+// line, and one pair per line otherwise.
+func (p *printer) hash(hash *ast.HashLiteral, indent int, column int) string {
+	if len(hash.Pairs) == 0 {
+		return "{}"
+	}
+
+	pair := func(i int, indent int, column int) string {
+		key := p.expression(hash.Pairs[i].Key, indent, column) + ": "
+		return key + p.expression(hash.Pairs[i].Value, indent, advance(column, key))
+	}
+
+	if hash.Token.Line == hash.Rbrace.Line {
+		saved := p.save()
+		out := "{"
+		for i := range hash.Pairs {
+			if i > 0 {
+				out += ", "
+			}
+			out += pair(i, indent, advance(column, out))
+		}
+		out += "}"
+		if !strings.Contains(out, "\n") && column+width(out) <= MAX_WIDTH {
+			return out
+		}
+		p.restore(saved)
+	}
+
+	return p.broken("{", "}", len(hash.Pairs), indent, func(i int, column int) string {
+		return pair(i, indent+1, column)
+	})
+}
+
+// broken prints count elements one per line between open and close.
+func (p *printer) broken(open string, close string, count int, indent int, element func(i int, column int) string) string {
+	prefix := strings.Repeat(INDENT, indent+1)
+	var out strings.Builder
+	out.WriteString(open + "\n")
+	for i := 0; i < count; i++ {
+		out.WriteString(prefix + element(i, len(prefix)))
+		if i < count-1 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString(strings.Repeat(INDENT, indent) + close)
+	return out.String()
+}
+
+// block prints a block. A block holding a single expression stays on one
+// line if it was written that way and still fits.
+func (p *printer) block(block *ast.BlockStatement, indent int, column int) string {
+	if p.hasComments(block.Token.Position, block.Rbrace) {
+		return "{\n" + p.statements(block.Statements, indent+1, block.Token.Position, block.Rbrace, false) + strings.Repeat(INDENT, indent) + "}"
+	}
+	if len(block.Statements) == 0 {
+		return "{}"
+	}
+
+	if stmt, ok := block.Statements[0].(*ast.ExpressionStatement); ok && len(block.Statements) == 1 && block.Token.Line == block.Rbrace.Line {
+		saved := p.save()
+		out := "{ " + p.expression(stmt.Expression, indent+1, column+2) + " }"
+		if !strings.Contains(out, "\n") && column+width(out) <= MAX_WIDTH {
+			return out
+		}
+		p.restore(saved)
+	}
+
+	return "{\n" + p.statements(block.Statements, indent+1, block.Token.Position, block.Rbrace, false) + strings.Repeat(INDENT, indent) + "}"
+}
+
+func (p *printer) hasComments(from token.Position, to token.Position) bool {
+	for i, comment := range p.comments {
+		if !p.used[i] && !before(comment.Token.Position, from) && before(comment.Token.Position, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// save and restore let the printer try a layout and back out of it without
+// losing the comments it wrote.
+func (p *printer) save() []bool {
+	return append([]bool{}, p.used...)
+}
+
+func (p *printer) restore(saved []bool) {
+	copy(p.used, saved)
+}
+
+func before(a token.Position, b token.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+}
+
+// advance returns the column reached after printing text from column.
+func advance(column int, text string) int {
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		return width(text[i+1:])
+	}
+	return column + width(text)
+}
+
+func width(text string) int {
+	return utf8.RuneCountInString(text)
+}
@@ -0,0 +1,79 @@
+package formatter_test
+
+import (
+	"monkey/formatter"
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func format(t *testing.T, input string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %v", p.Errors())
+	}
+	return formatter.Format(program)
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"let   x=1", "let x = 1;\n"},
+		{"(1 + 2) * 3", "(1 + 2) * 3;\n"},
+		{"((1 * 2)) + 3", "1 * 2 + 3;\n"},
+		{"(1 - 2) - 3; 1 - (2 - 3)", "1 - 2 - 3;\n1 - (2 - 3);\n"},
+		{"-(1 + 2); !(a == b); -(-x)", "-(1 + 2);\n!(a == b);\n-(-x);\n"},
+		{"(-f)(x); (a + b)[0]; (a.b).c", "(-f)(x);\n(a + b)[0];\na.b.c;\n"},
+		{"let f = fn(a,b){return a+b;}", "let f = fn(a, b) {\n  return a + b;\n};\n"},
+		{"let f = fn(a,b){a+b}", "let f = fn(a, b) { a + b };\n"},
+		{"let f = fn(a) {\na\n}", "let f = fn(a) {\n  a\n};\n"},
+		{"let empty = fn() {   }", "let empty = fn() {};\n"},
+		{"if (x) { 1 } else { 2 }\nlet y = 0", "if (x) { 1 } else { 2 }\nlet y = 0;\n"},
+		{"let h = {\"a\": 1, \"b\": [1,2]}", "let h = {\"a\": 1, \"b\": [1, 2]};\n"},
+		{"let h = {\"a\": 1,\n\"b\": 2}", "let h = {\n  \"a\": 1,\n  \"b\": 2\n};\n"},
+		{"import {a,b} from \"lib\"; export let z = 0x_ff", "import { a, b } from \"lib\";\nexport let z = 0x_ff;\n"},
+		{
+			"let x = 1;\n\n\n\nlet y = 2;",
+			"let x = 1;\n\nlet y = 2;\n",
+		},
+		{
+			"// header\nlet x = 1;   // trailing\nlet f = fn() {\n  // inside\n  x\n};\n// end",
+			"// header\nlet x = 1; // trailing\nlet f = fn() {\n  // inside\n  x\n};\n// end\n",
+		},
+		{
+			"let result = some_function_name(first_argument_value, second_argument_value, third);",
+			"let result = some_function_name(\n  first_argument_value,\n  second_argument_value,\n  third\n);\n",
+		},
+		{
+			"let doubled = map([1, 2, 3], fn(x) { let y = x * 2; y + some_long_identifier + another_one });",
+			"let doubled = map([1, 2, 3], fn(x) {\n  let y = x * 2;\n  y + some_long_identifier + another_one\n});\n",
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, format(t, tt.input), tt.expected, tt.input)
+	}
+}
+
+func TestFormatIsIdempotent(t *testing.T) {
+	files, err := filepath.Glob("../prelude/*.monkey")
+	assert.NoError(t, err)
+	testdata, err := filepath.Glob("../prelude/testdata/*.monkey")
+	assert.NoError(t, err)
+
+	for _, file := range append(files, testdata...) {
+		source, err := os.ReadFile(file)
+		assert.NoError(t, err)
+
+		once := format(t, string(source))
+		assert.Equal(t, format(t, once), once, file)
+	}
+}
@@ -0,0 +1,234 @@
+// Package monkey embeds the Monkey interpreter in Go programs.
+//
+//	interp := monkey.New(monkey.WithOutput(&buf))
+//	interp.Set("limit", 10)
+//	result, err := interp.Run(ctx, "limit * 2")
+package monkey
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/optimizer"
+	"monkey/parser"
+	"monkey/prelude"
+	"os"
+	"strings"
+)
+
+// Interpreter evaluates Monkey programs against a persistent root
+// environment. It is not safe for concurrent use.
+type Interpreter struct {
+	runtime   *object.Runtime
+	env       *object.Environment
+	noPrelude bool
+}
+
+type Option func(*Interpreter)
+
+// WithOutput redirects the output of print, which defaults to stdout.
+func WithOutput(out io.Writer) Option {
+	return func(interp *Interpreter) { interp.runtime.Output = out }
+}
+
+// WithMaxDepth limits how deeply function calls may nest.
+func WithMaxDepth(depth int) Option {
+	return func(interp *Interpreter) { interp.runtime.MaxDepth = depth }
+}
+
+// WithMaxSteps limits how many statements a single Run or Call may evaluate.
+func WithMaxSteps(steps int) Option {
+	return func(interp *Interpreter) { interp.runtime.MaxSteps = steps }
+}
+
+// WithBuiltins replaces the standard builtins with registry. A nil registry
+// keeps the standard builtins.
+func WithBuiltins(registry *object.Builtins) Option {
+	return func(interp *Interpreter) {
+		if registry == nil {
+			registry = evaluator.NewBuiltins()
+		}
+		interp.runtime.Builtins = registry
+	}
+}
+
+// WithoutBuiltins removes the named builtins or namespaces, for example to
+// keep sandboxed scripts from writing output with print. The registry is
+// copied first, so a registry shared through WithBuiltins is left intact.
+func WithoutBuiltins(names ...string) Option {
+	return func(interp *Interpreter) {
+		builtins := interp.runtime.Builtins.Clone()
+		builtins.Remove(names...)
+		interp.runtime.Builtins = builtins
+	}
+}
+
+// WithModulePath sets the directories searched for imported modules,
+// overriding MONKEYPATH.
+func WithModulePath(dirs ...string) Option {
+	return func(interp *Interpreter) { interp.runtime.ModulePath = dirs }
+}
+
+// WithoutOptimizer evaluates programs exactly as written, without folding
+// constant expressions first.
+func WithoutOptimizer() Option {
+	return func(interp *Interpreter) { interp.runtime.Optimize = false }
+}
+
+// WithDebugger attaches debugger, which is called before every statement of
+// the programs and modules the interpreter runs, but not of the prelude.
+func WithDebugger(debugger object.Debugger) Option {
+	return func(interp *Interpreter) { interp.runtime.Debugger = debugger }
+}
+
+// WithTracer reports the statements, calls and errors of the programs and
+// modules the interpreter runs to tracer. The prelude is loaded untraced.
+func WithTracer(tracer object.Tracer) Option {
+	return func(interp *Interpreter) { interp.runtime.Tracer = tracer }
+}
+
+// WithoutPrelude starts the interpreter without the standard prelude, leaving
+// only the builtins in scope.
+func WithoutPrelude() Option {
+	return func(interp *Interpreter) { interp.noPrelude = true }
+}
+
+// New returns an interpreter with the standard builtins and prelude. The
+// prelude is embedded and only binds functions, which the prelude package's
+// tests verify, so loading it cannot fail and the panic below is unreachable.
+func New(options ...Option) *Interpreter {
+	runtime := &object.Runtime{Builtins: evaluator.NewBuiltins(), Optimize: true}
+	interp := &Interpreter{runtime: runtime}
+	for _, option := range options {
+		option(interp)
+	}
+
+	if interp.noPrelude {
+		interp.env = object.NewRuntimeEnvironment(runtime)
+		return interp
+	}
+
+	// The prelude is part of the interpreter, not of the scripts it runs, so
+	// its statements do not count against the step limit and are not shown
+	// to the debugger or the tracer.
+	maxSteps, debugger, tracer := runtime.MaxSteps, runtime.Debugger, runtime.Tracer
+	runtime.MaxSteps, runtime.Debugger, runtime.Tracer = 0, nil, nil
+	runtime.Prelude = object.NewRuntimeEnvironment(runtime)
+	if err := prelude.Load(runtime.Prelude); err != nil {
+		panic(err)
+	}
+	runtime.MaxSteps, runtime.Debugger, runtime.Tracer, runtime.Steps = maxSteps, debugger, tracer, 0
+	interp.env = object.NewEnclosedEnvironment(runtime.Prelude)
+	return interp
+}
+
+// ParseError reports the syntax errors that prevented a program from running.
+type ParseError struct {
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	return "parse error: " + strings.Join(e.Errors, "; ")
+}
+
+// Parse parses source without evaluating it. Syntax errors are returned as
+// a *ParseError.
+func Parse(source string) (*ast.Program, error) {
+	parser := parser.New(lexer.New(source))
+	program := parser.ParseProgram()
+	if len(parser.Errors()) != 0 {
+		return nil, &ParseError{Errors: parser.Errors()}
+	}
+	return program, nil
+}
+
+// Run parses and evaluates source. Undefined names are reported before
+// evaluation starts, even in branches that would never run, so a program
+// that used to fail only when it reached such a name is now rejected
+// outright. The program is optimized unless the interpreter was created
+// WithoutOptimizer. Failures are returned as *object.Error, which supports
+// errors.Is against object.ErrorKind values.
+func (interp *Interpreter) Run(ctx context.Context, source string) (object.Object, error) {
+	program, err := Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := evaluator.Resolve(program, interp.env); err != nil {
+		return nil, err
+	}
+	if interp.runtime.Optimize {
+		optimizer.Optimize(program)
+	}
+
+	defer interp.begin(ctx)()
+	return result(evaluator.Eval(program, interp.env))
+}
+
+// RunFile evaluates the program stored at path. Imports in it are resolved
+// relative to the file's directory.
+func (interp *Interpreter) RunFile(ctx context.Context, path string) (object.Object, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	interp.env.SetFile(path)
+	return interp.Run(ctx, string(source))
+}
+
+// Call invokes the function bound to name with Go arguments.
+func (interp *Interpreter) Call(ctx context.Context, name string, args ...interface{}) (object.Object, error) {
+	fn, ok := interp.env.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("monkey: %s is not defined", name)
+	}
+
+	objects := make([]object.Object, len(args))
+	for i, arg := range args {
+		obj, err := ToObject(arg)
+		if err != nil {
+			return nil, err
+		}
+		objects[i] = obj
+	}
+
+	defer interp.begin(ctx)()
+	return result(evaluator.ApplyFunction(fn, objects, interp.env))
+}
+
+// Set binds a Go value to name in the root environment.
+func (interp *Interpreter) Set(name string, value interface{}) error {
+	obj, err := ToObject(value)
+	if err != nil {
+		return err
+	}
+	interp.env.Set(name, obj)
+	return nil
+}
+
+// Builtins returns the registry of builtins visible to this interpreter.
+func (interp *Interpreter) Builtins() *object.Builtins {
+	return interp.runtime.Builtins
+}
+
+// Get returns the value bound to name in the root environment.
+func (interp *Interpreter) Get(name string) (object.Object, bool) {
+	return interp.env.Get(name)
+}
+
+func (interp *Interpreter) begin(ctx context.Context) func() {
+	interp.runtime.Context = ctx
+	interp.runtime.Steps = 0
+	return func() { interp.runtime.Context = nil }
+}
+
+func result(obj object.Object) (object.Object, error) {
+	if err, ok := obj.(*object.Error); ok {
+		return nil, err
+	}
+	return obj, nil
+}
@@ -0,0 +1,293 @@
+package monkey_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"monkey"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	interp := monkey.New()
+
+	result, err := interp.Run(context.Background(), "let add = fn(a, b) { a + b }; add(1, 2)")
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "3")
+
+	result, err = interp.Run(context.Background(), "add(3, 4)")
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "7")
+}
+
+func TestRunErrors(t *testing.T) {
+	interp := monkey.New()
+
+	_, err := interp.Run(context.Background(), "let = 5;")
+	var parseErr *monkey.ParseError
+	if assert.True(t, errors.As(err, &parseErr)) {
+		assert.NotEmpty(t, parseErr.Errors)
+	}
+
+	_, err = interp.Run(context.Background(), "1 + true")
+	assert.True(t, errors.Is(err, object.TYPE_ERROR))
+	assert.EqualError(t, err, "TypeError: type mismatch: INTEGER + BOOLEAN")
+
+	_, err = interp.Run(context.Background(), `[1, 2, 3]["1"]`)
+	assert.True(t, errors.Is(err, object.INDEX_ERROR))
+	assert.EqualError(t, err, "IndexError: array index must be INTEGER, got STRING")
+}
+
+func TestUndefinedNamesAreReportedBeforeRunning(t *testing.T) {
+	var out bytes.Buffer
+	interp := monkey.New(monkey.WithOutput(&out))
+
+	_, err := interp.Run(context.Background(), `print("start"); if (false) { missing(1) }`)
+	assert.EqualError(t, err, "NameError: identifier not found: missing")
+	var runtimeErr *object.Error
+	if assert.True(t, errors.As(err, &runtimeErr)) {
+		assert.Equal(t, runtimeErr.StackTrace(), "\tat <program> (1:30)\n")
+	}
+	assert.Equal(t, out.String(), "")
+
+	assert.NoError(t, interp.Set("threshold", 3))
+	result, err := interp.Run(context.Background(), "let above = fn(x) { x > threshold == limit() }; let limit = fn() { true }; above(5)")
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "true")
+}
+
+func TestTypeAnnotationsDoNotAffectEvaluation(t *testing.T) {
+	interp := monkey.New()
+
+	result, err := interp.Run(context.Background(), `
+let add = fn(a: int, b: int): int { a + b };
+let names: [string] = ["a", "b"];
+let label: string = add(1, 2);
+label + len(names)
+`)
+	// annotations are only verified by monkey check
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "5")
+}
+
+func TestParse(t *testing.T) {
+	program, err := monkey.Parse("let x = 1; x + 2")
+	assert.NoError(t, err)
+	assert.Equal(t, program.String(), "let x = 1;(x + 2)")
+
+	_, err = monkey.Parse("0b12")
+	assert.EqualError(t, err, `parse error: invalid digit '2' in binary literal "0b12"`)
+}
+
+func TestSetAndGet(t *testing.T) {
+	interp := monkey.New()
+
+	assert.NoError(t, interp.Set("name", "monkey"))
+	assert.NoError(t, interp.Set("limit", 10))
+	assert.NoError(t, interp.Set("enabled", true))
+	assert.Error(t, interp.Set("channel", make(chan int)))
+
+	result, err := interp.Run(context.Background(), `let greeting = if (enabled) { name + "!" }; limit * 2`)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "20")
+
+	greeting, ok := interp.Get("greeting")
+	assert.True(t, ok)
+	assert.Equal(t, greeting.Inspect(), "monkey!")
+
+	_, ok = interp.Get("missing")
+	assert.False(t, ok)
+}
+
+func TestCall(t *testing.T) {
+	interp := monkey.New()
+
+	_, err := interp.Run(context.Background(), "let greet = fn(name, times) { len(name) * times }")
+	assert.NoError(t, err)
+
+	result, err := interp.Call(context.Background(), "greet", "abc", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "6")
+
+	_, err = interp.Call(context.Background(), "greet", "abc")
+	assert.True(t, errors.Is(err, object.ARITY_ERROR))
+
+	_, err = interp.Call(context.Background(), "missing")
+	assert.EqualError(t, err, "monkey: missing is not defined")
+}
+
+func TestOutput(t *testing.T) {
+	var out bytes.Buffer
+	interp := monkey.New(monkey.WithOutput(&out))
+
+	_, err := interp.Run(context.Background(), `print("%s=%d", "x", 1)`)
+	assert.NoError(t, err)
+	assert.Equal(t, out.String(), "x=1\n")
+}
+
+func TestLimits(t *testing.T) {
+	loop := "let loop = fn(n) { loop(n + 1) }; loop(0)"
+
+	_, err := monkey.New(monkey.WithMaxDepth(50)).Run(context.Background(), loop)
+	assert.True(t, errors.Is(err, object.LIMIT_ERROR))
+	assert.EqualError(t, err, "LimitError: maximum call depth of 50 exceeded")
+
+	_, err = monkey.New(monkey.WithMaxSteps(100)).Run(context.Background(), loop)
+	assert.True(t, errors.Is(err, object.LIMIT_ERROR))
+	assert.EqualError(t, err, "LimitError: maximum of 100 steps exceeded")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = monkey.New().Run(ctx, loop)
+	assert.True(t, errors.Is(err, object.TIMEOUT))
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+type stoppingDebugger struct{ err error }
+
+func (d stoppingDebugger) Before(stmt ast.Statement, env *object.Environment) error { return d.err }
+
+func TestDebuggerStop(t *testing.T) {
+	errStop := errors.New("stop")
+	_, err := monkey.New(monkey.WithDebugger(stoppingDebugger{errStop})).Run(context.Background(), "1")
+	assert.EqualError(t, err, "Interrupted: execution stopped: stop")
+	assert.True(t, errors.Is(err, object.INTERRUPTED))
+	assert.False(t, errors.Is(err, object.TIMEOUT))
+	assert.True(t, errors.Is(err, errStop))
+}
+
+func TestStepsResetBetweenRuns(t *testing.T) {
+	interp := monkey.New(monkey.WithMaxSteps(3))
+
+	for i := 0; i < 5; i++ {
+		_, err := interp.Run(context.Background(), "1; 2; 3")
+		assert.NoError(t, err)
+	}
+}
+
+func TestBuiltinRegistry(t *testing.T) {
+	sandboxed := monkey.New(monkey.WithoutBuiltins("print"))
+	scripted := monkey.New()
+
+	assert.NoError(t, scripted.RegisterFunc("strings.upper", strings.ToUpper))
+	assert.NoError(t, scripted.RegisterFunc("strings.lower", strings.ToLower))
+
+	result, err := scripted.Run(context.Background(), `strings.upper("a") + strings.lower("B")`)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "Ab")
+
+	_, err = scripted.Run(context.Background(), `strings.title("a")`)
+	assert.EqualError(t, err, "NameError: unknown member of strings: title")
+
+	_, err = sandboxed.Run(context.Background(), `strings.upper("a")`)
+	assert.True(t, errors.Is(err, object.NAME_ERROR))
+
+	_, err = sandboxed.Run(context.Background(), `print("hi")`)
+	assert.EqualError(t, err, "NameError: identifier not found: print")
+
+	assert.Contains(t, scripted.Builtins().Names(), "print")
+	assert.NotContains(t, sandboxed.Builtins().Names(), "print")
+
+	scripted.Builtins().Remove("strings")
+	_, err = scripted.Run(context.Background(), `strings.upper("a")`)
+	assert.True(t, errors.Is(err, object.NAME_ERROR))
+}
+
+func TestSharedBuiltinRegistry(t *testing.T) {
+	var out bytes.Buffer
+	shared := evaluator.NewBuiltins()
+	sandboxed := monkey.New(monkey.WithBuiltins(shared), monkey.WithoutBuiltins("print"))
+	trusted := monkey.New(monkey.WithBuiltins(shared), monkey.WithOutput(&out))
+
+	_, err := sandboxed.Run(context.Background(), `print("hi")`)
+	assert.EqualError(t, err, "NameError: identifier not found: print")
+
+	_, err = trusted.Run(context.Background(), `print("hi")`)
+	assert.NoError(t, err)
+	assert.Equal(t, out.String(), "hi\n")
+	assert.Contains(t, shared.Names(), "print")
+
+	result, err := monkey.New(monkey.WithBuiltins(nil), monkey.WithoutBuiltins("print")).Run(context.Background(), `len("abc")`)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "3")
+}
+
+func TestBuiltinOverride(t *testing.T) {
+	interp := monkey.New()
+	assert.NoError(t, interp.RegisterFunc("len", func(s string) int { return -1 }))
+
+	result, err := interp.Run(context.Background(), `len("abc")`)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "-1")
+
+	result, err = monkey.New().Run(context.Background(), `len("abc")`)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "3")
+}
+
+func TestBuiltinMetadata(t *testing.T) {
+	registry := object.NewBuiltins()
+	registry.Register(&object.Builtin{
+		Name:   "greet",
+		Params: []string{"name", "greeting?"},
+		Doc:    "Greets name.",
+		Fn: func(env *object.Environment, args ...object.Object) object.Object {
+			greeting := "hello"
+			if len(args) == 2 {
+				greeting = args[1].Inspect()
+			}
+			return &object.String{Value: greeting + " " + args[0].Inspect()}
+		},
+	})
+	interp := monkey.New(monkey.WithBuiltins(registry))
+
+	result, err := interp.Run(context.Background(), `greet("bob")`)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "hello bob")
+
+	result, err = interp.Run(context.Background(), `greet("bob", "hi")`)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "hi bob")
+
+	_, err = interp.Run(context.Background(), `greet()`)
+	assert.EqualError(t, err, "ArityError: wrong number of arguments. got=0, want 1 to 2")
+
+	_, err = interp.Run(context.Background(), `len("abc")`)
+	assert.True(t, errors.Is(err, object.NAME_ERROR))
+
+	builtin, ok := registry.Get("greet")
+	assert.True(t, ok)
+	assert.Equal(t, builtin.Doc, "Greets name.")
+	min, max := builtin.Arity()
+	assert.Equal(t, []int{min, max}, []int{1, 2})
+}
+
+func TestPrelude(t *testing.T) {
+	result, err := monkey.New().Run(context.Background(), "count(map([1, 2, 3], fn(x) { x * 2 }), fn(x) { x > 2 })")
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "2")
+
+	result, err = monkey.New().Run(context.Background(), "let map = 5; map")
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "5")
+
+	_, err = monkey.New(monkey.WithoutPrelude()).Run(context.Background(), "take([1], 1)")
+	assert.EqualError(t, err, "NameError: identifier not found: take")
+
+	_, err = monkey.New(monkey.WithMaxSteps(1)).Run(context.Background(), "1")
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "lib.monkey"), []byte(`export let total = take([1, 2, 3], 2);`), 0o644))
+	result, err = monkey.New(monkey.WithModulePath(dir)).Run(context.Background(), `import { total } from "lib"; total`)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Inspect(), "[1, 2]")
+}
@@ -1,25 +1,61 @@
 package lexer
 
-import "monkey/token"
+import (
+	"fmt"
+	"monkey/token"
+	"strings"
+)
 
 type Lexer struct {
 	input        string // source
 	position     int    // current char position
 	nextPosition int    // next char position
 	currentChar  byte   // current char
+	line         int    // line of the current char
+	column       int    // column of the current char
+	previous     token.TokenType
+	errors       []string // problems found in ILLEGAL tokens
+	positions    []token.Position
+	comments     []token.Token
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	l.skipWhitespace()
 
+	position := token.Position{Line: l.line, Column: l.column}
+	tok := l.readToken()
+	tok.Position = position
+	for len(l.positions) < len(l.errors) {
+		l.positions = append(l.positions, position)
+	}
+	l.previous = tok.Type
+	return tok
+}
+
+// Comments returns the // line comments skipped so far as COMMENT tokens.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+// Errors describes the ILLEGAL tokens read so far.
+func (l *Lexer) Errors() []string {
+	return l.errors
+}
+
+// ErrorPositions returns where each of the tokens described by Errors starts.
+func (l *Lexer) ErrorPositions() []token.Position {
+	return l.positions
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
+
 	switch l.currentChar {
 	case '=':
 		if l.peekChar() == '=' {
@@ -37,7 +73,11 @@ func (l *Lexer) NextToken() token.Token {
 	case '*':
 		tok = token.NewToken(token.STAR, l.currentChar)
 	case '/':
-		tok = token.NewToken(token.SLASH, l.currentChar)
+		if literal, ok := l.readRegex(); ok {
+			tok = token.NewTokenWithLiteral(token.REGEX, literal)
+		} else {
+			tok = token.NewToken(token.SLASH, l.currentChar)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.currentChar
@@ -59,6 +99,10 @@ func (l *Lexer) NextToken() token.Token {
 		tok = token.NewToken(token.RPAREN, l.currentChar)
 	case ',':
 		tok = token.NewToken(token.COMMA, l.currentChar)
+	case '.':
+		tok = token.NewToken(token.DOT, l.currentChar)
+	case ':':
+		tok = token.NewToken(token.COLON, l.currentChar)
 	case '{':
 		tok = token.NewToken(token.LBRACE, l.currentChar)
 	case '}':
@@ -79,11 +123,15 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdentifierTokenType(tok.Literal)
 			return tok
 		} else if isDigit(l.currentChar) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Literal, tok.Type = l.readNumber()
+			if msg := validateNumber(tok.Literal, tok.Type); msg != "" {
+				l.errors = append(l.errors, msg)
+				tok.Type = token.ILLEGAL
+			}
 			return tok
 		} else {
 			tok = token.NewToken(token.ILLEGAL, l.currentChar)
+			l.errors = append(l.errors, fmt.Sprintf("illegal character %q", l.currentChar))
 		}
 	}
 
@@ -93,6 +141,10 @@ func (l *Lexer) NextToken() token.Token {
 }
 
 func (l *Lexer) readChar() {
+	if l.currentChar == '\n' {
+		l.line += 1
+		l.column = 0
+	}
 	if l.nextPosition >= len(l.input) {
 		l.currentChar = 0
 	} else {
@@ -100,6 +152,7 @@ func (l *Lexer) readChar() {
 	}
 	l.position = l.nextPosition
 	l.nextPosition += 1
+	l.column += 1
 }
 
 func (l *Lexer) peekChar() byte {
@@ -118,12 +171,101 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readNumber() string {
+// readNumber reads an integer or a float with a fraction and/or an exponent,
+// as in 3, 3.25 or 1e-9. Integers may have a 0x, 0o or 0b base prefix, and
+// digits may be separated by underscores. A dot not followed by a digit is
+// left for member access.
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position
-	for isDigit(l.currentChar) || l.currentChar == '.' {
+	var tokenType token.TokenType = token.INT
+
+	if l.currentChar == '0' && basePrefix(l.peekChar()) != 0 {
+		l.readChar()
 		l.readChar()
+		// letters are read too, so that a misplaced digit is reported as
+		// part of the literal
+		for isLetter(l.currentChar) || isDigit(l.currentChar) {
+			l.readChar()
+		}
+		return l.input[position:l.position], tokenType
 	}
-	return l.input[position:l.position]
+
+	l.readDigits()
+	if l.currentChar == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+	if (l.currentChar == 'e' || l.currentChar == 'E') && l.exponentFollows() {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.currentChar == '+' || l.currentChar == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+	return l.input[position:l.position], tokenType
+}
+
+func (l *Lexer) readDigits() {
+	for isDigit(l.currentChar) || l.currentChar == '_' {
+		l.readChar()
+	}
+}
+
+// basePrefix returns the base selected by the character after a leading 0,
+// or 0 when it is not a base prefix.
+func basePrefix(ch byte) int {
+	switch ch {
+	case 'x', 'X':
+		return 16
+	case 'o', 'O':
+		return 8
+	case 'b', 'B':
+		return 2
+	}
+	return 0
+}
+
+var baseNames = map[int]string{16: "hexadecimal", 10: "decimal", 8: "octal", 2: "binary"}
+
+// validateNumber checks the digits and underscores of a number literal read
+// by readNumber, returning a description of the first problem or "".
+func validateNumber(literal string, tokenType token.TokenType) string {
+	base, digits := 10, literal
+	if len(literal) > 1 && literal[0] == '0' && basePrefix(literal[1]) != 0 {
+		base, digits = basePrefix(literal[1]), literal[2:]
+		if strings.Trim(digits, "_") == "" {
+			return fmt.Sprintf("%s literal %q has no digits", baseNames[base], literal)
+		}
+	} else if tokenType == token.INT && len(literal) > 1 && literal[0] == '0' {
+		base = 8 // like strconv, a leading zero selects octal
+	}
+
+	for i := 0; i < len(digits); i++ {
+		ch := digits[i]
+		if ch == '_' {
+			prefixed := i == 0 && base != 10 && len(digits) < len(literal)
+			if !(prefixed || i > 0 && isDigitIn(digits[i-1], base)) || i+1 == len(digits) || !isDigitIn(digits[i+1], base) {
+				return fmt.Sprintf("'_' must separate successive digits in %q", literal)
+			}
+			continue
+		}
+		if tokenType == token.INT && !isDigitIn(ch, base) {
+			return fmt.Sprintf("invalid digit %q in %s literal %q", ch, baseNames[base], literal)
+		}
+	}
+	return ""
+}
+
+// exponentFollows reports whether the 'e' under the lexer starts an exponent,
+// that is, whether it is followed by digits with an optional sign.
+func (l *Lexer) exponentFollows() bool {
+	next := l.nextPosition
+	if next < len(l.input) && (l.input[next] == '+' || l.input[next] == '-') {
+		next++
+	}
+	return next < len(l.input) && isDigit(l.input[next])
 }
 
 func (l *Lexer) readString() string {
@@ -137,10 +279,70 @@ func (l *Lexer) readString() string {
 	return l.input[position:l.position]
 }
 
+// regexAllowed reports whether a '/' starts a regex literal rather than a
+// division, which is the case unless it follows an operand.
+func (l *Lexer) regexAllowed() bool {
+	switch l.previous {
+	case token.IDENTIFIER, token.INT, token.FLOAT, token.STRING, token.REGEX, token.TRUE, token.FALSE,
+		token.RPAREN, token.RBRACKET, token.RBRACE:
+		return false
+	}
+	return true
+}
+
+// readRegex reads a /pattern/flags literal, leaving the lexer on its last
+// character. Literals may not span lines; when the '/' does not start a
+// literal the lexer is left where it was.
+func (l *Lexer) readRegex() (string, bool) {
+	if !l.regexAllowed() {
+		return "", false
+	}
+
+	saved := *l
+	position := l.position
+	for {
+		l.readChar()
+		if l.currentChar == '\\' && l.peekChar() != '\n' && l.peekChar() != 0 {
+			l.readChar()
+			continue
+		}
+		if l.currentChar == '/' || l.currentChar == '\n' || l.currentChar == 0 {
+			break
+		}
+	}
+	if l.currentChar != '/' {
+		*l = saved
+		return "", false
+	}
+	for isLetter(l.peekChar()) {
+		l.readChar()
+	}
+	return l.input[position:l.nextPosition], true
+}
+
+// skipWhitespace skips whitespace and comments, which run from // to the end
+// of the line.
 func (l *Lexer) skipWhitespace() {
-	for l.currentChar == ' ' || l.currentChar == '\t' || l.currentChar == '\n' || l.currentChar == '\r' {
+	for {
+		switch {
+		case l.currentChar == ' ' || l.currentChar == '\t' || l.currentChar == '\n' || l.currentChar == '\r':
+			l.readChar()
+		case l.currentChar == '/' && l.peekChar() == '/':
+			l.readComment()
+		default:
+			return
+		}
+	}
+}
+
+func (l *Lexer) readComment() {
+	comment := token.Token{Type: token.COMMENT, Position: token.Position{Line: l.line, Column: l.column}}
+	position := l.position
+	for l.currentChar != '\n' && l.currentChar != 0 {
 		l.readChar()
 	}
+	comment.Literal = strings.TrimRight(l.input[position:l.position], " \t\r")
+	l.comments = append(l.comments, comment)
 }
 
 func isLetter(ch byte) bool {
@@ -150,3 +352,13 @@ func isLetter(ch byte) bool {
 func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
+
+func isDigitIn(ch byte, base int) bool {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch-'0') < base
+	case 'a' <= ch && ch <= 'f', 'A' <= ch && ch <= 'F':
+		return base == 16
+	}
+	return false
+}
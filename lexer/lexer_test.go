@@ -4,10 +4,12 @@ import (
 	"monkey/lexer"
 	"monkey/token"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestSingleCharacterTokens(t *testing.T) {
-	input := `=!+-*/<>(){},;`
+	input := `=!+-*/<>(){},;.:`
 
 	tests := []struct {
 		expectedType    token.TokenType
@@ -27,6 +29,8 @@ func TestSingleCharacterTokens(t *testing.T) {
 		{token.RBRACE, "}"},
 		{token.COMMA, ","},
 		{token.SEMICOLON, ";"},
+		{token.DOT, "."},
+		{token.COLON, ":"},
 	}
 
 	l := lexer.New(input)
@@ -189,3 +193,236 @@ func TestSource(t *testing.T) {
 		}
 	}
 }
+
+func TestTryCatchFinallyKeywords(t *testing.T) {
+	input := `try { } catch (e) { e.message } finally { }`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.TRY, "try"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.CATCH, "catch"},
+		{token.LPAREN, "("},
+		{token.IDENTIFIER, "e"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENTIFIER, "e"},
+		{token.DOT, "."},
+		{token.IDENTIFIER, "message"},
+		{token.RBRACE, "}"},
+		{token.FINALLY, "finally"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := lexer.New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong, expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  add(x,\n\ty);"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"add", 2, 3},
+		{"(", 2, 6},
+		{"x", 2, 7},
+		{",", 2, 8},
+		{"y", 3, 2},
+		{")", 3, 3},
+		{";", 3, 4},
+	}
+
+	l := lexer.New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong, expected=%d:%d, got=%s", i, tt.expectedLine, tt.expectedColumn, tok.Position)
+		}
+	}
+}
+
+func TestRegexLiterals(t *testing.T) {
+	input := `match(/a\/b+/i, s); x / 2 / y; [/\d/]; 1.5 / 2 / 3; x / 2.0 / y; 1 * /open`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENTIFIER, "match"},
+		{token.LPAREN, "("},
+		{token.REGEX, `/a\/b+/i`},
+		{token.COMMA, ","},
+		{token.IDENTIFIER, "s"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SLASH, "/"},
+		{token.IDENTIFIER, "y"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACKET, "["},
+		{token.REGEX, `/\d/`},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.FLOAT, "1.5"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SLASH, "/"},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "x"},
+		{token.SLASH, "/"},
+		{token.FLOAT, "2.0"},
+		{token.SLASH, "/"},
+		{token.IDENTIFIER, "y"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "1"},
+		{token.STAR, "*"},
+		{token.SLASH, "/"},
+		{token.IDENTIFIER, "open"},
+		{token.EOF, ""},
+	}
+
+	l := lexer.New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong, expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestPrefixedAndSeparatedNumberLiterals(t *testing.T) {
+	input := `0xFF 0o17 0B101 1_000 3.141_592 0b12 0x.5`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "0xFF"},
+		{token.INT, "0o17"},
+		{token.INT, "0B101"},
+		{token.INT, "1_000"},
+		{token.FLOAT, "3.141_592"},
+		{token.ILLEGAL, "0b12"},
+		{token.ILLEGAL, "0x"},
+		{token.DOT, "."},
+		{token.INT, "5"},
+		{token.EOF, ""},
+	}
+
+	l := lexer.New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong, expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	assert.Equal(t, l.Errors(), []string{
+		`invalid digit '2' in binary literal "0b12"`,
+		`hexadecimal literal "0x" has no digits`,
+	})
+}
+
+func TestNumberLiterals(t *testing.T) {
+	input := `3 3.25 0.5 1e9 2.5E-3 7e+2 1.x 5.message 4e`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "3"},
+		{token.FLOAT, "3.25"},
+		{token.FLOAT, "0.5"},
+		{token.FLOAT, "1e9"},
+		{token.FLOAT, "2.5E-3"},
+		{token.FLOAT, "7e+2"},
+		{token.INT, "1"},
+		{token.DOT, "."},
+		{token.IDENTIFIER, "x"},
+		{token.INT, "5"},
+		{token.DOT, "."},
+		{token.IDENTIFIER, "message"},
+		{token.INT, "4"},
+		{token.IDENTIFIER, "e"},
+		{token.EOF, ""},
+	}
+
+	l := lexer.New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokenType wrong, expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong, expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "let x = 1; // one  \n// two\nx / 2 // three"
+
+	l := lexer.New(input)
+	types := []token.TokenType{}
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		types = append(types, tok.Type)
+	}
+
+	assert.Equal(t, types, []token.TokenType{
+		token.LET, token.IDENTIFIER, token.ASSIGN, token.INT, token.SEMICOLON,
+		token.IDENTIFIER, token.SLASH, token.INT,
+	})
+	assert.Equal(t, l.Comments(), []token.Token{
+		{Type: token.COMMENT, Literal: "// one", Position: token.Position{Line: 1, Column: 12}},
+		{Type: token.COMMENT, Literal: "// two", Position: token.Position{Line: 2, Column: 1}},
+		{Type: token.COMMENT, Literal: "// three", Position: token.Position{Line: 3, Column: 7}},
+	})
+}
@@ -0,0 +1,111 @@
+// Package lint reports suspicious constructs in Monkey programs.
+//
+// Each check is a Rule with a name that can be enabled or disabled through a
+// Config. Lint resolves the program first, so names bound by the rules are
+// matched with their uses the same way the evaluator matches them.
+package lint
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/resolver"
+	"monkey/token"
+	"sort"
+)
+
+// Diagnostic is a problem reported by a rule.
+type Diagnostic struct {
+	Rule     string
+	Position token.Position
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Position, d.Message, d.Rule)
+}
+
+// Rule is a named check over a whole program.
+type Rule struct {
+	Name  string
+	Doc   string
+	check func(pass *pass)
+}
+
+// Rules returns the available rules in the order they run.
+func Rules() []*Rule {
+	return rules
+}
+
+// Config selects the rules Lint runs. When Enable is empty every rule runs
+// except those in Disable; otherwise only the rules in Enable run.
+type Config struct {
+	Enable  []string
+	Disable []string
+}
+
+// Lint checks program with the rules selected by config and returns the
+// diagnostics sorted by position. It fails when config names an unknown rule.
+func Lint(program *ast.Program, config Config) ([]Diagnostic, error) {
+	selected, err := config.rules()
+	if err != nil {
+		return nil, err
+	}
+
+	// bind every identifier used inside the program to its declaration;
+	// undefined names are the evaluator's concern, not a lint
+	resolver.Resolve(program, func(name string) bool { return true })
+
+	pass := &pass{program: program, diagnostics: []Diagnostic{}}
+	for _, rule := range selected {
+		pass.rule = rule
+		rule.check(pass)
+	}
+
+	sort.SliceStable(pass.diagnostics, func(i, j int) bool {
+		a, b := pass.diagnostics[i].Position, pass.diagnostics[j].Position
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return pass.diagnostics, nil
+}
+
+func (config Config) rules() ([]*Rule, error) {
+	enabled := map[string]bool{}
+	for _, rule := range rules {
+		enabled[rule.Name] = len(config.Enable) == 0
+	}
+	for _, name := range config.Enable {
+		if _, ok := enabled[name]; !ok {
+			return nil, fmt.Errorf("lint: unknown rule %q", name)
+		}
+		enabled[name] = true
+	}
+	for _, name := range config.Disable {
+		if _, ok := enabled[name]; !ok {
+			return nil, fmt.Errorf("lint: unknown rule %q", name)
+		}
+		enabled[name] = false
+	}
+
+	selected := []*Rule{}
+	for _, rule := range rules {
+		if enabled[rule.Name] {
+			selected = append(selected, rule)
+		}
+	}
+	return selected, nil
+}
+
+// pass holds the state of one Lint run.
+type pass struct {
+	program     *ast.Program
+	rule        *Rule
+	diagnostics []Diagnostic
+}
+
+func (p *pass) reportf(position token.Position, format string, a ...interface{}) {
+	p.diagnostics = append(p.diagnostics, Diagnostic{
+		Rule:     p.rule.Name,
+		Position: position,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
@@ -0,0 +1,89 @@
+package lint_test
+
+import (
+	"monkey/lexer"
+	"monkey/lint"
+	"monkey/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func run(t *testing.T, input string, config lint.Config) ([]string, error) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %v", p.Errors())
+	}
+
+	diagnostics, err := lint.Lint(program, config)
+	messages := []string{}
+	for _, diagnostic := range diagnostics {
+		messages = append(messages, diagnostic.String())
+	}
+	return messages, err
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		rule     string
+		input    string
+		expected []string
+	}{
+		{"unused", "let f = fn(a, b) { let c = 1; let d = 2; a + d }", []string{
+			"1:15: parameter b is never used (unused)",
+			"1:24: c is never used (unused)",
+		}},
+		{"unused", "let top = 1; let f = fn(_skip, x) { fn() { x } }", []string{}},
+		{"unused", "let f = fn() { let g = fn(n) { g(n) }; 0 }", []string{}},
+		{"unused", "try { 1 } catch (e) { let m = 1; 2 }", []string{"1:27: m is never used (unused)"}},
+		{"shadowed-builtin", "let len = 1; let f = fn(map) { map }; import { sum } from \"lib\"", []string{
+			"1:5: len shadows a builtin (shadowed-builtin)",
+			"1:25: map shadows a builtin (shadowed-builtin)",
+			"1:48: sum shadows a builtin (shadowed-builtin)",
+		}},
+		{"unreachable", "fn() { return 1; print(2); print(3) }", []string{"1:18: unreachable code after return (unreachable)"}},
+		{"unreachable", "fn(x) { if (x) { return 1; } else { return 2; }; x }", []string{"1:50: unreachable code after return (unreachable)"}},
+		{"unreachable", "fn(x) { if (x) { return 1; }; x }", []string{}},
+		{"constant-condition", "if (1 > 2) { 1 }; if (!true) { 2 }; if (x) { 3 }", []string{
+			"1:5: condition (1 > 2) is constant (constant-condition)",
+			"1:23: condition (!true) is constant (constant-condition)",
+		}},
+		{"type-mismatch", `x == "1"; 1 == "1"; 2.5 != !x; "a" < [1]; 1 < 2.5`, []string{
+			"1:13: comparison of INTEGER and STRING is always false (type-mismatch)",
+			"1:25: comparison of FLOAT and BOOLEAN is always true (type-mismatch)",
+			"1:36: comparison of STRING and ARRAY raises a TypeError (type-mismatch)",
+		}},
+		{"missing-return", "let f = fn(x) { if (x) { return 1; } }", []string{
+			"1:9: function f does not return a value on every path (missing-return)",
+		}},
+		{"missing-return", "let f = fn(x) { if (x) { return 1; }; let y = 2; }", []string{
+			"1:9: function f does not return a value on every path (missing-return)",
+		}},
+		{"missing-return", "fn(x) { if (x) { return 1; } else { 2 } }; fn(x) { if (x) { return 1; }; 0 }; fn(x) { x }", []string{}},
+	}
+
+	for _, tt := range tests {
+		messages, err := run(t, tt.input, lint.Config{Enable: []string{tt.rule}})
+		assert.NoError(t, err)
+		assert.Equal(t, messages, tt.expected, tt.input)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	input := "let f = fn(x) { if (true) { 1 } else { 2 } }"
+
+	messages, err := run(t, input, lint.Config{})
+	assert.NoError(t, err)
+	assert.Equal(t, messages, []string{
+		"1:12: parameter x is never used (unused)",
+		"1:21: condition true is constant (constant-condition)",
+	})
+
+	messages, err = run(t, input, lint.Config{Disable: []string{"unused"}})
+	assert.NoError(t, err)
+	assert.Equal(t, messages, []string{"1:21: condition true is constant (constant-condition)"})
+
+	_, err = run(t, input, lint.Config{Enable: []string{"unused", "bogus"}})
+	assert.EqualError(t, err, `lint: unknown rule "bogus"`)
+}
@@ -0,0 +1,352 @@
+package lint
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"strings"
+)
+
+var rules = []*Rule{
+	{
+		Name:  "unused",
+		Doc:   "local let bindings and parameters that are never used; names starting with _ are ignored",
+		check: checkUnused,
+	},
+	{
+		Name:  "shadowed-builtin",
+		Doc:   "bindings that hide a builtin such as len",
+		check: checkShadowedBuiltins,
+	},
+	{
+		Name:  "unreachable",
+		Doc:   "statements after a return",
+		check: checkUnreachable,
+	},
+	{
+		Name:  "constant-condition",
+		Doc:   "if conditions made only of literals",
+		check: checkConstantConditions,
+	},
+	{
+		Name:  "type-mismatch",
+		Doc:   "comparisons between values of different literal types",
+		check: checkTypeMismatches,
+	},
+	{
+		Name:  "missing-return",
+		Doc:   "functions that return on some paths and fall off the end on others",
+		check: checkMissingReturns,
+	},
+}
+
+func checkUnused(p *pass) {
+	checker := &unusedChecker{pass: p, scope: &usageScope{used: map[int]bool{}}}
+	for _, stmt := range p.program.Statements {
+		ast.Walk(checker, stmt)
+	}
+}
+
+// usageScope follows a resolver scope, recording which of its slots are used.
+type usageScope struct {
+	outer      *usageScope
+	parameters []*ast.Identifier
+	lets       []*ast.Identifier
+	used       map[int]bool
+}
+
+type unusedChecker struct {
+	pass  *pass
+	scope *usageScope
+}
+
+func (c *unusedChecker) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		if node.Binding != nil {
+			scope := c.scope
+			for depth := node.Binding.Depth; depth > 0 && scope != nil; depth-- {
+				scope = scope.outer
+			}
+			if scope != nil {
+				scope.used[node.Binding.Slot] = true
+			}
+		}
+	case *ast.LetStatement:
+		// top-level bindings may be used by importers or the host
+		if c.scope.outer != nil {
+			c.scope.lets = append(c.scope.lets, node.Name)
+		}
+		if node.Value != nil {
+			ast.Walk(c, node.Value)
+		}
+		return nil
+	case *ast.ImportStatement:
+		return nil
+	case *ast.MemberExpression:
+		ast.Walk(c, node.Object)
+		return nil
+	case *ast.FunctionLiteral:
+		c.enter()
+		c.scope.parameters = node.Parameters
+		ast.Walk(c, node.Body)
+		c.leave()
+		return nil
+	case *ast.TryExpression:
+		ast.Walk(c, node.Block)
+		if node.CatchBlock != nil {
+			c.enter()
+			ast.Walk(c, node.CatchBlock)
+			c.leave()
+		}
+		if node.FinallyBlock != nil {
+			ast.Walk(c, node.FinallyBlock)
+		}
+		return nil
+	}
+	return c
+}
+
+func (c *unusedChecker) enter() {
+	c.scope = &usageScope{outer: c.scope, used: map[int]bool{}}
+}
+
+func (c *unusedChecker) leave() {
+	for _, ident := range c.scope.parameters {
+		if c.unused(ident) {
+			c.pass.reportf(ident.Token.Position, "parameter %s is never used", ident.Value)
+		}
+	}
+	for _, ident := range c.scope.lets {
+		if c.unused(ident) {
+			c.pass.reportf(ident.Token.Position, "%s is never used", ident.Value)
+		}
+	}
+	c.scope = c.scope.outer
+}
+
+func (c *unusedChecker) unused(ident *ast.Identifier) bool {
+	return ident.Binding != nil && !c.scope.used[ident.Binding.Slot] && !strings.HasPrefix(ident.Value, "_")
+}
+
+func checkShadowedBuiltins(p *pass) {
+	builtins := evaluator.NewBuiltins()
+	check := func(ident *ast.Identifier) {
+		if ident == nil {
+			return
+		}
+		if _, ok := builtins.Lookup(ident.Value); ok {
+			p.reportf(ident.Token.Position, "%s shadows a builtin", ident.Value)
+		}
+	}
+
+	ast.Inspect(p.program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			check(node.Name)
+		case *ast.ImportStatement:
+			for _, name := range node.Names {
+				check(name)
+			}
+		case *ast.FunctionLiteral:
+			for _, param := range node.Parameters {
+				check(param)
+			}
+		case *ast.TryExpression:
+			check(node.CatchParameter)
+		}
+		return true
+	})
+}
+
+func checkUnreachable(p *pass) {
+	ast.Inspect(p.program, func(node ast.Node) bool {
+		var stmts []ast.Statement
+		switch node := node.(type) {
+		case *ast.Program:
+			stmts = node.Statements
+		case *ast.BlockStatement:
+			stmts = node.Statements
+		}
+		for i := 0; i < len(stmts)-1; i++ {
+			if alwaysReturns(stmts[i]) {
+				p.reportf(ast.SpanOf(stmts[i+1]).Start, "unreachable code after return")
+				break
+			}
+		}
+		return true
+	})
+}
+
+// alwaysReturns reports whether every path through stmt ends in a return,
+// or in an error raised before it completes.
+func alwaysReturns(stmt ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStatement:
+		return true
+	case *ast.ExpressionStatement:
+		switch expr := stmt.Expression.(type) {
+		case *ast.IfExpression:
+			return expr.ElseBranch != nil && blockReturns(expr.ThenBranch) && blockReturns(expr.ElseBranch)
+		case *ast.TryExpression:
+			if expr.FinallyBlock != nil && blockReturns(expr.FinallyBlock) {
+				return true
+			}
+			return blockReturns(expr.Block) && (expr.CatchBlock == nil || blockReturns(expr.CatchBlock))
+		}
+	}
+	return false
+}
+
+func blockReturns(block *ast.BlockStatement) bool {
+	for _, stmt := range block.Statements {
+		if alwaysReturns(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkConstantConditions(p *pass) {
+	ast.Inspect(p.program, func(node ast.Node) bool {
+		if expr, ok := node.(*ast.IfExpression); ok && isConstant(expr.Condition) {
+			p.reportf(ast.SpanOf(expr.Condition).Start, "condition %s is constant", expr.Condition.String())
+		}
+		return true
+	})
+}
+
+// isConstant reports whether expr always evaluates to the same value.
+func isConstant(expr ast.Expression) bool {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.BooleanLiteral, *ast.StringLiteral, *ast.RegexLiteral:
+		return true
+	case *ast.FunctionLiteral, *ast.ArrayLiteral, *ast.HashLiteral:
+		// always truthy, whatever their contents
+		return true
+	case *ast.PrefixExpression:
+		return isConstant(expr.Right)
+	case *ast.InfixExpression:
+		return isConstant(expr.Left) && isConstant(expr.Right)
+	}
+	return false
+}
+
+func checkTypeMismatches(p *pass) {
+	ast.Inspect(p.program, func(node ast.Node) bool {
+		expr, ok := node.(*ast.InfixExpression)
+		if !ok || !isComparison(expr.Operator) {
+			return true
+		}
+		left, right := staticType(expr.Left), staticType(expr.Right)
+		if left == "" || right == "" || left == right || isNumeric(left) && isNumeric(right) {
+			return true
+		}
+
+		switch expr.Operator {
+		case "==":
+			p.reportf(expr.Token.Position, "comparison of %s and %s is always false", left, right)
+		case "!=":
+			p.reportf(expr.Token.Position, "comparison of %s and %s is always true", left, right)
+		default:
+			p.reportf(expr.Token.Position, "comparison of %s and %s raises a TypeError", left, right)
+		}
+		return true
+	})
+}
+
+func isComparison(operator string) bool {
+	return operator == "==" || operator == "!=" || operator == "<" || operator == ">"
+}
+
+func isNumeric(objectType object.ObjectType) bool {
+	return objectType == object.INTEGER_OBJ || objectType == object.FLOAT_OBJ
+}
+
+// staticType returns the type expr evaluates to when it is known without
+// running the program, or "".
+func staticType(expr ast.Expression) object.ObjectType {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		return object.INTEGER_OBJ
+	case *ast.FloatLiteral:
+		return object.FLOAT_OBJ
+	case *ast.BooleanLiteral:
+		return object.BOOLEAN_OBJ
+	case *ast.StringLiteral:
+		return object.STRING_OBJ
+	case *ast.RegexLiteral:
+		return object.REGEX_OBJ
+	case *ast.ArrayLiteral:
+		return object.ARRAY_OBJ
+	case *ast.HashLiteral:
+		return object.HASH_OBJ
+	case *ast.FunctionLiteral:
+		return object.FUNCTION_OBJ
+	case *ast.PrefixExpression:
+		if expr.Operator == "!" {
+			return object.BOOLEAN_OBJ
+		}
+		if right := staticType(expr.Right); isNumeric(right) {
+			return right
+		}
+	case *ast.InfixExpression:
+		if isComparison(expr.Operator) {
+			return object.BOOLEAN_OBJ
+		}
+	}
+	return ""
+}
+
+func checkMissingReturns(p *pass) {
+	ast.Inspect(p.program, func(node ast.Node) bool {
+		fn, ok := node.(*ast.FunctionLiteral)
+		if ok && hasReturn(fn.Body) && !completes(fn.Body) {
+			name := "function"
+			if fn.Name != "" {
+				name += " " + fn.Name
+			}
+			p.reportf(fn.Token.Position, "%s does not return a value on every path", name)
+		}
+		return true
+	})
+}
+
+// hasReturn reports whether block contains a return statement outside the
+// functions nested in it.
+func hasReturn(block *ast.BlockStatement) bool {
+	found := false
+	ast.Inspect(block, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.ReturnStatement:
+			found = true
+		case *ast.FunctionLiteral:
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// completes reports whether every path through block ends in a return or in
+// an expression whose value the block produces.
+func completes(block *ast.BlockStatement) bool {
+	if block == nil || len(block.Statements) == 0 {
+		return false
+	}
+	if blockReturns(block) {
+		return true
+	}
+
+	last, ok := block.Statements[len(block.Statements)-1].(*ast.ExpressionStatement)
+	if !ok {
+		return false
+	}
+	switch expr := last.Expression.(type) {
+	case *ast.IfExpression:
+		return expr.ElseBranch != nil && completes(expr.ThenBranch) && completes(expr.ElseBranch)
+	case *ast.TryExpression:
+		return completes(expr.Block) && (expr.CatchBlock == nil || completes(expr.CatchBlock))
+	}
+	return true
+}
@@ -0,0 +1,312 @@
+package lsp
+
+import (
+	"monkey/ast"
+	"monkey/checker"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/resolver"
+	"monkey/token"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// document is an open text document and what the server knows about it.
+type document struct {
+	uri         string
+	text        string
+	lines       []string
+	parsed      bool // whether text parses
+	diagnostics []Diagnostic
+
+	// program is the last version of the document that parsed without
+	// errors, so editing features keep working while the text is broken.
+	// It is nil until the document first parses.
+	program      *ast.Program
+	programLines []string // the lines of program's source
+	index        *index
+	types        map[ast.Expression]checker.Type
+}
+
+// analyze parses text and updates the diagnostics, along with the program
+// and its index when text parses. defined reports the names bound by the
+// builtins and the prelude.
+func (doc *document) analyze(text string, defined func(name string) bool) {
+	doc.text = text
+	doc.lines = strings.Split(text, "\n")
+	doc.diagnostics = []Diagnostic{}
+
+	p := parser.New(lexer.New(text))
+	program := p.ParseProgram()
+	doc.parsed = len(p.ErrorList()) == 0
+	if errs := p.ErrorList(); len(errs) > 0 {
+		for _, err := range errs {
+			doc.report(err.Position, SEVERITY_ERROR, "monkey", err.Message)
+		}
+		return
+	}
+
+	for _, err := range resolver.Resolve(program, defined) {
+		doc.report(err.Position, SEVERITY_ERROR, "monkey", err.Message)
+	}
+	types, errs := checker.Infer(program)
+	for _, err := range errs {
+		doc.report(err.Position, SEVERITY_WARNING, "monkey check", err.Message)
+	}
+
+	doc.program, doc.programLines, doc.types = program, doc.lines, types
+	doc.index = newIndex(program)
+}
+
+// report adds a diagnostic covering the word starting at position.
+func (doc *document) report(position token.Position, severity int, source string, message string) {
+	start := doc.position(doc.lines, position)
+	end := start
+	if line := position.Line - 1; line >= 0 && line < len(doc.lines) && position.Column-1 <= len(doc.lines[line]) {
+		word := wordAt(doc.lines[line], position.Column-1)
+		end = doc.position(doc.lines, token.Position{Line: position.Line, Column: position.Column + len(word)})
+		if word == "" && end.Character == start.Character {
+			end.Character++
+		}
+	}
+	doc.diagnostics = append(doc.diagnostics, Diagnostic{
+		Range:    Range{Start: start, End: end},
+		Severity: severity,
+		Source:   source,
+		Message:  message,
+	})
+}
+
+// wordAt returns the identifier or number starting at offset in line, or ""
+// when another character starts there.
+func wordAt(line string, offset int) string {
+	end := offset
+	for end < len(line) && isWordByte(line[end]) {
+		end++
+	}
+	return line[offset:end]
+}
+
+func isWordByte(ch byte) bool {
+	return ch == '_' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9'
+}
+
+// position converts a 1-based byte position in lines to an LSP position.
+func (doc *document) position(lines []string, position token.Position) Position {
+	line := position.Line - 1
+	if line < 0 {
+		return Position{}
+	}
+	if line >= len(lines) {
+		return Position{Line: line}
+	}
+	text := lines[line]
+	offset := position.Column - 1
+	if offset > len(text) {
+		offset = len(text)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return Position{Line: line, Character: utf16Length(text[:offset])}
+}
+
+// offset converts an LSP position to a 1-based byte position in lines.
+func (doc *document) offset(lines []string, position Position) token.Position {
+	if position.Line < 0 || position.Line >= len(lines) {
+		return token.Position{Line: position.Line + 1, Column: 1}
+	}
+	text := lines[position.Line]
+	units, offset := 0, 0
+	for offset < len(text) && units < position.Character {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return token.Position{Line: position.Line + 1, Column: offset + 1}
+}
+
+func utf16Length(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// span converts the source range of node in the analyzed program.
+func (doc *document) span(node ast.Node) Range {
+	span := ast.SpanOf(node)
+	return Range{Start: doc.position(doc.programLines, span.Start), End: doc.position(doc.programLines, span.End)}
+}
+
+func (doc *document) identifierRange(ident *ast.Identifier) Range {
+	end := token.Position{Line: ident.Token.Line, Column: ident.Token.Column + len(ident.Value)}
+	return Range{Start: doc.position(doc.programLines, ident.Token.Position), End: doc.position(doc.programLines, end)}
+}
+
+// identifierAt returns the identifier of the analyzed program under the
+// given position, or nil.
+func (doc *document) identifierAt(position Position) *ast.Identifier {
+	if doc.index == nil {
+		return nil
+	}
+	at := doc.offset(doc.programLines, position)
+	for _, ident := range doc.index.identifiers {
+		if ident.Token.Line == at.Line && ident.Token.Column <= at.Column && at.Column <= ident.Token.Column+len(ident.Value) {
+			return ident
+		}
+	}
+	return nil
+}
+
+// symbol is a name declared in a scope together with the identifiers that
+// declare and refer to it.
+type symbol struct {
+	name         string
+	declaration  *ast.Identifier   // the first declaration, nil if not found
+	declarations []*ast.Identifier // every let, parameter or import binding it
+	references   []*ast.Identifier
+}
+
+// scope is a scope of the program as seen by the resolver: the program
+// itself, a function or a catch block.
+type scope struct {
+	outer   *scope
+	node    ast.Node // nil for the program
+	symbols map[int]*symbol
+}
+
+// index records the scopes of a resolved program and the symbols in them.
+type index struct {
+	root        *scope
+	scopes      []*scope
+	identifiers []*ast.Identifier
+	symbols     map[*ast.Identifier]*symbol
+}
+
+func newIndex(program *ast.Program) *index {
+	idx := &index{symbols: map[*ast.Identifier]*symbol{}}
+	idx.root = idx.enter(nil, nil)
+	ast.Walk(&indexer{index: idx, scope: idx.root}, program)
+	return idx
+}
+
+func (idx *index) enter(outer *scope, node ast.Node) *scope {
+	s := &scope{outer: outer, node: node, symbols: map[int]*symbol{}}
+	idx.scopes = append(idx.scopes, s)
+	return s
+}
+
+// symbolIn returns the symbol for slot in s, creating it on first use.
+func (s *scope) symbolIn(slot int, name string) *symbol {
+	sym, ok := s.symbols[slot]
+	if !ok {
+		sym = &symbol{name: name}
+		s.symbols[slot] = sym
+	}
+	return sym
+}
+
+// indexer walks a resolved program, following the bindings the resolver
+// set to connect each identifier with its symbol.
+type indexer struct {
+	index *index
+	scope *scope
+}
+
+func (v *indexer) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		v.use(node)
+	case *ast.LetStatement:
+		if node.Value != nil {
+			ast.Walk(v, node.Value)
+		}
+		v.declare(node.Name)
+		return nil
+	case *ast.ImportStatement:
+		for _, name := range node.Names {
+			v.declare(name)
+		}
+		return nil
+	case *ast.MemberExpression:
+		ast.Walk(v, node.Object)
+		return nil
+	case *ast.FunctionLiteral:
+		inner := &indexer{index: v.index, scope: v.index.enter(v.scope, node)}
+		for _, param := range node.Parameters {
+			inner.declare(param)
+		}
+		ast.Walk(inner, node.Body)
+		return nil
+	case *ast.TryExpression:
+		ast.Walk(v, node.Block)
+		if node.CatchBlock != nil {
+			inner := &indexer{index: v.index, scope: v.index.enter(v.scope, node.CatchBlock)}
+			if node.CatchParameter != nil {
+				inner.declare(node.CatchParameter)
+			}
+			ast.Walk(inner, node.CatchBlock)
+		}
+		if node.FinallyBlock != nil {
+			ast.Walk(v, node.FinallyBlock)
+		}
+		return nil
+	case ast.TypeAnnotation:
+		return nil
+	}
+	return v
+}
+
+func (v *indexer) declare(ident *ast.Identifier) {
+	v.index.identifiers = append(v.index.identifiers, ident)
+	if ident.Binding == nil {
+		return
+	}
+	sym := v.scope.symbolIn(ident.Binding.Slot, ident.Value)
+	if sym.declaration == nil {
+		sym.declaration = ident
+	}
+	sym.declarations = append(sym.declarations, ident)
+	v.index.symbols[ident] = sym
+}
+
+func (v *indexer) use(ident *ast.Identifier) {
+	v.index.identifiers = append(v.index.identifiers, ident)
+	if ident.Binding == nil {
+		return
+	}
+	s := v.scope
+	for depth := ident.Binding.Depth; depth > 0 && s != nil; depth-- {
+		s = s.outer
+	}
+	if s == nil {
+		return
+	}
+	sym := s.symbolIn(ident.Binding.Slot, ident.Value)
+	sym.references = append(sym.references, ident)
+	v.index.symbols[ident] = sym
+}
+
+// scopeAt returns the innermost scope containing position.
+func (idx *index) scopeAt(position token.Position) *scope {
+	innermost := idx.root
+	for _, s := range idx.scopes {
+		if s.node != nil && contains(ast.SpanOf(s.node), position) && depthOf(s) > depthOf(innermost) {
+			innermost = s
+		}
+	}
+	return innermost
+}
+
+func depthOf(s *scope) int {
+	depth := 0
+	for ; s.outer != nil; s = s.outer {
+		depth++
+	}
+	return depth
+}
+
+func contains(span ast.Span, position token.Position) bool {
+	afterStart := position.Line > span.Start.Line || position.Line == span.Start.Line && position.Column >= span.Start.Column
+	beforeEnd := position.Line < span.End.Line || position.Line == span.End.Line && position.Column <= span.End.Column
+	return afterStart && beforeEnd
+}
@@ -0,0 +1,433 @@
+// Package lsp implements a Language Server Protocol server for Monkey.
+//
+// The server speaks JSON-RPC over a pair of streams, normally the standard
+// input and output of `monkey lsp`. It synchronizes whole documents and
+// offers diagnostics from the parser, the resolver and the checker, hover,
+// go-to-definition, find-references, document symbols, completion and
+// formatting.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/formatter"
+	"monkey/object"
+	"monkey/prelude"
+	"monkey/token"
+	"monkey/wire"
+	"sort"
+	"strings"
+)
+
+// Server is a language server for one client.
+type Server struct {
+	in        *bufio.Reader
+	out       io.Writer
+	documents map[string]*document
+	builtins  *object.Builtins
+	prelude   *object.Environment
+	shutdown  bool
+}
+
+// NewServer returns a server reading requests from in and writing responses
+// and notifications to out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	runtime := &object.Runtime{Builtins: evaluator.NewBuiltins()}
+	env := object.NewRuntimeEnvironment(runtime)
+	if err := prelude.Load(env); err != nil {
+		panic(err)
+	}
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: map[string]*document{},
+		builtins:  runtime.Builtins,
+		prelude:   env,
+	}
+}
+
+// Serve handles messages until the client sends exit or closes the input.
+func (server *Server) Serve() error {
+	for {
+		body, err := wire.ReadMessage(server.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := server.replyError(nil, &Error{Code: PARSE_ERROR, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+		if err := server.handle(&req); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches req and replies to it unless it is a notification.
+// Only failures to write to the client are returned.
+func (server *Server) handle(req *request) error {
+	if server.shutdown {
+		// only exit is expected after shutdown
+		if req.ID == nil {
+			return nil
+		}
+		return server.replyError(req.ID, &Error{Code: INVALID_REQUEST, Message: "server is shut down"})
+	}
+
+	var result interface{}
+	var err error
+	switch req.Method {
+	case "initialize":
+		result = server.initialize()
+	case "initialized":
+	case "shutdown":
+		server.shutdown = true
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err = decode(req.Params, &params); err == nil {
+			return server.open(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err = decode(req.Params, &params); err == nil && len(params.ContentChanges) > 0 {
+			changes := params.ContentChanges
+			return server.open(params.TextDocument.URI, changes[len(changes)-1].Text)
+		}
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err = decode(req.Params, &params); err == nil {
+			delete(server.documents, params.TextDocument.URI)
+			return server.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+				URI:         params.TextDocument.URI,
+				Diagnostics: []Diagnostic{},
+			})
+		}
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err = decode(req.Params, &params); err == nil {
+			result = server.hover(params)
+		}
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err = decode(req.Params, &params); err == nil {
+			result = server.definition(params)
+		}
+	case "textDocument/references":
+		var params ReferenceParams
+		if err = decode(req.Params, &params); err == nil {
+			result = server.references(params)
+		}
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err = decode(req.Params, &params); err == nil {
+			result = server.documentSymbols(params)
+		}
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err = decode(req.Params, &params); err == nil {
+			result = server.completion(params)
+		}
+	case "textDocument/formatting":
+		var params DocumentFormattingParams
+		if err = decode(req.Params, &params); err == nil {
+			result = server.format(params)
+		}
+	default:
+		err = &Error{Code: METHOD_NOT_FOUND, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+
+	if req.ID == nil {
+		// notifications get no reply, even when they fail
+		return nil
+	}
+	if err != nil {
+		rpcErr, ok := err.(*Error)
+		if !ok {
+			rpcErr = &Error{Code: INVALID_PARAMS, Message: err.Error()}
+		}
+		return server.replyError(req.ID, rpcErr)
+	}
+	return wire.WriteMessage(server.out, response{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+func decode(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return &Error{Code: INVALID_PARAMS, Message: "missing params"}
+	}
+	return json.Unmarshal(params, v)
+}
+
+func (server *Server) replyError(id *json.RawMessage, err *Error) error {
+	return wire.WriteMessage(server.out, errorResponse{JSONRPC: "2.0", ID: id, Error: err})
+}
+
+func (server *Server) notify(method string, params interface{}) error {
+	return wire.WriteMessage(server.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (server *Server) initialize() interface{} {
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"textDocumentSync":           1, // full
+			"hoverProvider":              true,
+			"definitionProvider":         true,
+			"referencesProvider":         true,
+			"documentSymbolProvider":     true,
+			"documentFormattingProvider": true,
+			"completionProvider":         map[string]interface{}{},
+		},
+		"serverInfo": map[string]interface{}{"name": "monkey"},
+	}
+}
+
+// defined reports whether name is bound by a builtin or the prelude.
+func (server *Server) defined(name string) bool {
+	if _, ok := server.builtins.Lookup(name); ok {
+		return true
+	}
+	_, ok := server.prelude.Get(name)
+	return ok
+}
+
+// open analyzes a new version of a document and publishes its diagnostics.
+func (server *Server) open(uri string, text string) error {
+	doc, ok := server.documents[uri]
+	if !ok {
+		doc = &document{uri: uri}
+		server.documents[uri] = doc
+	}
+	doc.analyze(text, server.defined)
+	return server.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: doc.diagnostics,
+	})
+}
+
+func (server *Server) hover(params TextDocumentPositionParams) interface{} {
+	doc, ok := server.documents[params.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	ident := doc.identifierAt(params.Position)
+	if ident == nil {
+		return nil
+	}
+
+	var contents string
+	if sym, ok := doc.index.symbols[ident]; ok {
+		contents = fmt.Sprintf("```monkey\n%s: %s\n```", sym.name, doc.typeOf(ident))
+	} else if builtin, ok := server.builtins.Get(ident.Value); ok {
+		contents = builtinDoc(builtin)
+	} else if value, ok := server.prelude.Get(ident.Value); ok {
+		contents = preludeDoc(ident.Value, value)
+	} else {
+		return nil
+	}
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: contents},
+		Range:    doc.identifierRange(ident),
+	}
+}
+
+// typeOf returns the type the checker inferred for ident, or any.
+func (doc *document) typeOf(ident *ast.Identifier) string {
+	if typ, ok := doc.types[ident]; ok {
+		return typ.String()
+	}
+	return "any"
+}
+
+func builtinDoc(builtin *object.Builtin) string {
+	signature := builtin.Name
+	if builtin.Value != nil {
+		signature += " = " + builtin.Value.Inspect()
+	} else {
+		signature += "(" + strings.Join(builtin.Params, ", ") + ")"
+	}
+	doc := "```monkey\n" + signature + "\n```"
+	if builtin.Doc != "" {
+		doc += "\n" + builtin.Doc
+	}
+	return doc
+}
+
+func preludeDoc(name string, value object.Object) string {
+	signature := name
+	if fn, ok := value.(*object.Function); ok {
+		params := []string{}
+		for _, param := range fn.Parameters {
+			params = append(params, param.Value)
+		}
+		signature += "(" + strings.Join(params, ", ") + ")"
+	} else {
+		signature += " = " + value.Inspect()
+	}
+	return "```monkey\n" + signature + "\n```\nDefined in the prelude."
+}
+
+func (server *Server) definition(params TextDocumentPositionParams) interface{} {
+	doc, ok := server.documents[params.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+	ident := doc.identifierAt(params.Position)
+	if ident == nil {
+		return nil
+	}
+	sym, ok := doc.index.symbols[ident]
+	if !ok || sym.declaration == nil {
+		return nil
+	}
+	return Location{URI: doc.uri, Range: doc.identifierRange(sym.declaration)}
+}
+
+func (server *Server) references(params ReferenceParams) interface{} {
+	locations := []Location{}
+	doc, ok := server.documents[params.TextDocument.URI]
+	if !ok {
+		return locations
+	}
+	ident := doc.identifierAt(params.Position)
+	if ident == nil {
+		return locations
+	}
+	sym, ok := doc.index.symbols[ident]
+	if !ok {
+		return locations
+	}
+
+	idents := sym.references
+	if params.Context.IncludeDeclaration {
+		idents = append(append([]*ast.Identifier{}, sym.declarations...), idents...)
+	}
+	sort.SliceStable(idents, func(i, j int) bool {
+		a, b := idents[i].Token.Position, idents[j].Token.Position
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	for _, ident := range idents {
+		locations = append(locations, Location{URI: doc.uri, Range: doc.identifierRange(ident)})
+	}
+	return locations
+}
+
+func (server *Server) documentSymbols(params DocumentSymbolParams) interface{} {
+	doc, ok := server.documents[params.TextDocument.URI]
+	if !ok || doc.program == nil {
+		return []DocumentSymbol{}
+	}
+	return doc.symbols(doc.program.Statements)
+}
+
+// symbols lists the lets among stmts, with the lets of the functions they
+// bind as children.
+func (doc *document) symbols(stmts []ast.Statement) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, stmt := range stmts {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok {
+			continue
+		}
+		symbol := DocumentSymbol{
+			Name:           let.Name.Value,
+			Kind:           SYMBOL_VARIABLE,
+			Detail:         doc.typeOf(let.Name),
+			Range:          doc.span(let),
+			SelectionRange: doc.identifierRange(let.Name),
+		}
+		if fn, ok := let.Value.(*ast.FunctionLiteral); ok {
+			symbol.Kind = SYMBOL_FUNCTION
+			symbol.Children = doc.symbols(fn.Body.Statements)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+func (server *Server) completion(params TextDocumentPositionParams) interface{} {
+	items := []CompletionItem{}
+	seen := map[string]bool{}
+	add := func(item CompletionItem) {
+		if !seen[item.Label] {
+			seen[item.Label] = true
+			items = append(items, item)
+		}
+	}
+
+	// names in scope come first, innermost scope first
+	if doc, ok := server.documents[params.TextDocument.URI]; ok && doc.index != nil {
+		for s := doc.index.scopeAt(doc.offset(doc.programLines, params.Position)); s != nil; s = s.outer {
+			for _, sym := range sortedSymbols(s) {
+				kind := COMPLETION_VARIABLE
+				typ := doc.typeOf(sym.declaration)
+				if strings.HasPrefix(typ, "fn") {
+					kind = COMPLETION_FUNCTION
+				}
+				add(CompletionItem{Label: sym.name, Kind: kind, Detail: typ})
+			}
+		}
+	}
+	for _, name := range server.builtins.Names() {
+		builtin, _ := server.builtins.Get(name)
+		kind := COMPLETION_FUNCTION
+		if builtin.Value != nil {
+			kind = COMPLETION_CONSTANT
+		}
+		add(CompletionItem{Label: name, Kind: kind, Detail: "builtin", Documentation: builtin.Doc})
+	}
+	for _, name := range server.prelude.Names() {
+		value, _ := server.prelude.Get(name)
+		kind := COMPLETION_VARIABLE
+		if _, ok := value.(*object.Function); ok {
+			kind = COMPLETION_FUNCTION
+		}
+		add(CompletionItem{Label: name, Kind: kind, Detail: "prelude"})
+	}
+	for _, keyword := range token.Keywords() {
+		add(CompletionItem{Label: keyword, Kind: COMPLETION_KEYWORD})
+	}
+	return items
+}
+
+// sortedSymbols returns the declared symbols of s in source order.
+func sortedSymbols(s *scope) []*symbol {
+	symbols := []*symbol{}
+	for _, sym := range s.symbols {
+		if sym.declaration != nil {
+			symbols = append(symbols, sym)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		a, b := symbols[i].declaration.Token.Position, symbols[j].declaration.Token.Position
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+	return symbols
+}
+
+func (server *Server) format(params DocumentFormattingParams) interface{} {
+	edits := []TextEdit{}
+	doc, ok := server.documents[params.TextDocument.URI]
+	// formatting the last version that parsed would undo the edits since
+	if !ok || !doc.parsed {
+		return edits
+	}
+	formatted := formatter.Format(doc.program)
+	if formatted == doc.text {
+		return edits
+	}
+	end := Position{Line: len(doc.lines) - 1, Character: utf16Length(doc.lines[len(doc.lines)-1])}
+	return append(edits, TextEdit{Range: Range{End: end}, NewText: formatted})
+}
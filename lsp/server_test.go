@@ -0,0 +1,311 @@
+package lsp_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey/lsp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// client drives a server over pipes the way an editor would.
+type client struct {
+	t      *testing.T
+	in     io.WriteCloser
+	out    *bufio.Reader
+	nextID int
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	c := &client{t: t, in: inWriter, out: bufio.NewReader(outReader), done: make(chan error, 1)}
+	go func() {
+		c.done <- lsp.NewServer(inReader, outWriter).Serve()
+		outWriter.Close()
+	}()
+
+	var result struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	c.request("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}, &result)
+	assert.Equal(t, result.Capabilities["hoverProvider"], true)
+	c.notify("initialized", map[string]interface{}{})
+	return c
+}
+
+func (c *client) send(message map[string]interface{}) {
+	message["jsonrpc"] = "2.0"
+	body, err := json.Marshal(message)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// receive reads the next message from the server.
+func (c *client) receive() map[string]json.RawMessage {
+	length := 0
+	for {
+		line, err := c.out.ReadString('\n')
+		if err != nil {
+			c.t.Fatalf("reading header: %v", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if value := strings.TrimPrefix(line, "Content-Length: "); value != line {
+			length, _ = strconv.Atoi(value)
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.out, body); err != nil {
+		c.t.Fatalf("reading body: %v", err)
+	}
+
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		c.t.Fatalf("decoding %s: %v", body, err)
+	}
+	return message
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.send(map[string]interface{}{"method": method, "params": params})
+}
+
+// request sends a request and decodes the result of its response into
+// result, failing the test on an error response.
+func (c *client) request(method string, params interface{}, result interface{}) {
+	c.nextID++
+	c.send(map[string]interface{}{"id": c.nextID, "method": method, "params": params})
+
+	message := c.receive()
+	if _, ok := message["error"]; ok {
+		c.t.Fatalf("%s failed: %s", method, message["error"])
+	}
+	assert.Equal(c.t, string(message["id"]), strconv.Itoa(c.nextID))
+	if err := json.Unmarshal(message["result"], result); err != nil {
+		c.t.Fatalf("decoding %s result %s: %v", method, message["result"], err)
+	}
+}
+
+// open sends the text of a document and returns the diagnostics published
+// for it.
+func (c *client) open(uri string, text string) []lsp.Diagnostic {
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "monkey", "version": 1, "text": text},
+	})
+	return c.diagnostics(uri)
+}
+
+func (c *client) change(uri string, text string) []lsp.Diagnostic {
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []interface{}{map[string]interface{}{"text": text}},
+	})
+	return c.diagnostics(uri)
+}
+
+func (c *client) diagnostics(uri string) []lsp.Diagnostic {
+	message := c.receive()
+	assert.Equal(c.t, string(message["method"]), `"textDocument/publishDiagnostics"`)
+	var params lsp.PublishDiagnosticsParams
+	if err := json.Unmarshal(message["params"], &params); err != nil {
+		c.t.Fatal(err)
+	}
+	assert.Equal(c.t, params.URI, uri)
+	return params.Diagnostics
+}
+
+func (c *client) close() {
+	var result interface{}
+	c.request("shutdown", nil, &result)
+	c.notify("exit", nil)
+	assert.NoError(c.t, <-c.done)
+}
+
+func position(uri string, line int, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     map[string]interface{}{"line": line, "character": character},
+	}
+}
+
+func span(startLine, startCharacter, endLine, endCharacter int) lsp.Range {
+	return lsp.Range{
+		Start: lsp.Position{Line: startLine, Character: startCharacter},
+		End:   lsp.Position{Line: endLine, Character: endCharacter},
+	}
+}
+
+const URI = "file:///tmp/main.monkey"
+
+const SOURCE = `let square = fn(x: int): int { x * x };
+let total = square(3) + len("abc");
+let greet = fn(name) {
+  let message = "hi " + name;
+  message
+};
+greet("bob")
+`
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+
+	assert.Equal(t, c.open(URI, SOURCE), []lsp.Diagnostic{})
+	assert.Equal(t, c.change(URI, "let x = 1 +;\nx"), []lsp.Diagnostic{
+		{Range: span(0, 11, 0, 12), Severity: lsp.SEVERITY_ERROR, Source: "monkey", Message: "no prefix parse function for ; found"},
+	})
+	assert.Equal(t, c.change(URI, "let x = 1;\nmissing(x) + \"a\" + x"), []lsp.Diagnostic{
+		{Range: span(1, 0, 1, 7), Severity: lsp.SEVERITY_ERROR, Source: "monkey", Message: "identifier not found: missing"},
+	})
+	assert.Equal(t, c.change(URI, "let x = 1;\n\"é\" + x"), []lsp.Diagnostic{
+		{Range: span(1, 4, 1, 5), Severity: lsp.SEVERITY_WARNING, Source: "monkey check", Message: "type mismatch: string + int"},
+	})
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	c.open(URI, SOURCE)
+
+	tests := []struct {
+		line      int
+		character int
+		expected  string
+	}{
+		{1, 14, "```monkey\nsquare: fn(int): int\n```"},
+		{1, 4, "```monkey\ntotal: int\n```"},
+		{3, 24, "```monkey\nname: any\n```"},
+		{1, 25, "```monkey\nlen(value)\n```\nReturns the number of characters in a string or elements in an array."},
+	}
+
+	for _, tt := range tests {
+		var hover lsp.Hover
+		c.request("textDocument/hover", position(URI, tt.line, tt.character), &hover)
+		assert.Equal(t, hover.Contents.Value, tt.expected, "%d:%d", tt.line, tt.character)
+	}
+
+	var hover *lsp.Hover
+	c.request("textDocument/hover", position(URI, 0, 37), &hover)
+	assert.Nil(t, hover)
+}
+
+func TestDefinitionAndReferences(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	c.open(URI, SOURCE)
+
+	var location lsp.Location
+	c.request("textDocument/definition", position(URI, 4, 4), &location)
+	assert.Equal(t, location, lsp.Location{URI: URI, Range: span(3, 6, 3, 13)})
+
+	c.request("textDocument/definition", position(URI, 3, 25), &location)
+	assert.Equal(t, location, lsp.Location{URI: URI, Range: span(2, 15, 2, 19)})
+
+	params := position(URI, 0, 5)
+	params["context"] = map[string]interface{}{"includeDeclaration": true}
+	var locations []lsp.Location
+	c.request("textDocument/references", params, &locations)
+	assert.Equal(t, locations, []lsp.Location{
+		{URI: URI, Range: span(0, 4, 0, 10)},
+		{URI: URI, Range: span(1, 12, 1, 18)},
+	})
+
+	params = position(URI, 0, 16)
+	params["context"] = map[string]interface{}{"includeDeclaration": false}
+	c.request("textDocument/references", params, &locations)
+	assert.Equal(t, locations, []lsp.Location{
+		{URI: URI, Range: span(0, 31, 0, 32)},
+		{URI: URI, Range: span(0, 35, 0, 36)},
+	})
+}
+
+func TestDocumentSymbols(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	c.open(URI, SOURCE)
+
+	var symbols []lsp.DocumentSymbol
+	c.request("textDocument/documentSymbol", map[string]interface{}{"textDocument": map[string]interface{}{"uri": URI}}, &symbols)
+	assert.Equal(t, symbols, []lsp.DocumentSymbol{
+		{Name: "square", Detail: "fn(int): int", Kind: lsp.SYMBOL_FUNCTION, Range: span(0, 0, 0, 38), SelectionRange: span(0, 4, 0, 10)},
+		{Name: "total", Detail: "int", Kind: lsp.SYMBOL_VARIABLE, Range: span(1, 0, 1, 34), SelectionRange: span(1, 4, 1, 9)},
+		{Name: "greet", Detail: "fn(any): any", Kind: lsp.SYMBOL_FUNCTION, Range: span(2, 0, 5, 1), SelectionRange: span(2, 4, 2, 9), Children: []lsp.DocumentSymbol{
+			{Name: "message", Detail: "any", Kind: lsp.SYMBOL_VARIABLE, Range: span(3, 2, 3, 28), SelectionRange: span(3, 6, 3, 13)},
+		}},
+	})
+}
+
+func TestCompletion(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	c.open(URI, SOURCE)
+
+	var items []lsp.CompletionItem
+	c.request("textDocument/completion", position(URI, 4, 2), &items)
+
+	labels := []string{}
+	kinds := map[string]int{}
+	for _, item := range items {
+		labels = append(labels, item.Label)
+		kinds[item.Label] = item.Kind
+	}
+	assert.Equal(t, labels[:5], []string{"name", "message", "square", "total", "greet"})
+	assert.Equal(t, kinds["len"], lsp.COMPLETION_FUNCTION)
+	assert.Equal(t, kinds["PI"], lsp.COMPLETION_CONSTANT)
+	assert.Equal(t, kinds["compose"], lsp.COMPLETION_FUNCTION)
+	assert.Equal(t, kinds["return"], lsp.COMPLETION_KEYWORD)
+
+	// names of other functions are out of scope
+	c.request("textDocument/completion", position(URI, 6, 0), &items)
+	for _, item := range items {
+		assert.NotEqual(t, item.Label, "message")
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	c.open(URI, "let  x=1;\nx+ 2")
+
+	params := map[string]interface{}{"textDocument": map[string]interface{}{"uri": URI}}
+	var edits []lsp.TextEdit
+	c.request("textDocument/formatting", params, &edits)
+	assert.Equal(t, edits, []lsp.TextEdit{{Range: span(0, 0, 1, 4), NewText: "let x = 1;\nx + 2;\n"}})
+
+	c.change(URI, "let x = 1;\nx + 2;\n")
+	c.request("textDocument/formatting", params, &edits)
+	assert.Equal(t, edits, []lsp.TextEdit{})
+
+	c.change(URI, "let x = ;")
+	c.request("textDocument/formatting", params, &edits)
+	assert.Equal(t, edits, []lsp.TextEdit{})
+}
+
+func TestUnknownMethodsAndShutdown(t *testing.T) {
+	c := newClient(t)
+
+	c.nextID++
+	c.send(map[string]interface{}{"id": c.nextID, "method": "workspace/symbol", "params": map[string]interface{}{}})
+	assert.Equal(t, string(c.receive()["error"]), `{"code":-32601,"message":"method not found: workspace/symbol"}`)
+
+	var result interface{}
+	c.request("shutdown", nil, &result)
+	assert.Nil(t, result)
+	c.nextID++
+	c.send(map[string]interface{}{"id": c.nextID, "method": "textDocument/hover", "params": position(URI, 0, 0)})
+	assert.Equal(t, string(c.receive()["error"]), `{"code":-32600,"message":"server is shut down"}`)
+
+	c.notify("exit", nil)
+	assert.NoError(t, <-c.done)
+}
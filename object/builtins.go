@@ -0,0 +1,91 @@
+package object
+
+import (
+	"sort"
+	"strings"
+)
+
+// Builtins is a registry of builtin functions. Names may be namespaced with
+// dots, as in strings.upper, in which case scripts reach them through a
+// Namespace object bound to the prefix.
+type Builtins struct {
+	entries map[string]*Builtin
+}
+
+func NewBuiltins() *Builtins {
+	return &Builtins{entries: make(map[string]*Builtin)}
+}
+
+// Register adds builtins under their names, replacing existing entries.
+func (b *Builtins) Register(builtins ...*Builtin) {
+	for _, builtin := range builtins {
+		b.entries[builtin.Name] = builtin
+	}
+}
+
+// Remove drops the named builtins; removing a namespace removes its members.
+func (b *Builtins) Remove(names ...string) {
+	for _, name := range names {
+		delete(b.entries, name)
+		for entry := range b.entries {
+			if strings.HasPrefix(entry, name+".") {
+				delete(b.entries, entry)
+			}
+		}
+	}
+}
+
+// Lookup resolves name to a builtin, the value of a constant or, when name
+// is a namespace prefix of registered builtins, to a Namespace.
+func (b *Builtins) Lookup(name string) (Object, bool) {
+	if builtin, ok := b.entries[name]; ok {
+		if builtin.Value != nil {
+			return builtin.Value, true
+		}
+		return builtin, true
+	}
+	for entry := range b.entries {
+		if strings.HasPrefix(entry, name+".") {
+			return &Namespace{Name: name, Builtins: b}, true
+		}
+	}
+	return nil, false
+}
+
+func (b *Builtins) Get(name string) (*Builtin, bool) {
+	builtin, ok := b.entries[name]
+	return builtin, ok
+}
+
+// Names returns the fully qualified names of all builtins, sorted.
+func (b *Builtins) Names() []string {
+	names := make([]string, 0, len(b.entries))
+	for name := range b.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clone returns a registry with the same entries that can be changed
+// independently.
+func (b *Builtins) Clone() *Builtins {
+	clone := NewBuiltins()
+	for name, builtin := range b.entries {
+		clone.entries[name] = builtin
+	}
+	return clone
+}
+
+type Namespace struct {
+	Name     string
+	Builtins *Builtins
+}
+
+func (ns *Namespace) Type() ObjectType { return NAMESPACE_OBJ }
+func (ns *Namespace) Inspect() string  { return "namespace " + ns.Name }
+
+// Member resolves a builtin or nested namespace below ns.
+func (ns *Namespace) Member(name string) (Object, bool) {
+	return ns.Builtins.Lookup(ns.Name + "." + name)
+}
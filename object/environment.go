@@ -1,8 +1,12 @@
 package object
 
+import "sort"
+
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store   map[string]Object
+	outer   *Environment
+	runtime *Runtime
+	file    string // source file the environment's code was loaded from
 }
 
 func NewEnvironment() *Environment {
@@ -13,9 +17,48 @@ func NewEnvironment() *Environment {
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	env.runtime = outer.runtime
+	env.file = outer.file
+	return env
+}
+
+// NewRuntimeEnvironment creates a root environment bound to runtime.
+func NewRuntimeEnvironment(runtime *Runtime) *Environment {
+	env := NewEnvironment()
+	env.runtime = runtime
 	return env
 }
 
+// Names returns the names bound in e itself, not in its outer environments,
+// in sorted order.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Outer returns the environment enclosing e, or nil for a root environment.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+func (e *Environment) Runtime() *Runtime {
+	return e.runtime
+}
+
+// File returns the path of the source file whose code runs in this
+// environment, or "" when the code did not come from a file.
+func (e *Environment) File() string {
+	return e.file
+}
+
+func (e *Environment) SetFile(path string) {
+	e.file = path
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
 	obj, ok := e.store[name]
 	if !ok && e.outer != nil {
@@ -24,6 +67,18 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// Resolve is like Get but also returns the environment that binds name, or
+// nil when name is unbound.
+func (e *Environment) Resolve(name string) (Object, *Environment) {
+	if obj, ok := e.store[name]; ok {
+		return obj, e
+	}
+	if e.outer != nil {
+		return e.outer.Resolve(name)
+	}
+	return nil, nil
+}
+
 func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
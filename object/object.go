@@ -3,13 +3,19 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"math/big"
 	"monkey/ast"
+	"monkey/token"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
 type ObjectType string
 
-type BuiltinFunction func(args ...Object) Object
+type BuiltinFunction func(env *Environment, args ...Object) Object
 
 const (
 	INTEGER_OBJ      = "INTEGER"
@@ -21,6 +27,34 @@ const (
 	FUNCTION_OBJ     = "FUNCTION"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
+	ERROR_VALUE_OBJ  = "ERROR_VALUE"
+	FLOAT_OBJ        = "FLOAT"
+	HASH_OBJ         = "HASH"
+	NAMESPACE_OBJ    = "NAMESPACE"
+	MODULE_OBJ       = "MODULE"
+	REGEX_OBJ        = "REGEX"
+	BIGINT_OBJ       = "BIGINT"
+)
+
+// ErrorKind classifies an Error. It implements the error interface so hosts
+// can match a kind with errors.Is(err, object.TYPE_ERROR).
+type ErrorKind string
+
+func (k ErrorKind) Error() string { return string(k) }
+
+const (
+	RUNTIME_ERROR ErrorKind = "RuntimeError"
+	TYPE_ERROR    ErrorKind = "TypeError"
+	NAME_ERROR    ErrorKind = "NameError"
+	INDEX_ERROR   ErrorKind = "IndexError"
+	ARITY_ERROR   ErrorKind = "ArityError"
+	ZERO_DIVISION ErrorKind = "ZeroDivision"
+	USER_ERROR    ErrorKind = "UserError"
+	TIMEOUT       ErrorKind = "Timeout"
+	LIMIT_ERROR   ErrorKind = "LimitError"
+	IMPORT_ERROR  ErrorKind = "ImportError"
+	VALUE_ERROR   ErrorKind = "ValueError"
+	INTERRUPTED   ErrorKind = "Interrupted" // stopped by the runtime's Debugger
 )
 
 type Object interface {
@@ -28,12 +62,54 @@ type Object interface {
 	Inspect() string
 }
 
+// StackFrame records a function the error unwound through and the
+// position of the call that entered it.
+type StackFrame struct {
+	Function string
+	Position token.Position
+}
+
+func (f StackFrame) String() string {
+	if f.Position.Line == 0 {
+		return "at " + f.Function
+	}
+	return fmt.Sprintf("at %s (%s)", f.Function, f.Position)
+}
+
 type Error struct {
+	Kind    ErrorKind
 	Message string
+	Cause   error        // error this one was raised from, if any
+	Stack   []StackFrame // innermost frame first
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string  { return "ERROR: " + e.Error() }
+
+func (e *Error) Error() string { return string(e.Kind) + ": " + e.Message }
+func (e *Error) Unwrap() error { return e.Cause }
+
+func (e *Error) Is(target error) bool {
+	kind, ok := target.(ErrorKind)
+	return ok && e.Kind == kind
+}
+
+func (e *Error) StackTrace() string {
+	var out bytes.Buffer
+	for _, frame := range e.Stack {
+		out.WriteString("\t" + frame.String() + "\n")
+	}
+	return out.String()
+}
+
+// ErrorValue is an error that has been caught or created by a script. Unlike
+// Error it does not propagate, so it can be bound, passed around and thrown.
+type ErrorValue struct {
+	Error *Error
+}
+
+func (ev *ErrorValue) Type() ObjectType { return ERROR_VALUE_OBJ }
+func (ev *ErrorValue) Inspect() string  { return ev.Error.Error() }
 
 type Integer struct {
 	Value int64
@@ -47,6 +123,47 @@ func (i *Integer) Type() ObjectType {
 	return INTEGER_OBJ
 }
 
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+// BigInt is an integer outside the range of int64. Integer arithmetic
+// promotes to it on overflow, and results that fit again are demoted back to
+// Integer by IntegerFromBig, so a BigInt never holds a value an Integer could.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (i *BigInt) Type() ObjectType { return BIGINT_OBJ }
+func (i *BigInt) Inspect() string  { return i.Value.String() }
+func (i *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write(i.Value.Bytes())
+	return HashKey{Type: i.Type(), Value: h.Sum64() ^ uint64(i.Value.Sign())}
+}
+
+// IntegerFromBig returns value as an Integer when it fits in int64 and as a
+// BigInt otherwise. The BigInt takes ownership of value.
+func IntegerFromBig(value *big.Int) Object {
+	if value.IsInt64() {
+		return &Integer{Value: value.Int64()}
+	}
+	return &BigInt{Value: value}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	literal := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(literal, ".eIN") {
+		literal += ".0"
+	}
+	return literal
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -59,6 +176,14 @@ func (b *Boolean) Inspect() string {
 	return fmt.Sprintf("%t", b.Value)
 }
 
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
 type String struct {
 	Value string
 }
@@ -66,6 +191,12 @@ type String struct {
 func (s *String) Type() ObjectType { return STRING_OBJ }
 func (s *String) Inspect() string  { return s.Value }
 
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
 type Null struct{}
 
 func (n *Null) Type() ObjectType {
@@ -84,6 +215,7 @@ func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Function struct {
+	Name       string
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
@@ -109,12 +241,38 @@ func (f *Function) Inspect() string {
 }
 
 type Builtin struct {
-	Fn BuiltinFunction
+	Name string
+	// Params names the parameters for documentation and arity checks. A
+	// trailing "?" marks an optional parameter and a trailing "..." a
+	// variadic one. Builtins without Params validate arguments themselves.
+	Params []string
+	Doc    string
+	Fn     BuiltinFunction
+	// Value, when set, is what the name evaluates to instead of the builtin
+	// itself. It registers constants such as PI.
+	Value Object
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return "builtin function" }
 
+// Arity returns the minimum and maximum number of arguments accepted by the
+// builtin according to Params. max is -1 for variadic builtins.
+func (b *Builtin) Arity() (min int, max int) {
+	for _, param := range b.Params {
+		switch {
+		case strings.HasSuffix(param, "..."):
+			return min, -1
+		case strings.HasSuffix(param, "?"):
+			max++
+		default:
+			min++
+			max++
+		}
+	}
+	return min, max
+}
+
 type Array struct {
 	Elements []Object
 }
@@ -134,3 +292,75 @@ func (arr *Array) Inspect() string {
 
 	return out.String()
 }
+
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by objects that can be used as hash keys.
+type Hashable interface {
+	HashKey() HashKey
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs map[HashKey]HashPair
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+	sort.Strings(pairs)
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, ok := h.Pairs[key.HashKey()]
+	return pair.Value, ok
+}
+
+// Set binds value to key, which must implement Hashable.
+func (h *Hash) Set(key Object, value Object) {
+	h.Pairs[key.(Hashable).HashKey()] = HashPair{Key: key, Value: value}
+}
+
+// Module is a loaded source file. Only bindings declared with export are
+// visible to importers.
+type Module struct {
+	Name    string
+	Path    string
+	Exports map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "module " + m.Name }
+
+// Regex is a compiled regular expression. Flags are the letters that followed
+// the pattern in a /pattern/flags literal.
+type Regex struct {
+	Pattern string
+	Flags   string
+	Regexp  *regexp.Regexp
+}
+
+func (r *Regex) Type() ObjectType { return REGEX_OBJ }
+func (r *Regex) Inspect() string  { return "/" + r.Pattern + "/" + r.Flags }
@@ -0,0 +1,59 @@
+package object
+
+import (
+	"context"
+	"io"
+	"monkey/ast"
+	"monkey/token"
+)
+
+// Runtime holds the per-interpreter state shared by an environment and every
+// environment enclosed by it.
+type Runtime struct {
+	Context  context.Context // checked before every statement, may be nil
+	Output   io.Writer       // destination of print, may be nil for stdout
+	Builtins *Builtins       // builtins visible to scripts, may be nil for the defaults
+	MaxDepth int             // maximum call depth, 0 for unlimited
+	MaxSteps int             // maximum statements per run, 0 for unlimited
+	Optimize bool            // optimize programs and modules before evaluating them
+	Debugger Debugger        // notified before every statement, may be nil
+	Tracer   Tracer          // notified of statements, calls and errors, may be nil
+
+	// Prelude holds the standard library bindings. Module environments
+	// enclose it so imported files see the same helpers as the main program.
+	Prelude *Environment
+
+	// ModulePath lists directories searched for imports that are not found
+	// next to the importing file. MONKEYPATH is used when it is nil.
+	ModulePath []string
+	Modules    map[string]*Module // loaded modules by absolute path
+	Importing  []string           // modules being loaded, outermost first
+
+	Depth int // current call depth
+	Steps int // statements evaluated so far
+
+	// Calls lists the function calls in progress, outermost first. It is
+	// only tracked while a Debugger is attached.
+	Calls []StackFrame
+
+	// Raised is the error last reported to the Tracer, which is reported
+	// by the statement that raised it but not by those it unwinds through.
+	Raised *Error
+}
+
+// Debugger is called by the evaluator before each statement runs, with the
+// environment the statement runs in. Returning an error stops the run.
+type Debugger interface {
+	Before(stmt ast.Statement, env *Environment) error
+}
+
+// Tracer is called by the evaluator as a program runs. Enter and Exit are
+// called around every call of a *Function or *Builtin; the result passed to
+// Exit is an *Error when the call failed. Error is called once for each
+// error, with the innermost statement it was raised in.
+type Tracer interface {
+	Statement(stmt ast.Statement, env *Environment)
+	Enter(fn Object, args []Object, call token.Position)
+	Exit(fn Object, result Object)
+	Error(err *Error, stmt ast.Statement)
+}
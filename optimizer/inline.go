@@ -0,0 +1,158 @@
+package optimizer
+
+import (
+	"monkey/ast"
+	"monkey/token"
+	"path/filepath"
+	"strings"
+)
+
+// constant is a let in a function body that binds a literal.
+type constant struct {
+	value ast.Expression
+	end   token.Position // where the let ends; only later uses see the value
+}
+
+// inlineConstants replaces the uses of the constants of fn with their value.
+// A constant is a let that is a statement of the body itself, so it always
+// runs before the code after it, that binds a literal to a name declared
+// nowhere else in the function's scope.
+func inlineConstants(fn *ast.FunctionLiteral) {
+	declarations := map[string]int{}
+	for _, param := range fn.Parameters {
+		declarations[param.Value]++
+	}
+	countDeclarations(fn.Body.Statements, declarations)
+
+	constants := map[int]constant{}
+	for _, stmt := range fn.Body.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || let.Name.Binding == nil || !isLiteral(let.Value) || declarations[let.Name.Value] != 1 {
+			continue
+		}
+		constants[let.Name.Binding.Slot] = constant{value: let.Value, end: ast.SpanOf(let).End}
+	}
+	if len(constants) == 0 {
+		return
+	}
+
+	uses := map[*ast.Identifier]ast.Expression{}
+	ast.Walk(&usesFinder{constants: constants, uses: uses}, fn.Body)
+	ast.Modify(fn.Body, func(node ast.Node) ast.Node {
+		if ident, ok := node.(*ast.Identifier); ok {
+			if value, ok := uses[ident]; ok {
+				return copyLiteral(value, ident.Token.Position)
+			}
+		}
+		return node
+	})
+}
+
+// countDeclarations counts the names bound by stmts in their own scope,
+// leaving out nested functions and catch blocks, which have scopes of their
+// own.
+func countDeclarations(stmts []ast.Statement, declarations map[string]int) {
+	var count func(node ast.Node) bool
+	count = func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			declarations[node.Name.Value]++
+		case *ast.ImportStatement:
+			if node.Names == nil {
+				path := node.Path.Value
+				declarations[strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))]++
+			}
+			for _, name := range node.Names {
+				declarations[name.Value]++
+			}
+			return false
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.TryExpression:
+			ast.Inspect(node.Block, count)
+			if node.FinallyBlock != nil {
+				ast.Inspect(node.FinallyBlock, count)
+			}
+			return false
+		}
+		return true
+	}
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, count)
+	}
+}
+
+// usesFinder collects the identifiers referring to constants, counting the
+// scopes entered below the function body in depth to match the bindings set
+// by the resolver.
+type usesFinder struct {
+	depth     int
+	constants map[int]constant
+	uses      map[*ast.Identifier]ast.Expression
+}
+
+func (f *usesFinder) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		if node.Binding == nil || node.Binding.Depth != f.depth {
+			return nil
+		}
+		if constant, ok := f.constants[node.Binding.Slot]; ok && after(node.Token.Position, constant.end) {
+			f.uses[node] = constant.value
+		}
+	case *ast.LetStatement:
+		// the name is a declaration, not a use
+		if node.Value != nil {
+			ast.Walk(f, node.Value)
+		}
+		return nil
+	case *ast.ImportStatement:
+		return nil
+	case *ast.MemberExpression:
+		ast.Walk(f, node.Object)
+		return nil
+	case *ast.FunctionLiteral:
+		ast.Walk(f.enter(), node.Body)
+		return nil
+	case *ast.TryExpression:
+		ast.Walk(f, node.Block)
+		if node.CatchBlock != nil {
+			ast.Walk(f.enter(), node.CatchBlock)
+		}
+		if node.FinallyBlock != nil {
+			ast.Walk(f, node.FinallyBlock)
+		}
+		return nil
+	}
+	return f
+}
+
+func (f *usesFinder) enter() *usesFinder {
+	return &usesFinder{depth: f.depth + 1, constants: f.constants, uses: f.uses}
+}
+
+func after(position token.Position, end token.Position) bool {
+	return position.Line > end.Line || position.Line == end.Line && position.Column >= end.Column
+}
+
+func copyLiteral(value ast.Expression, position token.Position) ast.Expression {
+	switch value := value.(type) {
+	case *ast.IntegerLiteral:
+		literal := *value
+		literal.Token.Position = position
+		return &literal
+	case *ast.FloatLiteral:
+		literal := *value
+		literal.Token.Position = position
+		return &literal
+	case *ast.StringLiteral:
+		literal := *value
+		literal.Token.Position = position
+		return &literal
+	case *ast.BooleanLiteral:
+		literal := *value
+		literal.Token.Position = position
+		return &literal
+	}
+	return value
+}
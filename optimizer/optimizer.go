@@ -0,0 +1,303 @@
+// Package optimizer rewrites Monkey programs into equivalent ones that do
+// less work when evaluated.
+//
+// Optimize folds operations on literals into a single literal, drops the
+// branches of if expressions whose condition is a literal, and replaces the
+// uses of a let that binds a literal once in a function body with the
+// literal itself. Operations that would fail, such as a division by zero,
+// are left in place so the evaluator reports them where they are written.
+package optimizer
+
+import (
+	"math"
+	"math/big"
+	"monkey/ast"
+	"monkey/token"
+	"strconv"
+	"strings"
+)
+
+// Optimize rewrites program in place and returns it. Inlining relies on the
+// bindings set by the resolver, so programs that have not been resolved are
+// only folded.
+func Optimize(program *ast.Program) *ast.Program {
+	ast.Modify(program, fold)
+	ast.Inspect(program, func(node ast.Node) bool {
+		if fn, ok := node.(*ast.FunctionLiteral); ok {
+			inlineConstants(fn)
+		}
+		return true
+	})
+	// inlined constants may make more expressions foldable
+	ast.Modify(program, fold)
+	return program
+}
+
+func fold(node ast.Node) ast.Node {
+	switch node := node.(type) {
+	case *ast.PrefixExpression:
+		if folded := foldPrefix(node); folded != nil {
+			return folded
+		}
+	case *ast.InfixExpression:
+		if folded := foldInfix(node); folded != nil {
+			return folded
+		}
+	case *ast.IfExpression:
+		// an if whose taken branch is a single expression has its value
+		if truthy, ok := isTruthy(node.Condition); ok {
+			branch := node.ElseBranch
+			if truthy {
+				branch = node.ThenBranch
+			}
+			if branch != nil && len(branch.Statements) == 1 {
+				if stmt, ok := branch.Statements[0].(*ast.ExpressionStatement); ok && stmt.Expression != nil {
+					return stmt.Expression
+				}
+			}
+		}
+	case *ast.BlockStatement:
+		node.Statements = pruneBranches(node.Statements)
+	case *ast.Program:
+		node.Statements = pruneBranches(node.Statements)
+	}
+	return node
+}
+
+// pruneBranches replaces the if statements of stmts whose condition is a
+// literal with the statements of the branch taken. Branches share the
+// environment of their block, so their lets stay visible after them.
+func pruneBranches(stmts []ast.Statement) []ast.Statement {
+	pruned := []ast.Statement{}
+	for i, stmt := range stmts {
+		expr, ok := stmt.(*ast.ExpressionStatement)
+		if !ok {
+			pruned = append(pruned, stmt)
+			continue
+		}
+		ifExpr, ok := expr.Expression.(*ast.IfExpression)
+		if !ok {
+			pruned = append(pruned, stmt)
+			continue
+		}
+		truthy, ok := isTruthy(ifExpr.Condition)
+		if !ok {
+			pruned = append(pruned, stmt)
+			continue
+		}
+
+		branch := ifExpr.ElseBranch
+		if truthy {
+			branch = ifExpr.ThenBranch
+		}
+		if branch == nil || len(branch.Statements) == 0 {
+			// the value of the last statement is the value of the block
+			if i == len(stmts)-1 {
+				pruned = append(pruned, stmt)
+			}
+			continue
+		}
+		pruned = append(pruned, branch.Statements...)
+	}
+	return pruned
+}
+
+// isTruthy reports whether a literal condition holds, following the
+// evaluator: false, 0 and 0.0 are false and every other literal is true.
+func isTruthy(expr ast.Expression) (truthy bool, ok bool) {
+	switch expr := expr.(type) {
+	case *ast.BooleanLiteral:
+		return expr.Value, true
+	case *ast.IntegerLiteral:
+		return expr.Big != nil || expr.Value != 0, true
+	case *ast.FloatLiteral:
+		return expr.Value != 0, true
+	case *ast.StringLiteral:
+		return true, true
+	}
+	return false, false
+}
+
+func isLiteral(expr ast.Expression) bool {
+	_, ok := isTruthy(expr)
+	return ok
+}
+
+func foldPrefix(expr *ast.PrefixExpression) ast.Expression {
+	position := ast.SpanOf(expr).Start
+	switch expr.Operator {
+	case "!":
+		// the bang operator only negates booleans and null; any other value
+		// gives false
+		switch right := expr.Right.(type) {
+		case *ast.BooleanLiteral:
+			return booleanLiteral(!right.Value, position)
+		case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral:
+			return booleanLiteral(false, position)
+		}
+	case "-":
+		switch right := expr.Right.(type) {
+		case *ast.IntegerLiteral:
+			return integerLiteral(new(big.Int).Neg(integerValue(right)), position)
+		case *ast.FloatLiteral:
+			return floatLiteral(-right.Value, position)
+		}
+	}
+	return nil
+}
+
+// foldInfix computes operations on two literals the way the evaluator does,
+// returning nil for those it would reject or that give no literal.
+func foldInfix(expr *ast.InfixExpression) ast.Expression {
+	if !isLiteral(expr.Left) || !isLiteral(expr.Right) {
+		return nil
+	}
+	position := ast.SpanOf(expr).Start
+
+	left, leftInt := expr.Left.(*ast.IntegerLiteral)
+	right, rightInt := expr.Right.(*ast.IntegerLiteral)
+	if leftInt && rightInt {
+		return foldIntegers(expr.Operator, integerValue(left), integerValue(right), position)
+	}
+
+	leftFloat, leftNumber := numberValue(expr.Left)
+	rightFloat, rightNumber := numberValue(expr.Right)
+	if leftNumber && rightNumber {
+		return foldFloats(expr.Operator, leftFloat, rightFloat, position)
+	}
+
+	if left, ok := expr.Left.(*ast.StringLiteral); ok {
+		if right, ok := expr.Right.(*ast.StringLiteral); ok {
+			return foldStrings(expr.Operator, left.Value, right.Value, position)
+		}
+	}
+
+	if left, ok := expr.Left.(*ast.BooleanLiteral); ok {
+		if right, ok := expr.Right.(*ast.BooleanLiteral); ok {
+			switch expr.Operator {
+			case "==":
+				return booleanLiteral(left.Value == right.Value, position)
+			case "!=":
+				return booleanLiteral(left.Value != right.Value, position)
+			}
+		}
+	}
+	return nil
+}
+
+func foldIntegers(operator string, left *big.Int, right *big.Int, position token.Position) ast.Expression {
+	switch operator {
+	case "+":
+		return integerLiteral(new(big.Int).Add(left, right), position)
+	case "-":
+		return integerLiteral(new(big.Int).Sub(left, right), position)
+	case "*":
+		return integerLiteral(new(big.Int).Mul(left, right), position)
+	case "/":
+		if right.Sign() == 0 {
+			return nil
+		}
+		return integerLiteral(new(big.Int).Quo(left, right), position)
+	case "<":
+		return booleanLiteral(left.Cmp(right) < 0, position)
+	case ">":
+		return booleanLiteral(left.Cmp(right) > 0, position)
+	case "==":
+		return booleanLiteral(left.Cmp(right) == 0, position)
+	case "!=":
+		return booleanLiteral(left.Cmp(right) != 0, position)
+	}
+	return nil
+}
+
+func foldFloats(operator string, left float64, right float64, position token.Position) ast.Expression {
+	switch operator {
+	case "+":
+		return floatLiteral(left+right, position)
+	case "-":
+		return floatLiteral(left-right, position)
+	case "*":
+		return floatLiteral(left*right, position)
+	case "/":
+		if right == 0 {
+			return nil
+		}
+		return floatLiteral(left/right, position)
+	case "<":
+		return booleanLiteral(left < right, position)
+	case ">":
+		return booleanLiteral(left > right, position)
+	case "==":
+		return booleanLiteral(left == right, position)
+	case "!=":
+		return booleanLiteral(left != right, position)
+	}
+	return nil
+}
+
+func foldStrings(operator string, left string, right string, position token.Position) ast.Expression {
+	switch operator {
+	case "+":
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: left + right, Position: position}, Value: left + right}
+	case "<":
+		return booleanLiteral(left < right, position)
+	case ">":
+		return booleanLiteral(left > right, position)
+	case "==":
+		return booleanLiteral(left == right, position)
+	case "!=":
+		return booleanLiteral(left != right, position)
+	}
+	return nil
+}
+
+func integerValue(literal *ast.IntegerLiteral) *big.Int {
+	if literal.Big != nil {
+		return literal.Big
+	}
+	return big.NewInt(literal.Value)
+}
+
+// numberValue converts an integer or float literal to a float64 like the
+// evaluator does when one operand of an operation is a float.
+func numberValue(expr ast.Expression) (float64, bool) {
+	switch expr := expr.(type) {
+	case *ast.IntegerLiteral:
+		if expr.Big != nil {
+			value, _ := new(big.Float).SetInt(expr.Big).Float64()
+			return value, true
+		}
+		return float64(expr.Value), true
+	case *ast.FloatLiteral:
+		return expr.Value, true
+	}
+	return 0, false
+}
+
+func integerLiteral(value *big.Int, position token.Position) ast.Expression {
+	tok := token.Token{Type: token.INT, Literal: value.String(), Position: position}
+	if value.IsInt64() {
+		return &ast.IntegerLiteral{Token: tok, Value: value.Int64()}
+	}
+	return &ast.IntegerLiteral{Token: tok, Big: value}
+}
+
+// floatLiteral returns nil for infinities and NaN, which have no literal.
+func floatLiteral(value float64, position token.Position) ast.Expression {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return nil
+	}
+	literal := strconv.FormatFloat(value, 'g', -1, 64)
+	if !strings.ContainsAny(literal, ".e") {
+		literal += ".0"
+	}
+	return &ast.FloatLiteral{Token: token.Token{Type: token.FLOAT, Literal: literal, Position: position}, Value: value}
+}
+
+func booleanLiteral(value bool, position token.Position) ast.Expression {
+	tok := token.Token{Type: token.FALSE, Literal: "false", Position: position}
+	if value {
+		tok = token.Token{Type: token.TRUE, Literal: "true", Position: position}
+	}
+	return &ast.BooleanLiteral{Token: tok, Value: value}
+}
@@ -0,0 +1,115 @@
+package optimizer_test
+
+import (
+	"bytes"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/optimizer"
+	"monkey/parser"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %v", p.Errors())
+	}
+	return program
+}
+
+// run evaluates input in a fresh environment and describes its result, its
+// output and the stack of the error it fails with, if any.
+func run(t *testing.T, input string, optimize bool) string {
+	var out bytes.Buffer
+	env := object.NewRuntimeEnvironment(&object.Runtime{Output: &out})
+
+	program := parse(t, input)
+	if err := evaluator.Resolve(program, env); err != nil {
+		t.Fatalf("resolve error: %s", err.Inspect())
+	}
+	if optimize {
+		optimizer.Optimize(program)
+	}
+
+	result := evaluator.Eval(program, env)
+	description := out.String()
+	if result != nil {
+		description += result.Inspect()
+	}
+	if err, ok := result.(*object.Error); ok {
+		description += "\n" + err.StackTrace()
+	}
+	return description
+}
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"60 * 60 * 24", "86400"},
+		{`"a" + "b" + "c"`, "abc"},
+		{"!true; !0; -(2 - 5)", "falsefalse3"},
+		{"1 + 2.5; 7 / 2; 7.0 / 2", "3.533.5"},
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"1 < 2 == true; \"a\" > \"b\"", "truefalse"},
+		{"let x = 1; x + 2 * 3", "let x = 1;(x + 6)"},
+		{"1 / 0; 1 + \"a\"; -\"a\"", "(1 / 0)(1 + a)(-a)"},
+		{"if (1 > 2) { 1 } else { 2 }", "2"},
+		{"let x = if (true) { 1 };", "let x = 1;"},
+		{"if (false) { print(1); } print(2)", "print(2)"},
+		{"if (true) { let y = 2; print(y); } y", "let y = 2;print(y)y"},
+		{"if (false) { 1 }", "iffalse 1"},
+		{"let f = fn() { let day = 60 * 60 * 24; day * 7 }", "let f = fn() let day = 86400;604800;"},
+		{"let f = fn(n) { let s = \"x\"; fn() { s + n } }", "let f = fn(n) let s = x;fn() (x + n);"},
+		{"let f = fn() { let k = 1; let k = 2; k }", "let f = fn() let k = 1;let k = 2;k;"},
+		{"let f = fn(k) { let k = 1; k }", "let f = fn(k) let k = 1;k;"},
+		{"let f = fn(c) { if (c) { let k = 1; }; k }", "let f = fn(c) ifc let k = 1;k;"},
+		{"let f = fn() { let g = fn() { k }; let k = 1; g() + k }", "let f = fn() let g = fn() k;let k = 1;(g() + 1);"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+		evaluator.Resolve(program, object.NewEnvironment())
+		assert.Equal(t, tt.expected, optimizer.Optimize(program).String(), tt.input)
+	}
+}
+
+func TestOptimizedProgramsBehaveTheSame(t *testing.T) {
+	inputs := []string{
+		"60 * 60 * 24 + 1 - 2 / 3",
+		"9223372036854775807 * 3 - 9223372036854775807 * 2",
+		"-9223372036854775808; -(-9223372036854775807 - 1)",
+		"1.5 * 2 + 3 / 2.0 - 0.1",
+		"1e308 * 10",
+		"0.1 + 0.2 == 0.3; 2 == 2.0; 1 < 1.5",
+		`"con" + "cat" == "concat"; "a" < "b"`,
+		"!5; !!0; !\"\"; -(-2.5); true == !false; true != false",
+		"1 == \"1\"; true == 1",
+		"let f = fn() { 1 / 0 }; f()",
+		"let f = fn() { 1 + 2 + \"a\" }; f()",
+		"let f = fn() { -\"a\" }; f()",
+		"let f = fn() { true + false }; f()",
+		"if (0) { 1 } else { 2 }; if (0.0) { 1 }",
+		"if (\"\") { print(\"empty strings are truthy\") }",
+		"let f = fn() { if (1 > 2) { return 1; } 2 }; f()",
+		"let f = fn() { if (true) { return 1; } 2 }; f()",
+		"let f = fn() { if (false) { 1 } }; f()",
+		"let f = fn() { let x = 2; let g = fn() { x * x }; g() + x }; f()",
+		"let f = fn(n) { let limit = 10; if (n > limit) { limit } else { n } }; [f(3), f(30)]",
+		"let f = fn() { let s = \"a\"; try { s + 1 } catch (e) { s + e.message } }; f()",
+		"let f = fn() { let g = fn() { k }; let k = 1; g() + k }; f()",
+		"let k = 5; let f = fn() { let g = fn() { k }; let r = g(); let k = 1; r + k }; f()",
+		"let x = 1; let f = fn() { x }; let x = 2; f()",
+		"let f = fn() { let big = 9223372036854775807 * 2; big / 2 }; f()",
+	}
+
+	for _, input := range inputs {
+		assert.Equal(t, run(t, input, false), run(t, input, true), input)
+	}
+}
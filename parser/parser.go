@@ -1,11 +1,14 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"monkey/ast"
 	"monkey/lexer"
 	"monkey/token"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -31,6 +34,16 @@ var precedences = map[token.TokenType]int{
 	token.STAR:         PRODUCT,
 	token.LPAREN:       CALL,
 	token.LBRACKET:     INDEX,
+	token.DOT:          INDEX,
+}
+
+// Precedence returns the binding power of an infix operator, or LOWEST for
+// other tokens.
+func Precedence(operator token.TokenType) int {
+	if precedence, ok := precedences[operator]; ok {
+		return precedence
+	}
+	return LOWEST
 }
 
 type (
@@ -42,28 +55,34 @@ type Parser struct {
 	lexer        *lexer.Lexer
 	currentToken token.Token
 	peekToken    token.Token
-	errors       []string
+	errors       []*Error
+	blockDepth   int // nesting of the block statements being parsed
 
 	prefixParseFunctions map[token.TokenType]PrefixParseFunction
 	infixParseFunctions  map[token.TokenType]InfixParseFunction
 }
 
 func New(lexer *lexer.Lexer) *Parser {
-	parser := &Parser{lexer: lexer, errors: []string{}}
+	parser := &Parser{lexer: lexer, errors: []*Error{}}
 
 	// register prefix parsing functions
 	parser.prefixParseFunctions = make(map[token.TokenType]PrefixParseFunction)
 	parser.registerPrefix(token.IDENTIFIER, parser.parseIdentifier)
 	parser.registerPrefix(token.INT, parser.parseIntegerLiteral)
+	parser.registerPrefix(token.FLOAT, parser.parseFloatLiteral)
 	parser.registerPrefix(token.BANG, parser.parsePrefixExpression)
 	parser.registerPrefix(token.MINUS, parser.parsePrefixExpression)
 	parser.registerPrefix(token.TRUE, parser.parseBooleanLiteral)
 	parser.registerPrefix(token.FALSE, parser.parseBooleanLiteral)
 	parser.registerPrefix(token.STRING, parser.parseStringLiteral)
+	parser.registerPrefix(token.REGEX, parser.parseRegexLiteral)
 	parser.registerPrefix(token.LPAREN, parser.parseGroupedExpression)
 	parser.registerPrefix(token.IF, parser.parseIfExpression)
 	parser.registerPrefix(token.FUNCTION, parser.parseFunctionLiteral)
 	parser.registerPrefix(token.LBRACKET, parser.parseArrayLiteral)
+	parser.registerPrefix(token.TRY, parser.parseTryExpression)
+	parser.registerPrefix(token.LBRACE, parser.parseHashLiteral)
+	parser.registerPrefix(token.ILLEGAL, parser.parseIllegal)
 
 	// register infix parsing functions
 	parser.infixParseFunctions = make(map[token.TokenType]InfixParseFunction)
@@ -77,6 +96,7 @@ func New(lexer *lexer.Lexer) *Parser {
 	parser.registerInfix(token.GREATER_THAN, parser.parseInfixExpression)
 	parser.registerInfix(token.LPAREN, parser.parseCallExpression)
 	parser.registerInfix(token.LBRACKET, parser.parseIndexExpression)
+	parser.registerInfix(token.DOT, parser.parseMemberExpression)
 
 	// read two tokens to currentToken and nextToken are both set
 	parser.nextToken()
@@ -92,11 +112,40 @@ func (parser *Parser) ParseProgram() *ast.Program {
 		program.Statements = append(program.Statements, stmt)
 		parser.nextToken()
 	}
+	for _, comment := range parser.lexer.Comments() {
+		program.Comments = append(program.Comments, &ast.Comment{Token: comment})
+	}
 	return program
 }
 
+// Error is a syntax error with the position of the token it was found at.
+type Error struct {
+	Position token.Position
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position, e.Message)
+}
+
+// Errors returns the problems reported by the lexer followed by those found
+// while parsing.
 func (parser *Parser) Errors() []string {
-	return parser.errors
+	messages := []string{}
+	for _, err := range parser.ErrorList() {
+		messages = append(messages, err.Message)
+	}
+	return messages
+}
+
+// ErrorList returns the same problems as Errors along with their positions.
+func (parser *Parser) ErrorList() []*Error {
+	list := []*Error{}
+	positions := parser.lexer.ErrorPositions()
+	for i, msg := range parser.lexer.Errors() {
+		list = append(list, &Error{Position: positions[i], Message: msg})
+	}
+	return append(list, parser.errors...)
 }
 
 func (parser *Parser) nextToken() {
@@ -110,6 +159,10 @@ func (parser *Parser) parseStatement() ast.Statement {
 		return parser.parseLetStatement()
 	case token.RETURN:
 		return parser.parseReturnStatement()
+	case token.IMPORT:
+		return parser.parseImportStatement()
+	case token.EXPORT:
+		return parser.parseExportStatement()
 	default:
 		return parser.parseExpressionStatement()
 	}
@@ -119,6 +172,7 @@ func (parser *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: parser.currentToken}
 	block.Statements = []ast.Statement{}
 
+	parser.blockDepth++
 	parser.nextToken()
 
 	for !parser.currentTokenIs(token.RBRACE) && !parser.currentTokenIs(token.EOF) {
@@ -126,6 +180,8 @@ func (parser *Parser) parseBlockStatement() *ast.BlockStatement {
 		block.Statements = append(block.Statements, stmt)
 		parser.nextToken()
 	}
+	block.Rbrace = parser.currentToken.Position
+	parser.blockDepth--
 
 	return block
 }
@@ -140,6 +196,15 @@ func (parser *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Name = &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Literal}
 
+	if parser.peekTokenIs(token.COLON) {
+		parser.nextToken()
+		parser.nextToken()
+		stmt.Type = parser.parseTypeAnnotation()
+		if stmt.Type == nil {
+			return nil
+		}
+	}
+
 	if !parser.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -148,6 +213,64 @@ func (parser *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = parser.parseExpression(LOWEST)
 
+	if function, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		function.Name = stmt.Name.Value
+	}
+
+	if parser.peekTokenIs(token.SEMICOLON) {
+		parser.nextToken()
+	}
+
+	return stmt
+}
+
+func (parser *Parser) parseExportStatement() ast.Statement {
+	// only the top-level bindings of a module are collected as its exports
+	if parser.blockDepth > 0 {
+		parser.errorf(parser.currentToken.Position, "export is only allowed at the top level of a module")
+	}
+	if !parser.expectPeek(token.LET) {
+		return nil
+	}
+
+	stmt := parser.parseLetStatement()
+	if stmt == nil {
+		return nil
+	}
+	stmt.Exported = true
+	return stmt
+}
+
+func (parser *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: parser.currentToken}
+
+	if parser.peekTokenIs(token.LBRACE) {
+		parser.nextToken()
+		stmt.Names = []*ast.Identifier{}
+		for !parser.peekTokenIs(token.RBRACE) {
+			if !parser.expectPeek(token.IDENTIFIER) {
+				return nil
+			}
+			stmt.Names = append(stmt.Names, &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Literal})
+			if !parser.peekTokenIs(token.RBRACE) && !parser.expectPeek(token.COMMA) {
+				return nil
+			}
+		}
+		parser.nextToken()
+
+		// 'from' is only special here, so it is not reserved as a keyword
+		if !parser.peekTokenIs(token.IDENTIFIER) || parser.peekToken.Literal != "from" {
+			parser.errorf(parser.peekToken.Position, "expected from after import list, but got %s instead", parser.peekToken.Type)
+			return nil
+		}
+		parser.nextToken()
+	}
+
+	if !parser.expectPeek(token.STRING) {
+		return nil
+	}
+	stmt.Path = &ast.StringLiteral{Token: parser.currentToken, Value: parser.currentToken.Literal}
+
 	if parser.peekTokenIs(token.SEMICOLON) {
 		parser.nextToken()
 	}
@@ -207,13 +330,38 @@ func (parser *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Literal}
 }
 
+// parseIllegal skips an ILLEGAL token, which the lexer has already reported.
+func (parser *Parser) parseIllegal() ast.Expression {
+	return nil
+}
+
 func (parser *Parser) parseIntegerLiteral() ast.Expression {
 	literal := &ast.IntegerLiteral{Token: parser.currentToken}
 
-	value, err := strconv.ParseInt(parser.currentToken.Literal, 0, 64)
+	// the lexer has validated the digits and base prefix
+	digits := strings.ReplaceAll(parser.currentToken.Literal, "_", "")
+	value, err := strconv.ParseInt(digits, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if bigValue, ok := new(big.Int).SetString(digits, 0); ok {
+			literal.Big = bigValue
+			return literal
+		}
+	}
+	if err != nil {
+		parser.errorf(parser.currentToken.Position, "could not parse %q as integer", parser.currentToken.Literal)
+		return nil
+	}
+
+	literal.Value = value
+	return literal
+}
+
+func (parser *Parser) parseFloatLiteral() ast.Expression {
+	literal := &ast.FloatLiteral{Token: parser.currentToken}
+
+	value, err := strconv.ParseFloat(strings.ReplaceAll(parser.currentToken.Literal, "_", ""), 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", parser.currentToken.Literal)
-		parser.errors = append(parser.errors, msg)
+		parser.errorf(parser.currentToken.Position, "could not parse %q as float", parser.currentToken.Literal)
 		return nil
 	}
 
@@ -229,6 +377,12 @@ func (parser *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: parser.currentToken, Value: parser.currentToken.Literal}
 }
 
+func (parser *Parser) parseRegexLiteral() ast.Expression {
+	literal := parser.currentToken.Literal
+	end := strings.LastIndex(literal, "/")
+	return &ast.RegexLiteral{Token: parser.currentToken, Pattern: literal[1:end], Flags: literal[end+1:]}
+}
+
 func (parser *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    parser.currentToken,
@@ -293,6 +447,49 @@ func (parser *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+func (parser *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: parser.currentToken}
+
+	if !parser.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Block = parser.parseBlockStatement()
+
+	if parser.peekTokenIs(token.CATCH) {
+		parser.nextToken()
+		if parser.peekTokenIs(token.LPAREN) {
+			parser.nextToken()
+			if !parser.expectPeek(token.IDENTIFIER) {
+				return nil
+			}
+			expression.CatchParameter = &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Literal}
+			if !parser.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+		if !parser.expectPeek(token.LBRACE) {
+			return nil
+		}
+		expression.CatchBlock = parser.parseBlockStatement()
+	}
+
+	if parser.peekTokenIs(token.FINALLY) {
+		parser.nextToken()
+		if !parser.expectPeek(token.LBRACE) {
+			return nil
+		}
+		expression.FinallyBlock = parser.parseBlockStatement()
+	}
+
+	if expression.CatchBlock == nil && expression.FinallyBlock == nil {
+		parser.errorf(parser.peekToken.Position, "expected catch or finally after try block, but got %s instead", parser.peekToken.Type)
+		return nil
+	}
+
+	return expression
+}
+
 func (parser *Parser) parseFunctionLiteral() ast.Expression {
 	literal := &ast.FunctionLiteral{Token: parser.currentToken}
 
@@ -300,7 +497,19 @@ func (parser *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	literal.Parameters = parser.parseFunctionParameters()
+	literal.Parameters, literal.ParameterTypes = parser.parseFunctionParameters()
+	if literal.Parameters == nil {
+		return nil
+	}
+
+	if parser.peekTokenIs(token.COLON) {
+		parser.nextToken()
+		parser.nextToken()
+		literal.ReturnType = parser.parseTypeAnnotation()
+		if literal.ReturnType == nil {
+			return nil
+		}
+	}
 
 	if !parser.expectPeek(token.LBRACE) {
 		return nil
@@ -310,45 +519,164 @@ func (parser *Parser) parseFunctionLiteral() ast.Expression {
 	return literal
 }
 
-func (parser *Parser) parseFunctionParameters() []*ast.Identifier {
+// parseFunctionParameters returns the parameters and, when any of them is
+// annotated, their types.
+func (parser *Parser) parseFunctionParameters() ([]*ast.Identifier, []ast.TypeAnnotation) {
 	identifiers := []*ast.Identifier{}
+	types := []ast.TypeAnnotation{}
+	annotated := false
 
 	if parser.peekTokenIs(token.RPAREN) {
 		parser.nextToken()
-		return identifiers
+		return identifiers, nil
 	}
 
-	parser.nextToken()
+	for {
+		parser.nextToken()
+		identifier := &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Literal}
+		identifiers = append(identifiers, identifier)
 
-	identifier := &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Literal}
-	identifiers = append(identifiers, identifier)
+		var annotation ast.TypeAnnotation
+		if parser.peekTokenIs(token.COLON) {
+			parser.nextToken()
+			parser.nextToken()
+			if annotation = parser.parseTypeAnnotation(); annotation == nil {
+				return nil, nil
+			}
+			annotated = true
+		}
+		types = append(types, annotation)
 
-	for parser.peekTokenIs(token.COMMA) {
-		parser.nextToken()
+		if !parser.peekTokenIs(token.COMMA) {
+			break
+		}
 		parser.nextToken()
-		identifier = &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Literal}
-		identifiers = append(identifiers, identifier)
 	}
 
 	if !parser.expectPeek(token.RPAREN) {
+		return nil, nil
+	}
+
+	if !annotated {
+		return identifiers, nil
+	}
+	return identifiers, types
+}
+
+// parseTypeAnnotation parses the type starting at the current token.
+func (parser *Parser) parseTypeAnnotation() ast.TypeAnnotation {
+	switch parser.currentToken.Type {
+	case token.IDENTIFIER:
+		return &ast.NamedType{Token: parser.currentToken}
+	case token.LBRACKET:
+		typ := &ast.ArrayType{Token: parser.currentToken}
+		parser.nextToken()
+		if typ.Element = parser.parseTypeAnnotation(); typ.Element == nil || !parser.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		typ.Rbracket = parser.currentToken.Position
+		return typ
+	case token.LBRACE:
+		typ := &ast.HashType{Token: parser.currentToken}
+		parser.nextToken()
+		if typ.Key = parser.parseTypeAnnotation(); typ.Key == nil || !parser.expectPeek(token.COLON) {
+			return nil
+		}
+		parser.nextToken()
+		if typ.Value = parser.parseTypeAnnotation(); typ.Value == nil || !parser.expectPeek(token.RBRACE) {
+			return nil
+		}
+		typ.Rbrace = parser.currentToken.Position
+		return typ
+	case token.FUNCTION:
+		return parser.parseFunctionType()
+	}
+
+	parser.errorf(parser.currentToken.Position, "expected a type, but got %s instead", parser.currentToken.Type)
+	return nil
+}
+
+func (parser *Parser) parseFunctionType() ast.TypeAnnotation {
+	typ := &ast.FunctionType{Token: parser.currentToken, Parameters: []ast.TypeAnnotation{}}
+	if !parser.expectPeek(token.LPAREN) {
 		return nil
 	}
 
-	return identifiers
+	if parser.peekTokenIs(token.RPAREN) {
+		parser.nextToken()
+	} else {
+		for {
+			parser.nextToken()
+			param := parser.parseTypeAnnotation()
+			if param == nil {
+				return nil
+			}
+			typ.Parameters = append(typ.Parameters, param)
+			if !parser.peekTokenIs(token.COMMA) {
+				break
+			}
+			parser.nextToken()
+		}
+		if !parser.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+	typ.Rparen = parser.currentToken.Position
+
+	if parser.peekTokenIs(token.COLON) {
+		parser.nextToken()
+		parser.nextToken()
+		if typ.Return = parser.parseTypeAnnotation(); typ.Return == nil {
+			return nil
+		}
+	}
+	return typ
 }
 
 func (parser *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	expr := &ast.CallExpression{Token: parser.currentToken, Function: function}
 	expr.Arguments = parser.parseExpressionList(token.RPAREN)
+	expr.Rparen = parser.currentToken.Position
 	return expr
 }
 
 func (parser *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: parser.currentToken}
 	array.Elements = parser.parseExpressionList(token.RBRACKET)
+	array.Rbracket = parser.currentToken.Position
 	return array
 }
 
+func (parser *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: parser.currentToken}
+	hash.Pairs = []ast.HashLiteralPair{}
+
+	for !parser.peekTokenIs(token.RBRACE) {
+		parser.nextToken()
+		key := parser.parseExpression(LOWEST)
+
+		if !parser.expectPeek(token.COLON) {
+			return nil
+		}
+
+		parser.nextToken()
+		value := parser.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{Key: key, Value: value})
+
+		if !parser.peekTokenIs(token.RBRACE) && !parser.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !parser.expectPeek(token.RBRACE) {
+		return nil
+	}
+	hash.Rbrace = parser.currentToken.Position
+
+	return hash
+}
+
 func (parser *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	expr := &ast.IndexExpression{Token: parser.currentToken, Left: left}
 	parser.nextToken()
@@ -357,10 +685,22 @@ func (parser *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	if !parser.expectPeek(token.RBRACKET) {
 		return nil
 	}
+	expr.Rbracket = parser.currentToken.Position
 
 	return expr
 }
 
+func (parser *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	expr := &ast.MemberExpression{Token: parser.currentToken, Object: left}
+
+	if !parser.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+
+	expr.Property = &ast.Identifier{Token: parser.currentToken, Value: parser.currentToken.Literal}
+	return expr
+}
+
 func (parser *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
 
@@ -418,13 +758,15 @@ func (parser *Parser) currentPrecedence() int {
 }
 
 func (parser *Parser) peekError(tokenType token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, but got %s instead", tokenType, parser.peekToken.Type)
-	parser.errors = append(parser.errors, msg)
+	parser.errorf(parser.peekToken.Position, "expected next token to be %s, but got %s instead", tokenType, parser.peekToken.Type)
 }
 
 func (parser *Parser) noPrefixParseFunctionError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	parser.errors = append(parser.errors, msg)
+	parser.errorf(parser.currentToken.Position, "no prefix parse function for %s found", t)
+}
+
+func (parser *Parser) errorf(position token.Position, format string, a ...interface{}) {
+	parser.errors = append(parser.errors, &Error{Position: position, Message: fmt.Sprintf(format, a...)})
 }
 
 func (parser *Parser) registerPrefix(tokenType token.TokenType, fn PrefixParseFunction) {
@@ -41,6 +41,97 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestImportStatements(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedPath  string
+		expectedNames []string
+	}{
+		{`import "util";`, "util", nil},
+		{`import "path/to/util"`, "path/to/util", nil},
+		{`import { a } from "util";`, "util", []string{"a"}},
+		{`import { a, b, c } from "./lib/util";`, "./lib/util", []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.New(tt.input)
+		parser := parser.New(lexer)
+		program := parser.ParseProgram()
+		checkParseErrors(t, parser)
+
+		assert.Len(t, program.Statements, 1)
+
+		stmt, ok := program.Statements[0].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.ImportStatement. got=%T", program.Statements[0])
+		}
+
+		assert.Equal(t, stmt.Path.Value, tt.expectedPath)
+		if tt.expectedNames == nil {
+			assert.Nil(t, stmt.Names)
+			continue
+		}
+		names := []string{}
+		for _, name := range stmt.Names {
+			names = append(names, name.Value)
+		}
+		assert.Equal(t, names, tt.expectedNames)
+	}
+}
+
+func TestImportStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`import util;`, "expected next token to be STRING, but got IDENTIFIER instead"},
+		{`import { a } "util";`, "expected from after import list, but got STRING instead"},
+		{`import { a b } from "util";`, "expected next token to be ,, but got IDENTIFIER instead"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.New(tt.input)
+		parser := parser.New(lexer)
+		parser.ParseProgram()
+
+		assert.Contains(t, parser.Errors(), tt.expectedError)
+	}
+}
+
+func TestExportStatement(t *testing.T) {
+	lexer := lexer.New("export let x = 5; let y = 6;")
+	parser := parser.New(lexer)
+	program := parser.ParseProgram()
+	checkParseErrors(t, parser)
+
+	assert.Len(t, program.Statements, 2)
+	assert.True(t, program.Statements[0].(*ast.LetStatement).Exported)
+	assert.False(t, program.Statements[1].(*ast.LetStatement).Exported)
+	assert.Equal(t, program.String(), "export let x = 5;let y = 6;")
+}
+
+func TestNestedExportStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"if (true) { export let x = 1; }", []string{"1:13: export is only allowed at the top level of a module"}},
+		{"let f = fn() { export let x = 1; x };", []string{"1:16: export is only allowed at the top level of a module"}},
+		{"if (true) { 1 }; export let x = 1;", nil},
+	}
+
+	for _, tt := range tests {
+		parser := parser.New(lexer.New(tt.input))
+		parser.ParseProgram()
+
+		var errors []string
+		for _, err := range parser.ErrorList() {
+			errors = append(errors, err.Error())
+		}
+		assert.Equal(t, errors, tt.expected, tt.input)
+	}
+}
+
 func TestReturnStatement(t *testing.T) {
 	input := `
 	return 5;
@@ -112,6 +203,50 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	testLiteralExpression(t, stmt.Expression, int64(5))
 }
 
+func TestBigIntegerLiteralExpression(t *testing.T) {
+	input := "123456789012345678901234567890;"
+
+	lexer := lexer.New(input)
+	parser := parser.New(lexer)
+	program := parser.ParseProgram()
+	checkParseErrors(t, parser)
+
+	assert.Len(t, program.Statements, 1)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+	if !ok {
+		t.Fatalf("expression is not ast.IntegerLiteral. got=%T", stmt.Expression)
+	}
+	if assert.NotNil(t, literal.Big) {
+		assert.Equal(t, literal.Big.String(), "123456789012345678901234567890")
+	}
+	assert.Equal(t, literal.String(), "123456789012345678901234567890")
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "2.5e-1;"
+
+	lexer := lexer.New(input)
+	parser := parser.New(lexer)
+	program := parser.ParseProgram()
+	checkParseErrors(t, parser)
+
+	assert.Len(t, program.Statements, 1)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+	assert.Equal(t, literal.Value, 0.25)
+	assert.Equal(t, literal.String(), "2.5e-1")
+}
+
 func TestBooleanLiteralExpression(t *testing.T) {
 	input := "true;false;"
 	boolValues := []bool{true, false}
@@ -328,6 +463,10 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"a.b + c.d(e)",
+			"(a.b + c.d(e))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -426,6 +565,82 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestTryExpression(t *testing.T) {
+	input := `try { x } catch (e) { y } finally { z }`
+
+	lexer := lexer.New(input)
+	parser := parser.New(lexer)
+	program := parser.ParseProgram()
+	checkParseErrors(t, parser)
+
+	assert.Len(t, program.Statements, 1)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	expr, ok := stmt.Expression.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.TryExpression. got=%T", stmt.Expression)
+	}
+
+	assert.Len(t, expr.Block.Statements, 1)
+	testIdentifier(t, expr.Block.Statements[0].(*ast.ExpressionStatement).Expression, "x")
+
+	testIdentifier(t, expr.CatchParameter, "e")
+	assert.Len(t, expr.CatchBlock.Statements, 1)
+	testIdentifier(t, expr.CatchBlock.Statements[0].(*ast.ExpressionStatement).Expression, "y")
+
+	assert.Len(t, expr.FinallyBlock.Statements, 1)
+	testIdentifier(t, expr.FinallyBlock.Statements[0].(*ast.ExpressionStatement).Expression, "z")
+}
+
+func TestTryExpressionVariants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { x } catch { y }", "try x catch y"},
+		{"try { x } finally { z }", "try x finally z"},
+		{"try { x } catch (e) { e.message }", "try x catch(e) e.message"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.New(tt.input)
+		parser := parser.New(lexer)
+		program := parser.ParseProgram()
+		checkParseErrors(t, parser)
+
+		assert.Equal(t, program.String(), tt.expected)
+	}
+}
+
+func TestTryExpressionWithoutHandler(t *testing.T) {
+	lexer := lexer.New("try { x }")
+	parser := parser.New(lexer)
+	parser.ParseProgram()
+
+	assert.Equal(t, parser.Errors(), []string{"expected catch or finally after try block, but got EOF instead"})
+}
+
+func TestErrorPositions(t *testing.T) {
+	lexer := lexer.New("let x = 1 +;\nlet @ = 2;")
+	parser := parser.New(lexer)
+	parser.ParseProgram()
+
+	errors := []string{}
+	for _, err := range parser.ErrorList() {
+		errors = append(errors, err.Error())
+	}
+	assert.Equal(t, errors, []string{
+		"2:5: illegal character '@'",
+		"1:12: no prefix parse function for ; found",
+		"2:5: expected next token to be IDENTIFIER, but got ILLEGAL instead",
+		"2:7: no prefix parse function for = found",
+	})
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -486,6 +701,72 @@ func TestFunctionParameterParser(t *testing.T) {
 	}
 }
 
+func TestParsingTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 1;", "let x: int = 1;"},
+		{"let xs: [string] = [];", "let xs: [string] = [];"},
+		{"let h: {string: [int]} = {};", "let h: {string: [int]} = {};"},
+		{"fn(a: int, b) { a }", "fn(a: int, b) a"},
+		{"fn(a: float): float { a }", "fn(a: float): float a"},
+		{"let apply = fn(f: fn(int): bool, x: int): bool { f(x) };", "let apply = fn(f: fn(int): bool, x: int): bool f(x);"},
+		{"fn(callback: fn()) { callback() }", "fn(callback: fn()) callback()"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.New(tt.input)
+		parser := parser.New(lexer)
+		program := parser.ParseProgram()
+		checkParseErrors(t, parser)
+
+		assert.Equal(t, program.String(), tt.expected, tt.input)
+	}
+}
+
+func TestParsingInvalidTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: = 1;", "expected a type, but got = instead"},
+		{"fn(a: 1) { a }", "expected a type, but got INT instead"},
+		{"let xs: [int = [];", "expected next token to be ], but got = instead"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.New(tt.input)
+		parser := parser.New(lexer)
+		parser.ParseProgram()
+
+		assert.Contains(t, parser.Errors(), tt.expected, tt.input)
+	}
+}
+
+func TestFunctionLiteralWithName(t *testing.T) {
+	input := `let myFunction = fn() { };`
+
+	lexer := lexer.New(input)
+	parser := parser.New(lexer)
+	program := parser.ParseProgram()
+	checkParseErrors(t, parser)
+
+	assert.Len(t, program.Statements, 1)
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T", program.Statements[0])
+	}
+
+	function, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.FunctionLiteral. got=%T", stmt.Value)
+	}
+
+	assert.Equal(t, function.Name, "myFunction")
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5)"
 
@@ -560,6 +841,53 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingMemberExpressions(t *testing.T) {
+	input := "error.message"
+
+	lexer := lexer.New(input)
+	parser := parser.New(lexer)
+	program := parser.ParseProgram()
+	checkParseErrors(t, parser)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	memberExpr, ok := stmt.Expression.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression not *ast.MemberExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, memberExpr.Object, "error") {
+		return
+	}
+
+	testIdentifier(t, memberExpr.Property, "message")
+}
+
+func TestParsingHashLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{}`, "{}"},
+		{`{"one": 1, "two": 2}`, "{one: 1, two: 2}"},
+		{`{"one": 0 + 1, true: 2 * 3, 4: [5]}`, "{one: (0 + 1), true: (2 * 3), 4: [5]}"},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.New(tt.input)
+		parser := parser.New(lexer)
+		program := parser.ParseProgram()
+		checkParseErrors(t, parser)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		hash, ok := stmt.Expression.(*ast.HashLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression not *ast.HashLiteral. got=%T", stmt.Expression)
+		}
+
+		assert.Equal(t, hash.String(), tt.expected)
+	}
+}
+
 func testIntegerLiteral(t *testing.T, literal ast.Expression, value int64) bool {
 	integerLiteral, ok := literal.(*ast.IntegerLiteral)
 	if !ok {
@@ -645,3 +973,86 @@ func checkParseErrors(t *testing.T, parser *parser.Parser) {
 	}
 	t.FailNow()
 }
+
+func TestParsingIntegerLiteralForms(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF", 255},
+		{"0o17", 15},
+		{"017", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0x_dead_beef", 0xdeadbeef},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.New(tt.input)
+		parser := parser.New(lexer)
+		program := parser.ParseProgram()
+		checkParseErrors(t, parser)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		assert.Equal(t, literal.Value, tt.expected)
+		assert.Equal(t, literal.String(), tt.input)
+	}
+}
+
+func TestParsingInvalidIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0b102", `invalid digit '2' in binary literal "0b102"`},
+		{"0o8", `invalid digit '8' in octal literal "0o8"`},
+		{"09", `invalid digit '9' in octal literal "09"`},
+		{"0xfg", `invalid digit 'g' in hexadecimal literal "0xfg"`},
+		{"0x", `hexadecimal literal "0x" has no digits`},
+		{"1__000", `'_' must separate successive digits in "1__000"`},
+		{"1_000_", `'_' must separate successive digits in "1_000_"`},
+		{"1_.5", `'_' must separate successive digits in "1_.5"`},
+		{"let x = 1 @ 2", `illegal character '@'`},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.New(tt.input)
+		parser := parser.New(lexer)
+		parser.ParseProgram()
+
+		assert.Contains(t, parser.Errors(), tt.expected, tt.input)
+	}
+}
+
+func TestParsingRegexLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedPattern string
+		expectedFlags   string
+	}{
+		{`/a+/`, "a+", ""},
+		{`/(?P<year>\d{4})-\d\d/im`, `(?P<year>\d{4})-\d\d`, "im"},
+		{`/a\/b/`, `a\/b`, ""},
+	}
+
+	for _, tt := range tests {
+		lexer := lexer.New(tt.input)
+		parser := parser.New(lexer)
+		program := parser.ParseProgram()
+		checkParseErrors(t, parser)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		regex, ok := stmt.Expression.(*ast.RegexLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression not *ast.RegexLiteral. got=%T", stmt.Expression)
+		}
+
+		assert.Equal(t, regex.Pattern, tt.expectedPattern)
+		assert.Equal(t, regex.Flags, tt.expectedFlags)
+		assert.Equal(t, regex.String(), tt.input)
+	}
+}
@@ -0,0 +1,50 @@
+// Package prelude provides the standard library written in Monkey itself.
+// Its sources are embedded in the binary and evaluated into the root
+// environment before user code runs.
+package prelude
+
+import (
+	"embed"
+	"fmt"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+)
+
+// Version is bound to PRELUDE_VERSION and changes whenever the set of
+// prelude functions or their behavior does.
+const Version = "1.3.0"
+
+//go:embed *.monkey
+var sources embed.FS
+
+// Load evaluates every prelude source, in name order, into env.
+func Load(env *object.Environment) error {
+	entries, err := sources.ReadDir(".")
+	if err != nil {
+		return err
+	}
+
+	env.Set("PRELUDE_VERSION", &object.String{Value: Version})
+
+	for _, entry := range entries {
+		source, err := sources.ReadFile(entry.Name())
+		if err != nil {
+			return err
+		}
+
+		p := parser.New(lexer.New(string(source)))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			return fmt.Errorf("prelude: %s: %s", entry.Name(), strings.Join(p.Errors(), "; "))
+		}
+
+		if err, ok := evaluator.Eval(program, env).(*object.Error); ok {
+			return fmt.Errorf("prelude: %s: %w", entry.Name(), err)
+		}
+	}
+
+	return nil
+}
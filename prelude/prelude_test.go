@@ -0,0 +1,91 @@
+package prelude_test
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/prelude"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPrelude runs the Monkey test files in testdata. They call assert_eq,
+// which raises an error when the inspected values differ.
+func TestPrelude(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*_test.monkey"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			source, err := os.ReadFile(file)
+			assert.NoError(t, err)
+
+			p := parser.New(lexer.New(string(source)))
+			program := p.ParseProgram()
+			assert.Empty(t, p.Errors())
+
+			builtins := evaluator.NewBuiltins()
+			builtins.Register(assertEq)
+			env := object.NewRuntimeEnvironment(&object.Runtime{Builtins: builtins, Output: &bytes.Buffer{}})
+			assert.NoError(t, prelude.Load(env))
+
+			if errObj, ok := evaluator.Eval(program, env).(*object.Error); ok {
+				t.Errorf("%s\n%s", errObj.Inspect(), errObj.StackTrace())
+			}
+		})
+	}
+}
+
+// TestPreludeOnlyDefinesFunctions guarantees that loading the prelude cannot
+// fail at run time: it only binds function literals, so it needs no builtins
+// and is unaffected by the limits an interpreter is configured with.
+func TestPreludeOnlyDefinesFunctions(t *testing.T) {
+	files, err := filepath.Glob("*.monkey")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		assert.NoError(t, err)
+
+		p := parser.New(lexer.New(string(source)))
+		program := p.ParseProgram()
+		assert.Empty(t, p.Errors(), file)
+
+		for _, stmt := range program.Statements {
+			let, ok := stmt.(*ast.LetStatement)
+			if !ok {
+				t.Errorf("%s: %s is not a let statement", file, stmt.String())
+				continue
+			}
+			_, ok = let.Value.(*ast.FunctionLiteral)
+			assert.True(t, ok, "%s: %s is not bound to a function literal", file, let.Name.Value)
+		}
+	}
+
+	env := object.NewRuntimeEnvironment(&object.Runtime{Builtins: object.NewBuiltins(), MaxDepth: 1})
+	assert.NoError(t, prelude.Load(env))
+}
+
+var assertEq = &object.Builtin{
+	Name:   "assert_eq",
+	Params: []string{"actual", "expected"},
+	Fn: func(env *object.Environment, args ...object.Object) object.Object {
+		actual, expected := args[0].Inspect(), args[1].Inspect()
+		if actual != expected || args[0].Type() != args[1].Type() {
+			return &object.Error{
+				Kind:    object.USER_ERROR,
+				Message: fmt.Sprintf("expected %s (%s), got %s (%s)", expected, args[1].Type(), actual, args[0].Type()),
+			}
+		}
+		return evaluator.NULL
+	},
+}
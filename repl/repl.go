@@ -7,14 +7,28 @@ import (
 	"monkey/evaluator"
 	"monkey/lexer"
 	"monkey/object"
+	"monkey/optimizer"
 	"monkey/parser"
+	"monkey/prelude"
 )
 
 const PROMPT = "> "
 
-func Start(in io.Reader, out io.Writer) {
+// Start reads lines from in and evaluates them, writing results to out. The
+// standard prelude is loaded first unless withPrelude is false, and each
+// line is optimized before it runs when optimize is true.
+func Start(in io.Reader, out io.Writer, withPrelude bool, optimize bool) {
 	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	runtime := &object.Runtime{Output: out, Optimize: optimize}
+	env := object.NewRuntimeEnvironment(runtime)
+
+	if withPrelude {
+		runtime.Prelude = object.NewRuntimeEnvironment(runtime)
+		if err := prelude.Load(runtime.Prelude); err != nil {
+			fmt.Fprintln(out, err)
+		}
+		env = object.NewEnclosedEnvironment(runtime.Prelude)
+	}
 
 	for {
 		fmt.Fprint(out, PROMPT)
@@ -36,11 +50,23 @@ func Start(in io.Reader, out io.Writer) {
 		io.WriteString(out, program.String())
 		io.WriteString(out, "\n")
 
+		if err := evaluator.Resolve(program, env); err != nil {
+			io.WriteString(out, err.Inspect()+"\n")
+			io.WriteString(out, err.StackTrace())
+			continue
+		}
+		if optimize {
+			optimizer.Optimize(program)
+		}
+
 		evaluated := evaluator.Eval(program, env)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
 		}
+		if err, ok := evaluated.(*object.Error); ok {
+			io.WriteString(out, err.StackTrace())
+		}
 	}
 }
 
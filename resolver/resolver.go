@@ -0,0 +1,203 @@
+// Package resolver checks the names used by a program before it runs.
+//
+// Scopes mirror the environments the evaluator creates: one for the program,
+// one for each function call holding its parameters and the lets of its body,
+// and one for each catch block. Blocks of if and try expressions share the
+// scope around them. A let is visible to the code after it in the same scope
+// and, since functions run later, anywhere inside the functions of that scope.
+package resolver
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+	"path/filepath"
+	"strings"
+)
+
+// Error is a problem found by the resolver.
+type Error struct {
+	Position token.Position
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position, e.Message)
+}
+
+// Resolve reports references to undefined names and duplicate parameters in
+// program, and sets the Binding of each identifier declared or used inside
+// it. defined reports whether a name is bound outside the program, by the
+// builtins, the prelude or the environment it will run in.
+func Resolve(program *ast.Program, defined func(name string) bool) []*Error {
+	r := &resolver{defined: defined}
+	r.scope = newScope(nil, false)
+	r.declare(program.Statements)
+	for _, stmt := range program.Statements {
+		ast.Walk(r, stmt)
+	}
+	return r.errors
+}
+
+type scope struct {
+	outer    *scope
+	function bool           // a function's scope, whose body runs when called
+	slots    map[string]int // every name declared in the scope
+	bound    map[string]bool
+}
+
+func newScope(outer *scope, function bool) *scope {
+	return &scope{outer: outer, function: function, slots: map[string]int{}, bound: map[string]bool{}}
+}
+
+type resolver struct {
+	scope   *scope
+	defined func(name string) bool
+	errors  []*Error
+}
+
+func (r *resolver) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		r.use(node)
+	case *ast.LetStatement:
+		if node.Value != nil {
+			ast.Walk(r, node.Value)
+		}
+		r.bind(node.Name)
+		return nil
+	case *ast.ImportStatement:
+		if node.Names == nil {
+			r.scope.bound[moduleName(node.Path.Value)] = true
+		}
+		for _, name := range node.Names {
+			r.bind(name)
+		}
+		return nil
+	case *ast.FunctionLiteral:
+		r.function(node)
+		return nil
+	case *ast.TryExpression:
+		ast.Walk(r, node.Block)
+		if node.CatchBlock != nil {
+			r.catch(node)
+		}
+		if node.FinallyBlock != nil {
+			ast.Walk(r, node.FinallyBlock)
+		}
+		return nil
+	case *ast.MemberExpression:
+		// the property is looked up on the object, not in scope
+		ast.Walk(r, node.Object)
+		return nil
+	}
+	return r
+}
+
+func (r *resolver) function(fn *ast.FunctionLiteral) {
+	r.scope = newScope(r.scope, true)
+	defer func() { r.scope = r.scope.outer }()
+
+	for _, param := range fn.Parameters {
+		if _, ok := r.scope.slots[param.Value]; ok {
+			r.errorf(param.Token.Position, "duplicate parameter %s", param.Value)
+		}
+		r.add(param.Value)
+		r.bind(param)
+	}
+	r.declare(fn.Body.Statements)
+	ast.Walk(r, fn.Body)
+}
+
+func (r *resolver) catch(try *ast.TryExpression) {
+	r.scope = newScope(r.scope, false)
+	defer func() { r.scope = r.scope.outer }()
+
+	if try.CatchParameter != nil {
+		r.add(try.CatchParameter.Value)
+		r.bind(try.CatchParameter)
+	}
+	r.declare(try.CatchBlock.Statements)
+	ast.Walk(r, try.CatchBlock)
+}
+
+// declare adds the names bound by stmts to the current scope before they are
+// resolved, so functions can refer to lets that follow them.
+func (r *resolver) declare(stmts []ast.Statement) {
+	var declare func(node ast.Node) bool
+	declare = func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			r.add(node.Name.Value)
+		case *ast.ImportStatement:
+			if node.Names == nil {
+				r.add(moduleName(node.Path.Value))
+			}
+			for _, name := range node.Names {
+				r.add(name.Value)
+			}
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.TryExpression:
+			// the catch block has a scope of its own
+			ast.Inspect(node.Block, declare)
+			if node.FinallyBlock != nil {
+				ast.Inspect(node.FinallyBlock, declare)
+			}
+			return false
+		}
+		return true
+	}
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, declare)
+	}
+}
+
+func (r *resolver) add(name string) {
+	if _, ok := r.scope.slots[name]; !ok {
+		r.scope.slots[name] = len(r.scope.slots)
+	}
+}
+
+// bind records that ident has been assigned in the current scope.
+func (r *resolver) bind(ident *ast.Identifier) {
+	r.scope.bound[ident.Value] = true
+	ident.Binding = &ast.Binding{Depth: 0, Slot: r.scope.slots[ident.Value]}
+}
+
+// use annotates ident with the innermost scope declaring it and reports it
+// when no scope has bound it by the time it runs.
+func (r *resolver) use(ident *ast.Identifier) {
+	name := ident.Value
+	found := false
+	deferred := false // whether a function boundary lies between use and scope
+	depth := 0
+	ident.Binding = nil
+	for s := r.scope; s != nil; s = s.outer {
+		if slot, ok := s.slots[name]; ok {
+			if ident.Binding == nil {
+				ident.Binding = &ast.Binding{Depth: depth, Slot: slot}
+			}
+			if deferred || s.bound[name] {
+				found = true
+				break
+			}
+		}
+		deferred = deferred || s.function
+		depth++
+	}
+
+	if !found && !r.defined(name) {
+		r.errorf(ident.Token.Position, "identifier not found: %s", name)
+	}
+}
+
+func (r *resolver) errorf(position token.Position, format string, a ...interface{}) {
+	r.errors = append(r.errors, &Error{Position: position, Message: fmt.Sprintf(format, a...)})
+}
+
+// moduleName returns the name an import without a list binds, which is the
+// file name of its path without the extension.
+func moduleName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
@@ -0,0 +1,92 @@
+package resolver_test
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/resolver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resolve(t *testing.T, input string) (*ast.Program, []string) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %v", p.Errors())
+	}
+
+	errors := []string{}
+	for _, err := range resolver.Resolve(program, func(name string) bool { return name == "len" }) {
+		errors = append(errors, err.Error())
+	}
+	return program, errors
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; x + len(x)", []string{}},
+		{"y", []string{"1:1: identifier not found: y"}},
+		{"let x = x;", []string{"1:9: identifier not found: x"}},
+		{"x; let x = 1;", []string{"1:1: identifier not found: x"}},
+		{"let f = fn() { g() }; let g = fn() { f() };", []string{}},
+		{"let f = fn(n) { if (n > 0) { f(n - 1) } else { 0 } }", []string{}},
+		{"fn() { x; let x = 1; }", []string{"1:8: identifier not found: x"}},
+		{"fn(a, b, a) { a }", []string{"1:10: duplicate parameter a"}},
+		{"if (true) { let x = 1; }; x", []string{}},
+		{"try { let x = 1; } finally { x }; x", []string{}},
+		{"try { 1 } catch (e) { let m = e.message; m }; m", []string{"1:47: identifier not found: m"}},
+		{"try { 1 } catch (e) { e }; e", []string{"1:28: identifier not found: e"}},
+		{"let point = {}; point.x", []string{}},
+		{`import "lib/strings"; import { pad } from "other"; strings.upper(pad)`, []string{}},
+		{"{foo: bar}[baz]", []string{
+			"1:2: identifier not found: foo",
+			"1:7: identifier not found: bar",
+			"1:12: identifier not found: baz",
+		}},
+	}
+
+	for _, tt := range tests {
+		_, errors := resolve(t, tt.input)
+		assert.Equal(t, errors, tt.expected, tt.input)
+	}
+}
+
+func TestResolveBindings(t *testing.T) {
+	program, errors := resolve(t, `
+let a = 1;
+let f = fn(b, c) {
+  let d = fn() { a + c + len(d) };
+  try { d() } catch (e) { e + b }
+};
+`)
+	assert.Empty(t, errors)
+
+	bindings := map[string][]ast.Binding{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			var binding ast.Binding
+			if ident.Binding == nil {
+				binding = ast.Binding{Depth: -1, Slot: -1}
+			} else {
+				binding = *ident.Binding
+			}
+			bindings[ident.Value] = append(bindings[ident.Value], binding)
+		}
+		return true
+	})
+
+	assert.Equal(t, bindings, map[string][]ast.Binding{
+		"a":   {{Depth: 0, Slot: 0}, {Depth: 2, Slot: 0}},
+		"f":   {{Depth: 0, Slot: 1}},
+		"b":   {{Depth: 0, Slot: 0}, {Depth: 1, Slot: 0}},
+		"c":   {{Depth: 0, Slot: 1}, {Depth: 1, Slot: 1}},
+		"d":   {{Depth: 0, Slot: 2}, {Depth: 1, Slot: 2}, {Depth: 0, Slot: 2}},
+		"len": {{Depth: -1, Slot: -1}},
+		"e":   {{Depth: 0, Slot: 0}, {Depth: 0, Slot: 0}},
+	})
+}
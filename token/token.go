@@ -1,20 +1,41 @@
 package token
 
+import (
+	"fmt"
+	"sort"
+)
+
 type TokenType string
 
+// Position is the 1-based line and column where a token starts in the source.
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Position
 }
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"true":    TRUE,
+	"false":   FALSE,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"import":  IMPORT,
+	"export":  EXPORT,
 }
 
 func NewToken(tokenType TokenType, ch byte) Token {
@@ -25,6 +46,16 @@ func NewTokenWithLiteral(tokenType TokenType, literal string) Token {
 	return Token{Type: tokenType, Literal: literal}
 }
 
+// Keywords returns the reserved words of the language in sorted order.
+func Keywords() []string {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
 func LookupIdentifierTokenType(identifier string) TokenType {
 	if tokenType, ok := keywords[identifier]; ok {
 		return tokenType
@@ -39,7 +70,10 @@ const (
 	// identifiers and literals
 	IDENTIFIER = "IDENTIFIER"
 	INT        = "INT"
+	FLOAT      = "FLOAT"
 	STRING     = "STRING"
+	REGEX      = "REGEX"
+	COMMENT    = "COMMENT"
 
 	// operators
 	ASSIGN = "="
@@ -58,6 +92,8 @@ const (
 	// delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
+	DOT       = "."
+	COLON     = ":"
 
 	LPAREN = "("
 	RPAREN = ")"
@@ -76,4 +112,9 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
 )
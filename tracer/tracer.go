@@ -0,0 +1,173 @@
+// Package tracer records what a Monkey program did as it ran. Attach a
+// tracer to an interpreter with monkey.WithTracer:
+//
+//	trace := tracer.NewText(os.Stderr)
+//	interp := monkey.New(monkey.WithTracer(trace))
+//
+// Text writes an indented trace for people to read, and JSON writes one
+// JSON object per event for offline analysis.
+package tracer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/object"
+	"monkey/token"
+	"strings"
+)
+
+// MAX_STATEMENT is the length statements are shortened to in traces.
+const MAX_STATEMENT = 60
+
+// Text writes an indented trace, one line per event, nesting the events of
+// each call under it.
+type Text struct {
+	out   io.Writer
+	depth int
+	err   error
+}
+
+func NewText(out io.Writer) *Text {
+	return &Text{out: out}
+}
+
+// Err returns the first error writing the trace. Events after it are
+// dropped.
+func (trace *Text) Err() error {
+	return trace.err
+}
+
+func (trace *Text) Statement(stmt ast.Statement, env *object.Environment) {
+	position := ast.SpanOf(stmt).Start
+	trace.printf("%d:%d %s", position.Line, position.Column, summary(stmt))
+}
+
+func (trace *Text) Enter(fn object.Object, args []object.Object, call token.Position) {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = arg.Inspect()
+	}
+	trace.printf("-> %s(%s)", name(fn), strings.Join(values, ", "))
+	trace.depth++
+}
+
+func (trace *Text) Exit(fn object.Object, result object.Object) {
+	trace.depth--
+	trace.printf("<- %s = %s", name(fn), inspect(result))
+}
+
+func (trace *Text) Error(err *object.Error, stmt ast.Statement) {
+	position := ast.SpanOf(stmt).Start
+	trace.printf("!! %s at %d:%d", err.Error(), position.Line, position.Column)
+}
+
+func (trace *Text) printf(format string, a ...interface{}) {
+	if trace.err != nil {
+		return
+	}
+	indent := strings.Repeat("  ", trace.depth)
+	_, trace.err = fmt.Fprintf(trace.out, indent+format+"\n", a...)
+}
+
+// Event is a line written by JSON. Depth counts the calls in progress, so
+// the statements of a function are one deeper than the call that entered
+// it.
+type Event struct {
+	Event     string   `json:"event"` // statement, enter, exit or error
+	Depth     int      `json:"depth"`
+	Line      int      `json:"line,omitempty"`
+	Column    int      `json:"column,omitempty"`
+	Statement string   `json:"statement,omitempty"`
+	Function  string   `json:"function,omitempty"`
+	Builtin   bool     `json:"builtin,omitempty"`
+	Arguments []string `json:"arguments,omitempty"`
+	Result    string   `json:"result,omitempty"`
+	Failed    bool     `json:"failed,omitempty"`
+	Kind      string   `json:"kind,omitempty"`
+	Message   string   `json:"message,omitempty"`
+}
+
+// JSON writes every event as a line of JSON.
+type JSON struct {
+	encoder *json.Encoder
+	depth   int
+	err     error
+}
+
+func NewJSON(out io.Writer) *JSON {
+	return &JSON{encoder: json.NewEncoder(out)}
+}
+
+// Err returns the first error writing the trace. Events after it are
+// dropped.
+func (trace *JSON) Err() error {
+	return trace.err
+}
+
+func (trace *JSON) Statement(stmt ast.Statement, env *object.Environment) {
+	position := ast.SpanOf(stmt).Start
+	trace.write(Event{Event: "statement", Line: position.Line, Column: position.Column, Statement: summary(stmt)})
+}
+
+func (trace *JSON) Enter(fn object.Object, args []object.Object, call token.Position) {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = arg.Inspect()
+	}
+	_, builtin := fn.(*object.Builtin)
+	trace.write(Event{Event: "enter", Line: call.Line, Column: call.Column, Function: name(fn), Builtin: builtin, Arguments: values})
+	trace.depth++
+}
+
+func (trace *JSON) Exit(fn object.Object, result object.Object) {
+	trace.depth--
+	_, builtin := fn.(*object.Builtin)
+	_, failed := result.(*object.Error)
+	trace.write(Event{Event: "exit", Function: name(fn), Builtin: builtin, Result: inspect(result), Failed: failed})
+}
+
+func (trace *JSON) Error(err *object.Error, stmt ast.Statement) {
+	position := ast.SpanOf(stmt).Start
+	trace.write(Event{Event: "error", Line: position.Line, Column: position.Column, Kind: string(err.Kind), Message: err.Message})
+}
+
+func (trace *JSON) write(event Event) {
+	if trace.err != nil {
+		return
+	}
+	event.Depth = trace.depth
+	trace.err = trace.encoder.Encode(event)
+}
+
+// summary returns the source of stmt, shortened to MAX_STATEMENT runes.
+func summary(stmt ast.Statement) string {
+	text := []rune(stmt.String())
+	if len(text) <= MAX_STATEMENT {
+		return string(text)
+	}
+	return string(text[:MAX_STATEMENT-3]) + "..."
+}
+
+func name(fn object.Object) string {
+	switch fn := fn.(type) {
+	case *object.Function:
+		if fn.Name != "" {
+			return fn.Name
+		}
+		return "<anonymous>"
+	case *object.Builtin:
+		return fn.Name
+	}
+	return fn.Inspect()
+}
+
+// inspect formats a result, which is nil for statements that produce no
+// value, such as a function ending in a let.
+func inspect(result object.Object) string {
+	if result == nil {
+		return "null"
+	}
+	return result.Inspect()
+}
@@ -0,0 +1,95 @@
+package tracer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"monkey"
+	"monkey/object"
+	"monkey/tracer"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const PROGRAM = `let add = fn(a, b) {
+  let sum = a + b;
+  sum
+};
+let total = add(1, len("abc"));
+let fail = fn() { total + "x" };
+try { fail() } catch (e) { e.message }`
+
+func run(t *testing.T, trace object.Tracer, program string, options ...monkey.Option) error {
+	options = append(options, monkey.WithTracer(trace))
+	_, err := monkey.New(options...).Run(context.Background(), program)
+	return err
+}
+
+func TestText(t *testing.T) {
+	var out bytes.Buffer
+	trace := tracer.NewText(&out)
+	assert.NoError(t, run(t, trace, PROGRAM))
+	assert.NoError(t, trace.Err())
+
+	assert.Equal(t, out.String(), `1:1 let add = fn(a, b) let sum = (a + b);sum;
+5:1 let total = add(1, len(abc));
+-> len(abc)
+<- len = 3
+-> add(1, 3)
+  2:3 let sum = (a + b);
+  3:3 sum
+<- add = 4
+6:1 let fail = fn() (total + x);
+7:1 try fail() catch(e) e.message
+7:7 fail()
+-> fail()
+  6:19 (total + x)
+  !! TypeError: type mismatch: INTEGER + STRING at 6:19
+<- fail = ERROR: TypeError: type mismatch: INTEGER + STRING
+7:28 e.message
+`)
+}
+
+func TestJSON(t *testing.T) {
+	var out bytes.Buffer
+	trace := tracer.NewJSON(&out)
+	assert.NoError(t, run(t, trace, PROGRAM))
+
+	events := []tracer.Event{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event tracer.Event
+		assert.NoError(t, json.Unmarshal([]byte(line), &event), line)
+		events = append(events, event)
+	}
+
+	assert.Equal(t, len(events), 16)
+	assert.Equal(t, events[2], tracer.Event{Event: "enter", Line: 5, Column: 23, Function: "len", Builtin: true, Arguments: []string{"abc"}})
+	assert.Equal(t, events[3], tracer.Event{Event: "exit", Function: "len", Builtin: true, Result: "3"})
+	assert.Equal(t, events[5], tracer.Event{Event: "statement", Depth: 1, Line: 2, Column: 3, Statement: "let sum = (a + b);"})
+	assert.Equal(t, events[13], tracer.Event{Event: "error", Depth: 1, Line: 6, Column: 19, Kind: "TypeError", Message: "type mismatch: INTEGER + STRING"})
+	assert.Equal(t, events[14], tracer.Event{Event: "exit", Function: "fail", Result: "ERROR: TypeError: type mismatch: INTEGER + STRING", Failed: true})
+}
+
+func TestErrorsAreTracedOnce(t *testing.T) {
+	var out bytes.Buffer
+	err := run(t, tracer.NewText(&out), "let f = fn(n) { if (n > 0) { f(n - 1) } else { n } };\nf(5)", monkey.WithMaxSteps(8))
+	assert.Error(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	errors := []string{}
+	for _, line := range lines {
+		if strings.Contains(line, "!!") {
+			errors = append(errors, strings.TrimSpace(line))
+		}
+	}
+	assert.Equal(t, errors, []string{"!! LimitError: maximum of 8 steps exceeded at 1:17"})
+	assert.Equal(t, lines[len(lines)-1], "<- f = ERROR: LimitError: maximum of 8 steps exceeded")
+}
+
+func TestLongStatementsAreShortened(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, run(t, tracer.NewText(&out), `let greeting = "a greeting that is much too long to be shown in full in a trace";`))
+	assert.Equal(t, out.String(), "1:1 let greeting = a greeting that is much too long to be sho...\n")
+}
@@ -0,0 +1,72 @@
+// Package wire reads and writes JSON messages framed by a Content-Length
+// header, the base protocol shared by the Language Server Protocol and the
+// Debug Adapter Protocol.
+package wire
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MAX_MESSAGE_LENGTH bounds the Content-Length a peer may announce, so that
+// a corrupt header cannot make ReadMessage allocate arbitrary amounts.
+const MAX_MESSAGE_LENGTH = 1 << 26
+
+// ReadMessage reads the body of one message framed by a Content-Length
+// header, returning io.EOF when the stream ends between messages.
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF && line == "" && length < 0 {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("wire: reading header: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("wire: malformed header %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || length < 0 {
+				return nil, fmt.Errorf("wire: invalid Content-Length %q", value)
+			}
+			if length > MAX_MESSAGE_LENGTH {
+				return nil, fmt.Errorf("wire: Content-Length %d exceeds %d", length, MAX_MESSAGE_LENGTH)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("wire: message without Content-Length")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("wire: reading body: %w", err)
+	}
+	return body, nil
+}
+
+// WriteMessage encodes v as JSON and writes it with a Content-Length header.
+func WriteMessage(w io.Writer, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
@@ -0,0 +1,43 @@
+package wire_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"monkey/wire"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadMessage(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, wire.WriteMessage(&buf, map[string]int{"seq": 1}))
+
+	r := bufio.NewReader(&buf)
+	body, err := wire.ReadMessage(r)
+	assert.NoError(t, err)
+	assert.Equal(t, string(body), `{"seq":1}`)
+
+	_, err = wire.ReadMessage(r)
+	assert.Equal(t, err, io.EOF)
+}
+
+func TestReadMessageErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Content-Type: json\r\n\r\n{}", "wire: message without Content-Length"},
+		{"Content-Length: -1\r\n\r\n", `wire: invalid Content-Length " -1"`},
+		{"Content-Length: 9223372036854775807\r\n\r\n", "wire: Content-Length 9223372036854775807 exceeds 67108864"},
+		{"Content-Length: 10\r\n\r\n{}", "wire: reading body: unexpected EOF"},
+		{"garbage\r\n\r\n", `wire: malformed header "garbage"`},
+	}
+
+	for _, tt := range tests {
+		_, err := wire.ReadMessage(bufio.NewReader(strings.NewReader(tt.input)))
+		assert.EqualError(t, err, tt.expected, tt.input)
+	}
+}